@@ -8,9 +8,21 @@ import (
 	"os/signal"
 	"syscall"
 
+	"website-monitor/internal/agent"
+	"website-monitor/internal/api"
+	"website-monitor/internal/check_repository"
 	"website-monitor/internal/checker"
+	"website-monitor/internal/cluster"
+	"website-monitor/internal/config"
 	"website-monitor/internal/db"
+	"website-monitor/internal/delivery_repository"
+	"website-monitor/internal/incident_repository"
+	"website-monitor/internal/instance_repository"
+	"website-monitor/internal/metrics"
+	"website-monitor/internal/models"
+	"website-monitor/internal/notifier"
 	"website-monitor/internal/scheduler"
+	"website-monitor/internal/state"
 	"website-monitor/internal/url_repository"
 )
 
@@ -21,8 +33,24 @@ func main() {
 }
 
 func run() error {
+	mode, err := config.Mode()
+	if err != nil {
+		return err
+	}
+
+	if mode == config.ModeAgent {
+		return runAgent()
+	}
+
 	log.Println("Starting Website Monitor...")
 
+	cfg, err := config.Load()
+	if err != nil {
+		log.Printf("Failed to load configuration: %v", err)
+
+		return err
+	}
+
 	database, err := connectToDatabase()
 	if err != nil {
 		return err
@@ -34,13 +62,58 @@ func run() error {
 		}
 	}(database)
 
-	sched, cancel, err := setupScheduler(database)
+	notif := notifier.New(delivery_repository.New(database), cfg.Notifier)
+	tracker := state.New(incident_repository.New(database), cfg.State)
+	tracker.OnTransition(notif)
+	mtr := metrics.New(tracker)
+	membership := cluster.New(instance_repository.New(database), cfg.Cluster)
+
+	sched, cancel, err := setupScheduler(database, cfg, tracker, mtr, membership)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	server, err := startApi(database, cfg.Api, mtr, sched, sched, membership)
 	if err != nil {
+		cancel()
+		sched.Stop()
+		membership.Stop()
+
 		return err
 	}
+
+	// Set up signal handling and wait for shutdown. The API and the scheduler are stopped first, so no new notifications are queued.
+	// The instance deregisters after its last check, so the other instances take over its urls right away
+	return waitForShutdown(cancel, server, sched, membership, notif)
+}
+
+// runAgent runs the checks of the urls assigned to the location of the agent and pushes their results to the collector.
+// The collector keeps the state of the urls, so the agent needs neither a database nor an API
+func runAgent() error {
+	log.Println("Starting Website Monitor agent...")
+
+	cfg, err := config.LoadAgent()
+	if err != nil {
+		log.Printf("Failed to load configuration: %v", err)
+
+		return err
+	}
+
+	client := agent.NewClient(cfg.Agent)
+	sched := scheduler.New(client, nil, agent.NewChecker(checker.New(nil, cfg.Checker), client), cfg.Scheduler)
+
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Set up signal handling and wait for shutdown
+	if err := sched.Start(ctx); err != nil {
+		log.Printf("Failed to start scheduler: %v", err)
+
+		return err
+	}
+
+	log.Printf("Agent at %s pushing results to %s", cfg.Agent.Location, cfg.Agent.CollectorURL)
+
 	return waitForShutdown(cancel, sched)
 }
 
@@ -57,16 +130,56 @@ func connectToDatabase() (*db.DB, error) {
 	return database, nil
 }
 
-func setupScheduler(database *db.DB) (*scheduler.Scheduler, context.CancelFunc, error) {
+// setupScheduler starts the scheduler. With a membership, the instance joins the other instances sharing the database
+// and only checks the urls it owns, otherwise it checks all urls
+func setupScheduler(database *db.DB, cfg *models.Config, tracker *state.Tracker, mtr *metrics.Metrics, membership *cluster.Membership) (*scheduler.Scheduler, context.CancelFunc, error) {
 	repo := url_repository.New(database)
-	chk := checker.New(database)
-	sched := scheduler.New(repo, database, chk)
+	chk := checker.New(database, cfg.Checker)
+	sched := scheduler.New(repo, database, chk, cfg.Scheduler)
+	// Metrics read the state computed by the tracker, so the tracker has to observe results first
+	sched.AddObserver(tracker)
+	sched.AddObserver(mtr)
+	sched.SetRecorder(mtr)
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 
-	if err := sched.Start(ctx); err != nil {
+	if membership != nil {
+		tracker.SetShard(membership)
+		sched.SetShard(membership)
+		membership.OnChange(func() {
+			// Open incidents of urls that moved here have to be known before their first check
+			if err := tracker.Load(ctx); err != nil {
+				log.Printf("Failed to reload open incidents: %v", err)
+			}
+			sched.Rebalance()
+		})
+
+		if err := membership.Start(ctx); err != nil {
+			cancel()
+			log.Printf("Failed to join cluster: %v", err)
+
+			return nil, nil, err
+		}
+	}
+
+	// leave undoes joining the cluster if the scheduler cannot start
+	leave := func() {
 		cancel()
+		if membership != nil {
+			membership.Stop()
+		}
+	}
+
+	if err := tracker.Load(ctx); err != nil {
+		leave()
+		log.Printf("Failed to load open incidents: %v", err)
+
+		return nil, nil, err
+	}
+
+	if err := sched.Start(ctx); err != nil {
+		leave()
 		log.Printf("Failed to start scheduler: %v", err)
 
 		return nil, nil, err
@@ -77,7 +190,28 @@ func setupScheduler(database *db.DB) (*scheduler.Scheduler, context.CancelFunc,
 	return sched, cancel, nil
 }
 
-func waitForShutdown(cancel context.CancelFunc, sched *scheduler.Scheduler) error {
+func startApi(database *db.DB, cfg models.ApiConfig, mtr *metrics.Metrics, loadErrors api.LoadErrorSource, collector api.Collector, router api.Router) (*api.Server, error) {
+	server := api.New(url_repository.New(database), check_repository.New(database))
+	server.Handle("/metrics", mtr.Handler())
+	server.SetLoadErrors(loadErrors)
+	server.SetToken(cfg.Token)
+	if cfg.Token == "" {
+		log.Printf("API_TOKEN is not set, urls cannot be changed through the API")
+	}
+	if len(cfg.AgentTokens) > 0 {
+		server.SetAgents(cfg.AgentTokens, collector, router)
+	}
+
+	if err := server.Start(cfg.Addr); err != nil {
+		log.Printf("Failed to start API: %v", err)
+
+		return nil, err
+	}
+
+	return server, nil
+}
+
+func waitForShutdown(cancel context.CancelFunc, components ...scheduler.Stoppable) error {
 	// Set up signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -89,15 +223,17 @@ func waitForShutdown(cancel context.CancelFunc, sched *scheduler.Scheduler) erro
 	log.Println("Received shutdown signal")
 
 	// Graceful shutdown
-	return performGracefulShutdown(cancel, sched)
+	return performGracefulShutdown(cancel, components...)
 }
 
-func performGracefulShutdown(cancel context.CancelFunc, sched scheduler.Stoppable) error {
+func performGracefulShutdown(cancel context.CancelFunc, components ...scheduler.Stoppable) error {
 	// Cancel context to signal all goroutines to stop
 	cancel()
 
-	// Stop scheduler and wait for all goroutines to finish
-	sched.Stop()
+	// Stop components in order and wait for their goroutines to finish
+	for _, component := range components {
+		component.Stop()
+	}
 
 	log.Println("Website Monitor stopped")
 
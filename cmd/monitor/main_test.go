@@ -4,6 +4,10 @@ import (
 	"context"
 	"testing"
 	"website-monitor/internal/db"
+	"website-monitor/internal/incident_repository"
+	"website-monitor/internal/metrics"
+	"website-monitor/internal/models"
+	"website-monitor/internal/state"
 
 	"github.com/DATA-DOG/go-sqlmock"
 )
@@ -23,10 +27,14 @@ func TestSetupScheduler_Success(t *testing.T) {
 	}
 	defer sqlDB.Close()
 
-	mock.ExpectQuery(`SELECT id, url, check_interval_sec, COALESCE\(regex_pattern, ''\) FROM monitored_urls`).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "check_interval_sec", "regex_pattern"}))
+	mock.ExpectQuery(`SELECT (.+) FROM incidents WHERE ended_at IS NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url_id", "url", "started_at", "cause", "first_check_id"}))
+	mock.ExpectQuery(`SELECT (.+) FROM monitored_urls`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "check_interval_sec", "regex_pattern", "method", "headers", "body", "expected_status_codes", "paused", "json_assertions", "max_body_bytes", "retry_attempts", "retry_delay_ms", "retry_on", "redirect_policy", "max_redirects", "timeout_ms", "slow_threshold_ms", "auth", "overlap_policy", "max_concurrent", "locations", "quorum"}))
 
-	sched, cancel, err := setupScheduler(db.New(sqlDB))
+	database := db.New(sqlDB)
+	tracker := state.New(incident_repository.New(database), models.StateConfig{})
+	sched, cancel, err := setupScheduler(database, &models.Config{}, tracker, metrics.New(tracker), nil)
 
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
@@ -47,7 +55,30 @@ func TestSetupScheduler_NilDatabase(t *testing.T) {
 		}
 	}()
 
-	_, _, _ = setupScheduler(nil)
+	tracker := state.New(incident_repository.New(nil), models.StateConfig{})
+	_, _, _ = setupScheduler(nil, &models.Config{}, tracker, metrics.New(tracker), nil)
+}
+
+func TestStartApi_Success(t *testing.T) {
+	sqlDB, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating mock db: %v", err)
+	}
+	defer sqlDB.Close()
+
+	server, err := startApi(db.New(sqlDB), models.ApiConfig{Addr: "127.0.0.1:0"}, metrics.New(nil), nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	server.Stop()
+}
+
+func TestStartApi_InvalidAddress(t *testing.T) {
+	_, err := startApi(nil, models.ApiConfig{Addr: "invalid-address"}, metrics.New(nil), nil, nil, nil)
+	if err == nil {
+		t.Error("Expected error for invalid address")
+	}
 }
 
 func TestPerformGracefulShutdown(t *testing.T) {
@@ -72,3 +103,27 @@ func TestPerformGracefulShutdown(t *testing.T) {
 		t.Error("Expected context to be cancelled")
 	}
 }
+
+func TestPerformGracefulShutdown_StopsAllComponentsInOrder(t *testing.T) {
+	var order []string
+	first := &orderedStoppable{name: "scheduler", order: &order}
+	second := &orderedStoppable{name: "notifier", order: &order}
+	_, cancel := context.WithCancel(context.Background())
+
+	if err := performGracefulShutdown(cancel, first, second); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	if len(order) != 2 || order[0] != "scheduler" || order[1] != "notifier" {
+		t.Errorf("Expected components to be stopped in order, got %v", order)
+	}
+}
+
+type orderedStoppable struct {
+	name  string
+	order *[]string
+}
+
+func (o *orderedStoppable) Stop() {
+	*o.order = append(*o.order, o.name)
+}
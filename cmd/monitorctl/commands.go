@@ -0,0 +1,570 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"website-monitor/internal/assertion"
+	"website-monitor/internal/checker"
+	"website-monitor/internal/models"
+	"website-monitor/internal/url_repository"
+	"website-monitor/internal/validator"
+)
+
+// urlFlags holds the flags describing a monitored url, shared by add, update and check
+type urlFlags struct {
+	url                 string
+	interval            int
+	regex               string
+	method              string
+	headers             headerFlags
+	body                string
+	expectedStatusCodes string
+	paused              bool
+	maxBodyBytes        int
+	retryAttempts       int
+	retryDelayMs        int
+	retryOn             string
+	redirectPolicy      string
+	maxRedirects        int
+	timeoutMs           int
+	slowThresholdMs     int
+	auth                authFlag
+	overlapPolicy       string
+	maxConcurrent       int
+	locations           string
+	quorum              int
+	jsonAssertions      jsonAssertionFlags
+	assertions          contentAssertionFlags
+}
+
+// register adds the url flags to the flag set
+func (f *urlFlags) register(fs *flag.FlagSet) {
+	f.headers = headerFlags{}
+
+	fs.StringVar(&f.url, "url", "", "address of the website, e.g. https://example.com")
+	fs.IntVar(&f.interval, "interval", 60, "check interval in seconds (5-300)")
+	fs.StringVar(&f.regex, "regex", "", "regex pattern the page has to match")
+	fs.StringVar(&f.method, "method", "GET", "HTTP method of the check request")
+	fs.Var(f.headers, "header", `request header as "Name: value", can be repeated`)
+	fs.StringVar(&f.body, "body", "", "request body")
+	fs.StringVar(&f.expectedStatusCodes, "expected-status", "", `acceptable status codes, e.g. "200-299,301"`)
+	fs.BoolVar(&f.paused, "paused", false, "store the url without checking it")
+	fs.IntVar(&f.retryAttempts, "retry-attempts", 0, "attempts of a failing check before it is recorded (0-5)")
+	fs.IntVar(&f.retryDelayMs, "retry-delay-ms", 0, "delay between attempts in milliseconds")
+	fs.StringVar(&f.retryOn, "retry-on", "", `failures that are retried, e.g. "network,timeout,502-504"`)
+	fs.IntVar(&f.timeoutMs, "timeout-ms", 0, "timeout of a check in milliseconds, 0 for 30 seconds or the interval if shorter")
+	fs.IntVar(&f.slowThresholdMs, "slow-ms", 0, "response time in milliseconds above which the url is degraded, 0 to disable")
+	fs.Var(&f.auth, "auth", `authentication as "basic <user> <PASSWORD_ENV>", "bearer <TOKEN_ENV>", "oauth2 <token url> <client id> <SECRET_ENV> [scope...]" or "none"`)
+	fs.StringVar(&f.redirectPolicy, "redirects", "", "redirect policy: follow, none or same_host")
+	fs.IntVar(&f.maxRedirects, "max-redirects", 0, "redirects followed before the check fails, 0 for the default of 10")
+	fs.StringVar(&f.overlapPolicy, "overlap", "", "what happens to a check that is due while the previous one still runs: skip, queue or concurrent")
+	fs.IntVar(&f.maxConcurrent, "max-concurrent", 0, "checks that run at the same time under the concurrent policy, 0 for the default of 2")
+	fs.StringVar(&f.locations, "locations", "", `comma separated locations the url is checked from, e.g. "eu-west,us-east,ap-south", empty for the monitor itself`)
+	fs.IntVar(&f.quorum, "quorum", 0, "locations that have to find the url down before it is considered down, 0 for a majority")
+	fs.IntVar(&f.maxBodyBytes, "max-body-bytes", 0, "how much of the body the regex and assertions scan, 0 for the default of 64KB")
+	fs.Var(&f.assertions, "assert", `content assertion as "<type> [header] [value]", e.g. "not_contains Maintenance" or "body_size 100-5000", can be repeated`)
+	fs.Var(&f.jsonAssertions, "json-assert", `JSON assertion as "<path> <op> [value]", e.g. '$.status equals "ok"', can be repeated`)
+}
+
+// apply copies the flags that were set on the command line to the url
+func (f *urlFlags) apply(fs *flag.FlagSet, url *models.MonitoredUrl) {
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "url":
+			url.Url = f.url
+		case "interval":
+			url.CheckIntervalSec = f.interval
+		case "regex":
+			url.RegexPattern = f.regex
+		case "method":
+			url.Method = f.method
+		case "header":
+			url.Headers = f.headers
+		case "body":
+			url.Body = f.body
+		case "expected-status":
+			url.ExpectedStatusCodes = f.expectedStatusCodes
+		case "paused":
+			url.Paused = f.paused
+		case "max-body-bytes":
+			url.MaxBodyBytes = f.maxBodyBytes
+		case "retry-attempts":
+			url.RetryAttempts = f.retryAttempts
+		case "retry-delay-ms":
+			url.RetryDelayMs = f.retryDelayMs
+		case "retry-on":
+			url.RetryOn = f.retryOn
+		case "timeout-ms":
+			url.TimeoutMs = f.timeoutMs
+		case "slow-ms":
+			url.SlowThresholdMs = f.slowThresholdMs
+		case "auth":
+			url.Auth = f.auth.auth
+		case "redirects":
+			url.RedirectPolicy = f.redirectPolicy
+		case "max-redirects":
+			url.MaxRedirects = f.maxRedirects
+		case "overlap":
+			url.OverlapPolicy = f.overlapPolicy
+		case "max-concurrent":
+			url.MaxConcurrent = f.maxConcurrent
+		case "locations":
+			url.Locations = splitList(f.locations)
+		case "quorum":
+			url.Quorum = f.quorum
+		case "json-assert":
+			url.JSONAssertions = f.jsonAssertions
+		case "assert":
+			url.Assertions = f.assertions
+		}
+	})
+}
+
+// defaults returns a url with the default values of the flags
+func (f *urlFlags) defaults() models.MonitoredUrl {
+	return models.MonitoredUrl{
+		CheckIntervalSec: f.interval,
+		Method:           f.method,
+	}
+}
+
+// headerFlags collects repeated -header flags
+type headerFlags map[string]string
+
+func (h headerFlags) String() string {
+	pairs := make([]string, 0, len(h))
+	for name, value := range h {
+		pairs = append(pairs, name+": "+value)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ", ")
+}
+
+func (h headerFlags) Set(value string) error {
+	name, headerValue, ok := strings.Cut(value, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf(`header must be formatted as "Name: value"`)
+	}
+
+	h[strings.TrimSpace(name)] = strings.TrimSpace(headerValue)
+
+	return nil
+}
+
+// jsonAssertionFlags collects repeated -json-assert flags
+type jsonAssertionFlags []models.JSONAssertion
+
+func (a *jsonAssertionFlags) String() string {
+	parts := make([]string, 0, len(*a))
+	for _, item := range *a {
+		parts = append(parts, item.Path+" "+item.Op)
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func (a *jsonAssertionFlags) Set(value string) error {
+	path, rest, _ := strings.Cut(strings.TrimSpace(value), " ")
+	op, rawValue, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	if path == "" || op == "" {
+		return fmt.Errorf(`json assertion must be formatted as "<path> <op> [value]"`)
+	}
+
+	item := models.JSONAssertion{Path: path, Op: op}
+	if rawValue = strings.TrimSpace(rawValue); rawValue != "" {
+		// Values are JSON, anything else is taken as a plain string
+		if err := json.Unmarshal([]byte(rawValue), &item.Value); err != nil {
+			item.Value = rawValue
+		}
+	}
+
+	*a = append(*a, item)
+
+	return nil
+}
+
+// contentAssertionFlags collects repeated -assert flags
+type contentAssertionFlags []models.ContentAssertion
+
+func (a *contentAssertionFlags) String() string {
+	types := make([]string, 0, len(*a))
+	for _, item := range *a {
+		types = append(types, item.Type)
+	}
+
+	return strings.Join(types, ", ")
+}
+
+func (a *contentAssertionFlags) Set(value string) error {
+	assertionType, rest, _ := strings.Cut(strings.TrimSpace(value), " ")
+	rest = strings.TrimSpace(rest)
+	item := models.ContentAssertion{Type: assertionType}
+
+	switch assertionType {
+	case "header_equals", "header_matches":
+		header, headerValue, _ := strings.Cut(rest, " ")
+		item.Header = header
+		item.Value = strings.TrimSpace(headerValue)
+	case "body_size":
+		// Bounds are given as "min-max", either side may be left out
+		minBytes, maxBytes, ok := strings.Cut(rest, "-")
+		if !ok {
+			return fmt.Errorf(`body_size must be formatted as "<min>-<max>", e.g. "100-" or "-5000"`)
+		}
+
+		for _, bound := range []struct {
+			text   string
+			target **int
+		}{{minBytes, &item.MinBytes}, {maxBytes, &item.MaxBytes}} {
+			if bound.text == "" {
+				continue
+			}
+
+			parsed, err := strconv.Atoi(strings.TrimSpace(bound.text))
+			if err != nil {
+				return fmt.Errorf("invalid body size %q", bound.text)
+			}
+			*bound.target = &parsed
+		}
+	default:
+		item.Value = rest
+	}
+
+	*a = append(*a, item)
+
+	return nil
+}
+
+// authFlag parses the -auth flag. Secrets are given as names of environment variables of the monitor
+type authFlag struct {
+	auth *models.Auth
+}
+
+func (a *authFlag) String() string {
+	if a.auth == nil {
+		return ""
+	}
+
+	return a.auth.Type
+}
+
+func (a *authFlag) Set(value string) error {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return fmt.Errorf("auth needs a type")
+	}
+
+	auth := models.Auth{Type: fields[0]}
+	args := fields[1:]
+
+	switch {
+	case auth.Type == "none" && len(args) == 0:
+		a.auth = nil
+
+		return nil
+	case auth.Type == checker.AuthBasic && len(args) == 2:
+		auth.Username, auth.PasswordEnv = args[0], args[1]
+	case auth.Type == checker.AuthBearer && len(args) == 1:
+		auth.TokenEnv = args[0]
+	case auth.Type == checker.AuthOAuth2 && len(args) >= 3:
+		auth.TokenURL, auth.ClientID, auth.ClientSecretEnv = args[0], args[1], args[2]
+		auth.Scopes = args[3:]
+		if len(auth.Scopes) == 0 {
+			auth.Scopes = nil
+		}
+	default:
+		return fmt.Errorf(`auth must be formatted as "basic <user> <PASSWORD_ENV>", "bearer <TOKEN_ENV>", "oauth2 <token url> <client id> <SECRET_ENV> [scope...]" or "none"`)
+	}
+
+	a.auth = &auth
+
+	return nil
+}
+
+func runList(ctx context.Context, repo url_repository.UrlManager, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	fs.SetOutput(out)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	urls, err := repo.ListMonitoredUrls(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Urls the monitor refuses to check, e.g. because of an invalid regex, are listed with the reason
+	invalid := checker.NewPatterns().Prepare(urls)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tURL\tINTERVAL\tMETHOD\tREGEX\tEXPECTED STATUS\tPAUSED\tERROR")
+	for _, url := range urls {
+		var loadError string
+		if err, ok := invalid[url.ID]; ok {
+			loadError = err.Error()
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%ds\t%s\t%s\t%s\t%v\t%s\n",
+			url.ID, url.Url, url.CheckIntervalSec, url.Method, url.RegexPattern, url.ExpectedStatusCodes, url.Paused, loadError)
+	}
+
+	return w.Flush()
+}
+
+func runAdd(ctx context.Context, repo url_repository.UrlManager, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	fs.SetOutput(out)
+	var flags urlFlags
+	flags.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	url := flags.defaults()
+	flags.apply(fs, &url)
+
+	url, err := validate(url)
+	if err != nil {
+		return err
+	}
+
+	created, err := repo.CreateMonitoredUrl(ctx, url)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Added %s with id %d\n", created.Url, created.ID)
+
+	return nil
+}
+
+func runUpdate(ctx context.Context, repo url_repository.UrlManager, args []string, out io.Writer) error {
+	id, args, err := parseIDArg(args)
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("update", flag.ContinueOnError)
+	fs.SetOutput(out)
+	var flags urlFlags
+	flags.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	url, err := repo.GetMonitoredUrl(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	flags.apply(fs, &url)
+
+	url, err = validate(url)
+	if err != nil {
+		return err
+	}
+
+	if _, err := repo.UpdateMonitoredUrl(ctx, url); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Updated %s (id %d)\n", url.Url, url.ID)
+
+	return nil
+}
+
+func runRemove(ctx context.Context, repo url_repository.UrlManager, args []string, out io.Writer) error {
+	id, _, err := parseIDArg(args)
+	if err != nil {
+		return err
+	}
+
+	if err := repo.DeleteMonitoredUrl(ctx, id); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Removed url %d\n", id)
+
+	return nil
+}
+
+func runSetPaused(ctx context.Context, repo url_repository.UrlManager, args []string, paused bool, out io.Writer) error {
+	id, _, err := parseIDArg(args)
+	if err != nil {
+		return err
+	}
+
+	if err := repo.SetPaused(ctx, id, paused); err != nil {
+		return err
+	}
+
+	if paused {
+		fmt.Fprintf(out, "Paused url %d\n", id)
+	} else {
+		fmt.Fprintf(out, "Resumed url %d\n", id)
+	}
+
+	return nil
+}
+
+// runCheck checks a stored url by id, or an address configured by flags, and prints the result.
+// It returns an error if the check failed, so scripts can rely on the exit code
+func runCheck(ctx context.Context, repo url_repository.UrlManager, chk checker.IChecker, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("check needs an id or an address")
+	}
+
+	target, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	fs.SetOutput(out)
+	var flags urlFlags
+	flags.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var url models.MonitoredUrl
+	if isID(target) {
+		id, _ := strconv.Atoi(target)
+
+		stored, err := repo.GetMonitoredUrl(ctx, id)
+		if err != nil {
+			return err
+		}
+		url = stored
+	} else {
+		url = flags.defaults()
+		url.Url = target
+	}
+	flags.apply(fs, &url)
+
+	url, err := validate(url)
+	if err != nil {
+		return err
+	}
+
+	result := chk.Check(ctx, url)
+	printResult(out, result)
+
+	if result.Failed() {
+		return fmt.Errorf("check of %s failed", url.Url)
+	}
+
+	return nil
+}
+
+// printResult prints a check result in a human readable form
+func printResult(out io.Writer, result models.CheckResult) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "URL:\t%s\n", result.URL)
+	fmt.Fprintf(w, "Checked at:\t%s\n", result.CheckTimestamp.Format("2006-01-02 15:04:05 MST"))
+	for _, hop := range result.Redirects {
+		fmt.Fprintf(w, "Redirect:\t%s -> %d -> %s\n", hop.URL, hop.Status, hop.Location)
+	}
+	if result.HttpStatus != nil {
+		fmt.Fprintf(w, "HTTP status:\t%d\n", *result.HttpStatus)
+	}
+	if result.ResponseTimeMs != nil {
+		fmt.Fprintf(w, "Response time:\t%d ms\n", *result.ResponseTimeMs)
+	}
+	for _, phase := range []struct {
+		name string
+		ms   *int
+	}{
+		{"DNS lookup", result.DnsMs},
+		{"Connect", result.ConnectMs},
+		{"TLS handshake", result.TlsHandshakeMs},
+		{"Time to first byte", result.TimeToFirstByteMs},
+		{"Transfer", result.TransferMs},
+	} {
+		if phase.ms != nil {
+			fmt.Fprintf(w, "  %s:\t%d ms\n", phase.name, *phase.ms)
+		}
+	}
+	if result.RegexMatch != nil {
+		fmt.Fprintf(w, "Regex match:\t%v\n", *result.RegexMatch)
+	}
+	if result.BodyTruncated {
+		fmt.Fprintf(w, "Body truncated:\t%v\n", result.BodyTruncated)
+	}
+	if result.CertExpiresAt != nil && result.CertDaysRemaining != nil {
+		fmt.Fprintf(w, "Certificate expires:\t%s (%d days)\n", result.CertExpiresAt.Format("2006-01-02 15:04:05 MST"), *result.CertDaysRemaining)
+	}
+	if result.Warning != "" {
+		fmt.Fprintf(w, "Warning:\t%s\n", result.Warning)
+	}
+	for _, r := range result.AssertionResults {
+		outcome := "passed"
+		if !r.Passed {
+			outcome = "failed"
+		}
+		fmt.Fprintf(w, "Assertion %s:\t%s\n", assertion.Describe(r), outcome)
+	}
+	if result.Attempts > 1 {
+		fmt.Fprintf(w, "Attempts:\t%d\n", result.Attempts)
+	}
+	for i, attemptError := range result.AttemptErrors {
+		fmt.Fprintf(w, "  Attempt %d failed:\t%s\n", i+1, attemptError)
+	}
+	if result.Error != "" {
+		fmt.Fprintf(w, "Error:\t%s\n", result.Error)
+	}
+
+	_ = w.Flush()
+}
+
+// validate normalizes the url and checks it can be stored
+func validate(url models.MonitoredUrl) (models.MonitoredUrl, error) {
+	url = validator.Normalize(url)
+
+	var errs validator.Errors
+	if err := validator.ValidateMonitoredUrl(url); errors.As(err, &errs) {
+		return url, fmt.Errorf("invalid monitored url:\n  %s", strings.Join(errs, "\n  "))
+	} else if err != nil {
+		return url, err
+	}
+
+	return url, nil
+}
+
+// parseIDArg parses the leading id argument of a command
+func parseIDArg(args []string) (int, []string, error) {
+	if len(args) == 0 || !isID(args[0]) {
+		return 0, args, fmt.Errorf("the first argument must be the id of a monitored url")
+	}
+
+	id, _ := strconv.Atoi(args[0])
+
+	return id, args[1:], nil
+}
+
+// isID reports whether the argument is a numeric id rather than an address
+func isID(arg string) bool {
+	id, err := strconv.Atoi(arg)
+
+	return err == nil && id > 0
+}
+
+// splitList splits a comma separated flag value, skipping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
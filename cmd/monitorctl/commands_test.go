@@ -0,0 +1,447 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"website-monitor/internal/checker"
+	"website-monitor/internal/models"
+	"website-monitor/internal/url_repository"
+)
+
+type mockUrlManager struct {
+	urls   map[int]models.MonitoredUrl
+	nextID int
+}
+
+func newMockUrlManager(urls ...models.MonitoredUrl) *mockUrlManager {
+	m := &mockUrlManager{urls: make(map[int]models.MonitoredUrl)}
+	for _, url := range urls {
+		m.urls[url.ID] = url
+		if url.ID > m.nextID {
+			m.nextID = url.ID
+		}
+	}
+	return m
+}
+
+func (m *mockUrlManager) ListMonitoredUrls(ctx context.Context) ([]models.MonitoredUrl, error) {
+	var urls []models.MonitoredUrl
+	for id := 1; id <= m.nextID; id++ {
+		if url, ok := m.urls[id]; ok {
+			urls = append(urls, url)
+		}
+	}
+	return urls, nil
+}
+
+func (m *mockUrlManager) GetMonitoredUrl(ctx context.Context, id int) (models.MonitoredUrl, error) {
+	url, ok := m.urls[id]
+	if !ok {
+		return url, url_repository.ErrNotFound
+	}
+	return url, nil
+}
+
+func (m *mockUrlManager) CreateMonitoredUrl(ctx context.Context, url models.MonitoredUrl) (models.MonitoredUrl, error) {
+	m.nextID++
+	url.ID = m.nextID
+	m.urls[url.ID] = url
+	return url, nil
+}
+
+func (m *mockUrlManager) UpdateMonitoredUrl(ctx context.Context, url models.MonitoredUrl) (models.MonitoredUrl, error) {
+	if _, ok := m.urls[url.ID]; !ok {
+		return url, url_repository.ErrNotFound
+	}
+	m.urls[url.ID] = url
+	return url, nil
+}
+
+func (m *mockUrlManager) DeleteMonitoredUrl(ctx context.Context, id int) error {
+	if _, ok := m.urls[id]; !ok {
+		return url_repository.ErrNotFound
+	}
+	delete(m.urls, id)
+	return nil
+}
+
+func (m *mockUrlManager) SetPaused(ctx context.Context, id int, paused bool) error {
+	url, ok := m.urls[id]
+	if !ok {
+		return url_repository.ErrNotFound
+	}
+	url.Paused = paused
+	m.urls[id] = url
+	return nil
+}
+
+func (m *mockUrlManager) ApplyChanges(ctx context.Context, changes url_repository.Changes) error {
+	for _, id := range changes.Delete {
+		if err := m.DeleteMonitoredUrl(ctx, id); err != nil {
+			return err
+		}
+	}
+	for _, url := range changes.Update {
+		if _, err := m.UpdateMonitoredUrl(ctx, url); err != nil {
+			return err
+		}
+	}
+	for _, url := range changes.Create {
+		if _, err := m.CreateMonitoredUrl(ctx, url); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRunAdd_HappyPath(t *testing.T) {
+	repo := newMockUrlManager()
+	var out bytes.Buffer
+
+	err := runAdd(context.Background(), repo, []string{
+		"-url", "https://example.com", "-interval", "30", "-method", "head", "-header", "Accept: text/html",
+	}, &out)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	url := repo.urls[1]
+	if url.Url != "https://example.com" || url.CheckIntervalSec != 30 || url.Method != "HEAD" {
+		t.Errorf("Unexpected stored url %+v", url)
+	}
+
+	if url.Headers["Accept"] != "text/html" {
+		t.Errorf("Expected Accept header to be stored, got %v", url.Headers)
+	}
+
+	if !strings.Contains(out.String(), "with id 1") {
+		t.Errorf("Expected id in output, got %q", out.String())
+	}
+}
+
+func TestRunAdd_Locations(t *testing.T) {
+	repo := newMockUrlManager()
+
+	err := runAdd(context.Background(), repo, []string{
+		"-url", "https://example.com", "-locations", "eu-west, us-east,ap-south", "-quorum", "3",
+	}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	url := repo.urls[1]
+	if !reflect.DeepEqual(url.Locations, []string{"eu-west", "us-east", "ap-south"}) || url.Quorum != 3 {
+		t.Errorf("Expected locations and quorum to be stored, got %v and %d", url.Locations, url.Quorum)
+	}
+}
+
+func TestRunAdd_InvalidUrl(t *testing.T) {
+	repo := newMockUrlManager()
+
+	err := runAdd(context.Background(), repo, []string{"-url", "example.com", "-interval", "1"}, &bytes.Buffer{})
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+
+	if len(repo.urls) != 0 {
+		t.Error("Expected invalid url not to be stored")
+	}
+}
+
+func TestRunUpdate_OnlyChangesGivenFlags(t *testing.T) {
+	repo := newMockUrlManager(models.MonitoredUrl{
+		ID: 1, Url: "https://example.com", CheckIntervalSec: 30, Method: "GET", RegexPattern: "Example",
+	})
+
+	err := runUpdate(context.Background(), repo, []string{"1", "-interval", "120"}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	url := repo.urls[1]
+	if url.CheckIntervalSec != 120 {
+		t.Errorf("Expected interval 120, got %d", url.CheckIntervalSec)
+	}
+
+	if url.RegexPattern != "Example" {
+		t.Errorf("Expected regex to be kept, got %q", url.RegexPattern)
+	}
+}
+
+func TestRunSetPaused_PauseAndResume(t *testing.T) {
+	repo := newMockUrlManager(models.MonitoredUrl{ID: 1, Url: "https://example.com", CheckIntervalSec: 30})
+
+	if err := runSetPaused(context.Background(), repo, []string{"1"}, true, &bytes.Buffer{}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !repo.urls[1].Paused {
+		t.Error("Expected url to be paused")
+	}
+
+	if err := runSetPaused(context.Background(), repo, []string{"1"}, false, &bytes.Buffer{}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if repo.urls[1].Paused {
+		t.Error("Expected url to be resumed")
+	}
+}
+
+func TestRunRemove_MissingID(t *testing.T) {
+	if err := runRemove(context.Background(), newMockUrlManager(), nil, &bytes.Buffer{}); err == nil {
+		t.Fatal("Expected error without id")
+	}
+}
+
+func TestRunList_PrintsUrls(t *testing.T) {
+	repo := newMockUrlManager(
+		models.MonitoredUrl{ID: 1, Url: "https://example.com", CheckIntervalSec: 30, Method: "GET"},
+		models.MonitoredUrl{ID: 2, Url: "https://google.com", CheckIntervalSec: 60, Method: "HEAD", Paused: true},
+		models.MonitoredUrl{ID: 3, Url: "https://github.com", CheckIntervalSec: 60, Method: "GET", RegexPattern: "[invalid"},
+	)
+	var out bytes.Buffer
+
+	if err := runList(context.Background(), repo, nil, &out); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for _, expected := range []string{"https://example.com", "https://google.com", "true", "invalid regex: regex_pattern is invalid"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+}
+
+func TestRunCheck_AdHocAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello"))
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+
+	err := runCheck(context.Background(), nil, checker.New(nil, models.CheckerConfig{}), []string{server.URL, "-regex", "Hello"}, &out)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "200") || !strings.Contains(out.String(), "true") {
+		t.Errorf("Unexpected output:\n%s", out.String())
+	}
+}
+
+func TestRunCheck_FailedCheckReturnsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	repo := newMockUrlManager(models.MonitoredUrl{
+		ID: 1, Url: server.URL, CheckIntervalSec: 30, ExpectedStatusCodes: "200",
+	})
+
+	err := runCheck(context.Background(), repo, checker.New(nil, models.CheckerConfig{}), []string{"1"}, &bytes.Buffer{})
+	if err == nil {
+		t.Fatal("Expected error for failed check")
+	}
+}
+
+func TestRun_UnknownCommand(t *testing.T) {
+	if err := run(context.Background(), nil, &bytes.Buffer{}); err == nil {
+		t.Fatal("Expected error without command")
+	}
+
+	// Neither the configuration nor the database is needed to reject the command
+	t.Setenv("TLS_EXPIRY_WARNING_DAYS", "invalid")
+	t.Setenv("DB_HOST", "")
+
+	var out bytes.Buffer
+	err := run(context.Background(), []string{"lsit"}, &out)
+	if err == nil || err.Error() != `unknown command "lsit"` {
+		t.Fatalf("Expected unknown command error, got: %v", err)
+	}
+
+	if !strings.HasPrefix(out.String(), "Usage: monitorctl") {
+		t.Errorf("Expected usage, got %q", out.String())
+	}
+}
+
+func TestRun_HelpWithoutConfig(t *testing.T) {
+	t.Setenv("TLS_EXPIRY_WARNING_DAYS", "invalid")
+
+	var out bytes.Buffer
+	if err := run(context.Background(), []string{"help"}, &out); err != nil {
+		t.Fatalf("Expected help without a valid configuration, got: %v", err)
+	}
+
+	if !strings.HasPrefix(out.String(), "Usage: monitorctl") {
+		t.Errorf("Expected usage, got %q", out.String())
+	}
+
+	out.Reset()
+	if err := run(context.Background(), nil, &out); err == nil || !strings.HasPrefix(out.String(), "Usage: monitorctl") {
+		t.Errorf("Expected usage and an error without a command, got %v and %q", err, out.String())
+	}
+}
+
+func TestRunExport_ThenSync(t *testing.T) {
+	repo := newMockUrlManager(
+		models.MonitoredUrl{ID: 1, Url: "https://example.com", CheckIntervalSec: 30, Method: "GET"},
+		models.MonitoredUrl{ID: 2, Url: "https://google.com", CheckIntervalSec: 60, Method: "GET"},
+	)
+	path := filepath.Join(t.TempDir(), "urls.yaml")
+
+	if err := runExport(context.Background(), repo, []string{"-o", path}, &bytes.Buffer{}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected export file, got: %v", err)
+	}
+
+	// Drop google.com and change the interval of example.com
+	data = []byte(strings.Replace(string(data), "check_interval_sec: 30", "check_interval_sec: 90", 1))
+	data = []byte(strings.Replace(string(data), "url: https://google.com", "url: https://new.example.com", 1))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runApply(context.Background(), repo, "sync", []string{path, "-dry-run"}, true, &out); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "Plan: 1 to create, 1 to update, 1 to delete") {
+		t.Errorf("Unexpected plan:\n%s", out.String())
+	}
+
+	if len(repo.urls) != 2 || repo.urls[1].CheckIntervalSec != 30 {
+		t.Fatalf("Expected dry run not to change urls, got %+v", repo.urls)
+	}
+
+	if err := runApply(context.Background(), repo, "sync", []string{path}, true, &bytes.Buffer{}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if repo.urls[1].CheckIntervalSec != 90 {
+		t.Errorf("Expected interval 90, got %d", repo.urls[1].CheckIntervalSec)
+	}
+
+	if _, ok := repo.urls[2]; ok {
+		t.Error("Expected google.com to be removed")
+	}
+
+	if url, ok := repo.urls[3]; !ok || url.Url != "https://new.example.com" {
+		t.Errorf("Expected new.example.com to be added, got %+v", repo.urls)
+	}
+}
+
+func TestRunApply_ImportKeepsUnlistedUrls(t *testing.T) {
+	repo := newMockUrlManager(models.MonitoredUrl{ID: 1, Url: "https://example.com", CheckIntervalSec: 30, Method: "GET"})
+	path := filepath.Join(t.TempDir(), "urls.json")
+	if err := os.WriteFile(path, []byte(`{"urls": [{"url": "https://google.com", "check_interval_sec": 60}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runApply(context.Background(), repo, "import", []string{path}, false, &bytes.Buffer{}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(repo.urls) != 2 {
+		t.Errorf("Expected both urls to be stored, got %+v", repo.urls)
+	}
+}
+
+func TestRunAdd_JSONAssertions(t *testing.T) {
+	repo := newMockUrlManager()
+
+	err := runAdd(context.Background(), repo, []string{
+		"-url", "https://example.com/health",
+		"-json-assert", `$.status equals "ok"`,
+		"-json-assert", "$.checks.length() gte 2",
+		"-json-assert", "$.version exists",
+	}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []models.JSONAssertion{
+		{Path: "$.status", Op: "equals", Value: "ok"},
+		{Path: "$.checks.length()", Op: "gte", Value: float64(2)},
+		{Path: "$.version", Op: "exists"},
+	}
+	if !reflect.DeepEqual(repo.urls[1].JSONAssertions, expected) {
+		t.Errorf("Expected assertions %+v, got %+v", expected, repo.urls[1].JSONAssertions)
+	}
+}
+
+func TestRunAdd_ContentAssertions(t *testing.T) {
+	repo := newMockUrlManager()
+
+	err := runAdd(context.Background(), repo, []string{
+		"-url", "https://example.com",
+		"-assert", "not_contains Database error",
+		"-assert", "header_matches Content-Type ^text/html",
+		"-assert", "body_size 100-",
+	}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	minBytes := 100
+	expected := []models.ContentAssertion{
+		{Type: "not_contains", Value: "Database error"},
+		{Type: "header_matches", Header: "Content-Type", Value: "^text/html"},
+		{Type: "body_size", MinBytes: &minBytes},
+	}
+	if !reflect.DeepEqual(repo.urls[1].Assertions, expected) {
+		t.Errorf("Expected assertions %+v, got %+v", expected, repo.urls[1].Assertions)
+	}
+}
+
+func TestRunAdd_Auth(t *testing.T) {
+	t.Setenv("MONITOR_SECRET_CLIENT_HOSTS", "example.com,auth.example.com")
+
+	repo := newMockUrlManager()
+
+	err := runAdd(context.Background(), repo, []string{
+		"-url", "https://example.com/internal",
+		"-auth", "oauth2 https://auth.example.com/token monitor MONITOR_SECRET_CLIENT health:read",
+	}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := &models.Auth{
+		Type:            "oauth2",
+		TokenURL:        "https://auth.example.com/token",
+		ClientID:        "monitor",
+		ClientSecretEnv: "MONITOR_SECRET_CLIENT",
+		Scopes:          []string{"health:read"},
+	}
+	if !reflect.DeepEqual(repo.urls[1].Auth, expected) {
+		t.Errorf("Expected auth %+v, got %+v", expected, repo.urls[1].Auth)
+	}
+
+	err = runUpdate(context.Background(), repo, []string{"1", "-auth", "none"}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if repo.urls[1].Auth != nil {
+		t.Errorf("Expected auth to be removed, got %+v", repo.urls[1].Auth)
+	}
+
+	if err := runAdd(context.Background(), repo, []string{"-url", "https://example.com", "-auth", "basic monitor"}, &bytes.Buffer{}); err == nil {
+		t.Error("Expected error for basic auth without password variable")
+	}
+}
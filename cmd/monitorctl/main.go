@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"website-monitor/internal/checker"
+	"website-monitor/internal/config"
+	"website-monitor/internal/db"
+	"website-monitor/internal/url_repository"
+)
+
+const usage = `Usage: monitorctl <command> [arguments]
+
+Commands:
+  list                      List all monitored urls
+  add [flags]               Add a monitored url
+  update <id> [flags]       Update flags given for a monitored url
+  remove <id>               Remove a monitored url
+  pause <id>                Stop checking a monitored url
+  resume <id>               Resume checking a paused url
+  check <id|url> [flags]    Run a one-off check without storing the result
+  export [flags]            Write all monitored urls as YAML or JSON
+  import <file> [-dry-run]  Add and update urls listed in a YAML or JSON file
+  sync <file> [-dry-run]    Make the monitored urls match the file, removing urls missing from it
+
+Run "monitorctl <command> -h" for the flags of a command.
+`
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// commands lists the commands run with the configuration, help is handled before it is loaded
+var commands = map[string]bool{
+	"list": true, "add": true, "update": true, "remove": true, "pause": true, "resume": true,
+	"check": true, "export": true, "import": true, "sync": true,
+}
+
+func run(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(out, usage)
+
+		return fmt.Errorf("no command given")
+	}
+
+	command, args := args[0], args[1:]
+
+	// Help and unknown commands need no configuration, so they also work where the environment is not set up
+	switch {
+	case command == "help" || command == "-h" || command == "--help":
+		fmt.Fprint(out, usage)
+
+		return nil
+	case !commands[command]:
+		fmt.Fprint(out, usage)
+
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	checkerCfg, err := config.LoadCheckerConfig()
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "check":
+		// Ad-hoc checks of an address do not need the database
+		if len(args) > 0 && !isID(args[0]) {
+			return runCheck(ctx, nil, checker.New(nil, *checkerCfg), args, out)
+		}
+	}
+
+	database, err := db.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func(database *db.DB) {
+		_ = database.Close()
+	}(database)
+
+	repo := url_repository.New(database)
+
+	switch command {
+	case "list":
+		return runList(ctx, repo, args, out)
+	case "add":
+		return runAdd(ctx, repo, args, out)
+	case "update":
+		return runUpdate(ctx, repo, args, out)
+	case "remove":
+		return runRemove(ctx, repo, args, out)
+	case "pause":
+		return runSetPaused(ctx, repo, args, true, out)
+	case "resume":
+		return runSetPaused(ctx, repo, args, false, out)
+	case "check":
+		return runCheck(ctx, repo, checker.New(database, *checkerCfg), args, out)
+	case "export":
+		return runExport(ctx, repo, args, out)
+	case "import":
+		return runApply(ctx, repo, command, args, false, out)
+	case "sync":
+		return runApply(ctx, repo, command, args, true, out)
+	default:
+		fmt.Fprint(out, usage)
+
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
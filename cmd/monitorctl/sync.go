@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"website-monitor/internal/url_repository"
+	"website-monitor/internal/url_sync"
+)
+
+func runExport(ctx context.Context, repo url_repository.UrlManager, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(out)
+	output := fs.String("o", "", "file to write to instead of stdout")
+	format := fs.String("format", "", "yaml or json, taken from the file extension by default")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *format == "" {
+		*format = url_sync.FormatFromPath(*output)
+	}
+
+	urls, err := repo.ListMonitoredUrls(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := url_sync.Encode(urls, *format)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err = out.Write(data)
+
+		return err
+	}
+
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", *output, err)
+	}
+
+	fmt.Fprintf(out, "Exported %d urls to %s\n", len(urls), *output)
+
+	return nil
+}
+
+// runApply brings the stored urls in line with a file. Import only creates and updates urls,
+// sync also deletes stored urls missing from the file
+func runApply(ctx context.Context, repo url_repository.UrlManager, command string, args []string, prune bool, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s needs the path of a YAML or JSON file", command)
+	}
+
+	path, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.SetOutput(out)
+	dryRun := fs.Bool("dry-run", false, "print the plan without applying it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	desired, err := url_sync.Decode(data)
+	if err != nil {
+		return err
+	}
+
+	current, err := repo.ListMonitoredUrls(ctx)
+	if err != nil {
+		return err
+	}
+
+	plan := url_sync.Diff(current, desired, prune)
+	plan.Print(out)
+
+	if plan.Empty() || *dryRun {
+		return nil
+	}
+
+	if err := repo.ApplyChanges(ctx, plan.Changes()); err != nil {
+		return fmt.Errorf("failed to apply changes: %w", err)
+	}
+
+	fmt.Fprintln(out, "Applied")
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package agent
+
+import (
+	"context"
+
+	"website-monitor/internal/checker"
+	"website-monitor/internal/models"
+)
+
+// Checker runs checks like the wrapped checker, but pushes their results to the collector instead of storing them
+type Checker struct {
+	checker.IChecker
+	client *Client
+}
+
+func NewChecker(chk checker.IChecker, client *Client) *Checker {
+	return &Checker{
+		IChecker: chk,
+		client:   client,
+	}
+}
+
+// InsertCheckResult pushes the result to the collector. The id of the stored check is not known to the agent
+func (c *Checker) InsertCheckResult(ctx context.Context, result models.CheckResult) (int, error) {
+	return 0, c.client.Push(ctx, result)
+}
@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"website-monitor/internal/models"
+)
+
+// requestTimeout bounds a single request to the collector
+const requestTimeout = 10 * time.Second
+
+// maxErrorBodyBytes limits how much of an error response is read into the error message
+const maxErrorBodyBytes = 1024
+
+// Client talks to the agent endpoints of the collector. It implements url_repository.UrlRepository,
+// so the scheduler of the agent reads the urls of its location from the collector
+type Client struct {
+	baseURL  string
+	location string
+	token    string
+	http     *http.Client
+
+	mu sync.Mutex
+	// ids maps the addresses of the urls of the last load to their ids, results only carry the address
+	ids map[string]int
+}
+
+func NewClient(cfg models.AgentConfig) *Client {
+	return &Client{
+		baseURL:  cfg.CollectorURL,
+		location: cfg.Location,
+		token:    cfg.Token,
+		http:     &http.Client{Timeout: requestTimeout},
+		ids:      make(map[string]int),
+	}
+}
+
+// GetMonitoredUrls returns the urls checked at the location of the agent
+func (c *Client) GetMonitoredUrls() ([]models.MonitoredUrl, error) {
+	var urls []models.MonitoredUrl
+	if err := c.do(context.Background(), http.MethodGet, "/agent/urls", nil, &urls); err != nil {
+		return nil, fmt.Errorf("failed to get monitored urls from collector: %w", err)
+	}
+
+	ids := make(map[string]int, len(urls))
+	for _, url := range urls {
+		ids[url.Url] = url.ID
+	}
+
+	c.mu.Lock()
+	c.ids = ids
+	c.mu.Unlock()
+
+	return urls, nil
+}
+
+// Push sends a check result to the collector, which stores it and updates the state of the url
+func (c *Client) Push(ctx context.Context, result models.CheckResult) error {
+	c.mu.Lock()
+	id, ok := c.ids[result.URL]
+	c.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("url %s is no longer checked at %s", result.URL, c.location)
+	}
+
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/agent/urls/%d/checks", id), result, nil); err != nil {
+		return fmt.Errorf("failed to push check result to collector: %w", err)
+	}
+
+	return nil
+}
+
+// do sends an authenticated request with an optional JSON body and decodes the JSON response into out, if given
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth(c.location, c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+
+		return fmt.Errorf("collector responded with status %d: %s", resp.StatusCode, bytes.TrimSpace(message))
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"website-monitor/internal/models"
+)
+
+// newCollector serves the agent endpoints for the eu-west agent and records the pushed results by path
+func newCollector(t *testing.T, pushed map[string]models.CheckResult) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if location, token, ok := r.BasicAuth(); !ok || location != "eu-west" || token != "s3cret" {
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/agent/urls":
+			_ = json.NewEncoder(w).Encode([]models.MonitoredUrl{{ID: 7, Url: "https://example.com", CheckIntervalSec: 30, Locations: []string{"eu-west"}}})
+		case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/agent/urls/"):
+			var result models.CheckResult
+			if err := json.NewDecoder(r.Body).Decode(&result); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			pushed[r.URL.Path] = result
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestClient_PushesResultsOfLoadedUrls(t *testing.T) {
+	pushed := map[string]models.CheckResult{}
+	collector := newCollector(t, pushed)
+	client := NewClient(models.AgentConfig{CollectorURL: collector.URL, Location: "eu-west", Token: "s3cret"})
+
+	urls, err := client.GetMonitoredUrls()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(urls) != 1 || urls[0].ID != 7 {
+		t.Fatalf("Expected url 7, got %+v", urls)
+	}
+
+	chk := NewChecker(nil, client)
+	result := models.CheckResult{URL: "https://example.com", CheckTimestamp: time.Now(), Error: "timeout", Location: "eu-west"}
+	if _, err := chk.InsertCheckResult(context.Background(), result); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if got, ok := pushed["/agent/urls/7/checks"]; !ok || got.Error != "timeout" {
+		t.Errorf("Expected result to be pushed for url 7, got %v", pushed)
+	}
+}
+
+func TestClient_PushUnknownUrl(t *testing.T) {
+	pushed := map[string]models.CheckResult{}
+	collector := newCollector(t, pushed)
+	client := NewClient(models.AgentConfig{CollectorURL: collector.URL, Location: "eu-west", Token: "s3cret"})
+
+	if err := client.Push(context.Background(), models.CheckResult{URL: "https://example.com"}); err == nil {
+		t.Error("Expected error for a url that was not loaded")
+	}
+
+	if len(pushed) != 0 {
+		t.Errorf("Expected nothing to be pushed, got %v", pushed)
+	}
+}
+
+func TestClient_WrongToken(t *testing.T) {
+	collector := newCollector(t, map[string]models.CheckResult{})
+	client := NewClient(models.AgentConfig{CollectorURL: collector.URL, Location: "eu-west", Token: "wrong"})
+
+	_, err := client.GetMonitoredUrls()
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected unauthorized error, got %v", err)
+	}
+}
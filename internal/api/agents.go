@@ -0,0 +1,217 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"website-monitor/internal/models"
+)
+
+// forwardedHeader marks check results forwarded by another instance, they are never forwarded again
+const forwardedHeader = "X-Monitor-Forwarded"
+
+// forwardTimeout bounds forwarding a check result to the instance owning the url
+const forwardTimeout = 10 * time.Second
+
+// Collector records the check results pushed by agents
+type Collector interface {
+	Record(ctx context.Context, url models.MonitoredUrl, result models.CheckResult) error
+}
+
+// Router locates the instance owning a url when several instances share the database
+type Router interface {
+	// Locate returns the address of the API of the instance owning the url, or local if it is this instance
+	Locate(ctx context.Context, urlID int) (address string, local bool, err error)
+}
+
+// SetAgents enables the endpoints of remote agents. Agents authenticate with HTTP basic auth, the location as user name
+// and its token from tokens as password. Results are recorded by the collector of the instance owning their url,
+// the router tells which one it is, nil if this is the only instance. It must be called before Start
+func (s *Server) SetAgents(tokens map[string]string, collector Collector, router Router) {
+	s.agentTokens = tokens
+	s.collector = collector
+	s.router = router
+	s.forwarder = &http.Client{Timeout: forwardTimeout}
+
+	s.mux.HandleFunc("/agent/urls", s.handleAgentUrls)
+	s.mux.HandleFunc("/agent/urls/", s.handleAgentChecks)
+}
+
+// authenticateAgent returns the location of the agent sending the request, or writes a 401 response and returns false
+func (s *Server) authenticateAgent(w http.ResponseWriter, r *http.Request) (string, bool) {
+	location, token, ok := r.BasicAuth()
+	expected, known := s.agentTokens[location]
+
+	// Tokens are compared in constant time, so they cannot be guessed from response times
+	if !ok || !known || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Basic realm="website-monitor agents"`)
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+
+		return "", false
+	}
+
+	return location, true
+}
+
+// handleAgentUrls serves GET /agent/urls, the urls the agent checks at its location
+func (s *Server) handleAgentUrls(w http.ResponseWriter, r *http.Request) {
+	location, ok := s.authenticateAgent(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+
+		return
+	}
+
+	urls, err := s.urls.ListMonitoredUrls(r.Context())
+	if err != nil {
+		s.writeRepositoryError(w, err)
+
+		return
+	}
+
+	assigned := []models.MonitoredUrl{}
+	for _, url := range urls {
+		if !url.Paused && url.HasLocation(location) {
+			assigned = append(assigned, url)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, assigned)
+}
+
+// handleAgentChecks serves POST /agent/urls/{id}/checks, a check result of the url at the location of the agent
+func (s *Server) handleAgentChecks(w http.ResponseWriter, r *http.Request) {
+	location, ok := s.authenticateAgent(w, r)
+	if !ok {
+		return
+	}
+
+	idStr, rest, _ := strings.Cut(strings.Trim(strings.TrimPrefix(r.URL.Path, "/agent/urls/"), "/"), "/")
+
+	id, err := parseID(idStr)
+	if err != nil || rest != "checks" {
+		writeError(w, http.StatusNotFound, "not found")
+
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, http.MethodPost)
+
+		return
+	}
+
+	var result models.CheckResult
+	if err := decodeJSON(w, r, &result); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	if result.CheckTimestamp.IsZero() {
+		writeError(w, http.StatusBadRequest, "check_timestamp is required")
+
+		return
+	}
+
+	url, err := s.urls.GetMonitoredUrl(r.Context(), id)
+	if err != nil {
+		s.writeRepositoryError(w, err)
+
+		return
+	}
+
+	// The agent may not have picked up a change of the url yet
+	if url.Paused || !url.HasLocation(location) {
+		writeError(w, http.StatusConflict, fmt.Sprintf("url %d is not checked from %s", id, location))
+
+		return
+	}
+
+	// The location is the one the agent authenticated for, whatever the agent claims
+	result.ID = 0
+	result.URL = url.Url
+	result.Location = location
+
+	// Only the owner of the url keeps its state, so the result is recorded there
+	if s.router != nil {
+		address, local, err := s.router.Locate(r.Context(), id)
+		if err == nil && !local && r.Header.Get(forwardedHeader) != "" {
+			// The instances disagree on the owner while the url moves, forwarding again could go in circles
+			err = fmt.Errorf("url %d was forwarded here by another instance", id)
+		}
+
+		if err != nil {
+			log.Printf("Failed to locate the owner of %s: %v", url.Url, err)
+			writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("url %d is moving between instances, retry later", id))
+
+			return
+		}
+
+		if !local {
+			s.forwardAgentCheck(w, r, address, result)
+
+			return
+		}
+	}
+
+	if err := s.collector.Record(r.Context(), url, result); err != nil {
+		log.Printf("Failed to record check of %s from %s: %v", url.Url, location, err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// forwardAgentCheck passes a check result on to the instance owning its url and relays the response.
+// The credentials of the agent are passed on, all instances accept the same agents
+func (s *Server) forwardAgentCheck(w http.ResponseWriter, r *http.Request, address string, result models.CheckResult) {
+	body, err := json.Marshal(result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal server error")
+
+		return
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, address+r.URL.Path, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Failed to forward check of %s to %s: %v", result.URL, address, err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+
+		return
+	}
+	req.Header.Set("Authorization", r.Header.Get("Authorization"))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(forwardedHeader, "true")
+
+	resp, err := s.forwarder.Do(req)
+	if err != nil {
+		log.Printf("Failed to forward check of %s to %s: %v", result.URL, address, err)
+		writeError(w, http.StatusBadGateway, "failed to reach the instance owning the url")
+
+		return
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, io.LimitReader(resp.Body, maxRequestBodyBytes))
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"website-monitor/internal/models"
+)
+
+type mockCollector struct {
+	urls    []models.MonitoredUrl
+	results []models.CheckResult
+}
+
+func (m *mockCollector) Record(ctx context.Context, url models.MonitoredUrl, result models.CheckResult) error {
+	m.urls = append(m.urls, url)
+	m.results = append(m.results, result)
+	return nil
+}
+
+// mockRouter locates every url at address, or at this instance if address is empty
+type mockRouter struct {
+	address string
+}
+
+func (m *mockRouter) Locate(ctx context.Context, urlID int) (string, bool, error) {
+	return m.address, m.address == "", nil
+}
+
+func doAgentRequest(t *testing.T, handler http.Handler, method, path, body, location, token string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.SetBasicAuth(location, token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func newAgentServer(collector *mockCollector) *Server {
+	return newAgentInstance(collector, nil)
+}
+
+// newAgentInstance returns the API of one of several instances sharing the urls, router locates their owners
+func newAgentInstance(collector *mockCollector, router Router) *Server {
+	server := New(newMockUrlManager(
+		models.MonitoredUrl{ID: 1, Url: "https://example.com", CheckIntervalSec: 30},
+		models.MonitoredUrl{ID: 2, Url: "https://google.com", CheckIntervalSec: 30, Locations: []string{"eu-west", "us-east"}},
+		models.MonitoredUrl{ID: 3, Url: "https://github.com", CheckIntervalSec: 30, Locations: []string{"us-east"}},
+	), nil)
+	server.SetAgents(map[string]string{"eu-west": "eu-secret", "us-east": "us-secret"}, collector, router)
+
+	return server
+}
+
+func TestAgentUrls_RequiresToken(t *testing.T) {
+	server := newAgentServer(&mockCollector{})
+
+	tests := map[string][2]string{
+		"unknown location": {"ap-south", "eu-secret"},
+		"wrong token":      {"eu-west", "us-secret"},
+		"no credentials":   {"", ""},
+	}
+
+	for name, credentials := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := doAgentRequest(t, server.Handler(), http.MethodGet, "/agent/urls", "", credentials[0], credentials[1])
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("Expected status 401, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestAgentUrls_ListsUrlsOfLocation(t *testing.T) {
+	server := newAgentServer(&mockCollector{})
+
+	rec := doAgentRequest(t, server.Handler(), http.MethodGet, "/agent/urls", "", "eu-west", "eu-secret")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var urls []models.MonitoredUrl
+	if err := json.Unmarshal(rec.Body.Bytes(), &urls); err != nil {
+		t.Fatalf("Expected JSON response, got error: %v", err)
+	}
+
+	if len(urls) != 1 || urls[0].ID != 2 {
+		t.Errorf("Expected only url 2 to be checked from eu-west, got %+v", urls)
+	}
+}
+
+func TestAgentChecks_RecordsResultAtLocation(t *testing.T) {
+	collector := &mockCollector{}
+	server := newAgentServer(collector)
+
+	body := `{"id":7,"url":"https://evil.example","check_timestamp":"2024-01-01T00:00:00Z","error":"timeout","location":"us-east"}`
+	rec := doAgentRequest(t, server.Handler(), http.MethodPost, "/agent/urls/2/checks", body, "eu-west", "eu-secret")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if len(collector.results) != 1 {
+		t.Fatalf("Expected 1 recorded result, got %d", len(collector.results))
+	}
+
+	result := collector.results[0]
+	if result.ID != 0 || result.URL != "https://google.com" || result.Location != "eu-west" || result.Error != "timeout" {
+		t.Errorf("Expected result of url 2 at eu-west, got %+v", result)
+	}
+}
+
+func TestAgentChecks_RejectsUrlsOfOtherLocations(t *testing.T) {
+	collector := &mockCollector{}
+	server := newAgentServer(collector)
+	body := `{"url":"https://github.com","check_timestamp":"2024-01-01T00:00:00Z"}`
+
+	rec := doAgentRequest(t, server.Handler(), http.MethodPost, "/agent/urls/3/checks", body, "eu-west", "eu-secret")
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", rec.Code)
+	}
+
+	rec = doAgentRequest(t, server.Handler(), http.MethodPost, "/agent/urls/9/checks", body, "eu-west", "eu-secret")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown url, got %d", rec.Code)
+	}
+
+	if len(collector.results) != 0 {
+		t.Errorf("Expected no recorded results, got %d", len(collector.results))
+	}
+}
+
+func TestAgentChecks_ForwardsToOwningInstance(t *testing.T) {
+	ownerCollector := &mockCollector{}
+	owner := httptest.NewServer(newAgentInstance(ownerCollector, &mockRouter{}).Handler())
+	defer owner.Close()
+
+	collector := &mockCollector{}
+	server := newAgentInstance(collector, &mockRouter{address: owner.URL})
+
+	body := `{"url":"https://google.com","check_timestamp":"2024-01-01T00:00:00Z","error":"timeout","location":"us-east"}`
+	rec := doAgentRequest(t, server.Handler(), http.MethodPost, "/agent/urls/2/checks", body, "eu-west", "eu-secret")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if len(collector.results) != 0 {
+		t.Errorf("Expected no result to be recorded by the instance not owning the url, got %d", len(collector.results))
+	}
+
+	if len(ownerCollector.results) != 1 {
+		t.Fatalf("Expected 1 result recorded by the owner, got %d", len(ownerCollector.results))
+	}
+
+	result := ownerCollector.results[0]
+	if ownerCollector.urls[0].ID != 2 || result.Location != "eu-west" || result.Error != "timeout" {
+		t.Errorf("Expected result of url 2 at eu-west, got %+v", result)
+	}
+
+	rec = doAgentRequest(t, server.Handler(), http.MethodPost, "/agent/urls/2/checks", body, "eu-west", "wrong")
+	if rec.Code != http.StatusUnauthorized || len(ownerCollector.results) != 1 {
+		t.Errorf("Expected unauthenticated result not to be forwarded, got status %d", rec.Code)
+	}
+}
+
+func TestAgentChecks_ForwardsOnlyOnce(t *testing.T) {
+	// Both instances think the other one owns the url, as while the url moves
+	collector := &mockCollector{}
+	other := httptest.NewServer(newAgentInstance(collector, &mockRouter{address: "http://127.0.0.1:1"}).Handler())
+	defer other.Close()
+
+	server := newAgentInstance(&mockCollector{}, &mockRouter{address: other.URL})
+
+	body := `{"url":"https://google.com","check_timestamp":"2024-01-01T00:00:00Z"}`
+	rec := doAgentRequest(t, server.Handler(), http.MethodPost, "/agent/urls/2/checks", body, "eu-west", "eu-secret")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if len(collector.results) != 0 {
+		t.Errorf("Expected no recorded results, got %d", len(collector.results))
+	}
+}
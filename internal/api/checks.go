@@ -0,0 +1,174 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"website-monitor/internal/check_repository"
+	"website-monitor/internal/models"
+)
+
+const (
+	defaultChecksLimit = 100
+	maxChecksLimit     = 1000
+	// defaultStatsWindow is used when the stats request does not specify from
+	defaultStatsWindow = 24 * time.Hour
+)
+
+// checksPage is the response of the check history endpoint
+type checksPage struct {
+	Checks []models.CheckResult `json:"checks"`
+	Limit  int                  `json:"limit"`
+	Offset int                  `json:"offset"`
+	// HasMore reports whether there are more checks after this page
+	HasMore bool `json:"has_more"`
+}
+
+// listChecks serves GET /urls/{id}/checks?from=&to=&location=&limit=&offset=
+func (s *Server) listChecks(w http.ResponseWriter, r *http.Request, id int) {
+	query := r.URL.Query()
+
+	from, err := parseTime(query.Get("from"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	to, err := parseTime(query.Get("to"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	limit, err := parseIntParam(query.Get("limit"), "limit", defaultChecksLimit, 1, maxChecksLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	offset, err := parseIntParam(query.Get("offset"), "offset", 0, 0, -1)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	url, err := s.urls.GetMonitoredUrl(r.Context(), id)
+	if err != nil {
+		s.writeRepositoryError(w, err)
+
+		return
+	}
+
+	// One extra check is requested to find out whether there is a next page
+	checks, err := s.checks.GetChecks(r.Context(), check_repository.CheckFilter{
+		URL:      url.Url,
+		From:     from,
+		To:       to,
+		Location: query.Get("location"),
+		Limit:    limit + 1,
+		Offset:   offset,
+	})
+	if err != nil {
+		s.writeRepositoryError(w, err)
+
+		return
+	}
+
+	page := checksPage{
+		Checks: checks,
+		Limit:  limit,
+		Offset: offset,
+	}
+	if len(checks) > limit {
+		page.Checks = checks[:limit]
+		page.HasMore = true
+	}
+	if page.Checks == nil {
+		page.Checks = []models.CheckResult{}
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// getStats serves GET /urls/{id}/stats?from=&to=, the window defaults to the last 24 hours
+func (s *Server) getStats(w http.ResponseWriter, r *http.Request, id int) {
+	query := r.URL.Query()
+
+	to := time.Now()
+	if parsed, err := parseTime(query.Get("to")); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+
+		return
+	} else if parsed != nil {
+		to = *parsed
+	}
+
+	from := to.Add(-defaultStatsWindow)
+	if parsed, err := parseTime(query.Get("from")); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+
+		return
+	} else if parsed != nil {
+		from = *parsed
+	}
+
+	if !from.Before(to) {
+		writeError(w, http.StatusBadRequest, "from must be before to")
+
+		return
+	}
+
+	url, err := s.urls.GetMonitoredUrl(r.Context(), id)
+	if err != nil {
+		s.writeRepositoryError(w, err)
+
+		return
+	}
+
+	stats, err := s.checks.GetStats(r.Context(), url.Url, from, to)
+	if err != nil {
+		s.writeRepositoryError(w, err)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// parseTime parses an optional RFC 3339 query parameter
+func parseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time %q, expected RFC 3339", value)
+	}
+
+	return &parsed, nil
+}
+
+// parseIntParam parses an optional integer query parameter within [min, max], a negative max means unbounded
+func parseIntParam(value, name string, defaultValue, min, max int) (int, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < min || (max >= 0 && parsed > max) {
+		if max >= 0 {
+			return 0, fmt.Errorf("%s must be an integer between %d and %d", name, min, max)
+		}
+
+		return 0, fmt.Errorf("%s must be an integer of at least %d", name, min)
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"website-monitor/internal/check_repository"
+	"website-monitor/internal/models"
+)
+
+type mockCheckRepository struct {
+	checks      []models.CheckResult
+	stats       models.CheckStats
+	lastFilter  check_repository.CheckFilter
+	lastStatsTo time.Time
+	lastFrom    time.Time
+}
+
+func (m *mockCheckRepository) GetChecks(ctx context.Context, filter check_repository.CheckFilter) ([]models.CheckResult, error) {
+	m.lastFilter = filter
+	if filter.Limit < len(m.checks) {
+		return m.checks[:filter.Limit], nil
+	}
+	return m.checks, nil
+}
+
+func (m *mockCheckRepository) GetStats(ctx context.Context, url string, from, to time.Time) (models.CheckStats, error) {
+	m.lastFrom = from
+	m.lastStatsTo = to
+	stats := m.stats
+	stats.URL = url
+	return stats, nil
+}
+
+var monitoredExample = models.MonitoredUrl{ID: 1, Url: "https://example.com", CheckIntervalSec: 30}
+
+func TestListChecks_Paginates(t *testing.T) {
+	checks := &mockCheckRepository{
+		checks: []models.CheckResult{{ID: 3}, {ID: 2}, {ID: 1}},
+	}
+	server := New(newMockUrlManager(monitoredExample), checks)
+
+	rec := doRequest(t, server.Handler(), http.MethodGet, "/urls/1/checks?limit=2&offset=4&from=2024-01-01T00:00:00Z", "")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var page checksPage
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Expected JSON response, got error: %v", err)
+	}
+
+	if len(page.Checks) != 2 || !page.HasMore {
+		t.Errorf("Expected 2 checks and more pages, got %d checks, has_more=%v", len(page.Checks), page.HasMore)
+	}
+
+	filter := checks.lastFilter
+	if filter.URL != monitoredExample.Url || filter.Offset != 4 || filter.From == nil || filter.To != nil {
+		t.Errorf("Unexpected filter %+v", filter)
+	}
+}
+
+func TestListChecks_InvalidParams(t *testing.T) {
+	server := New(newMockUrlManager(monitoredExample), &mockCheckRepository{})
+
+	paths := []string{
+		"/urls/1/checks?limit=0",
+		"/urls/1/checks?limit=5000",
+		"/urls/1/checks?offset=-1",
+		"/urls/1/checks?from=yesterday",
+	}
+
+	for _, path := range paths {
+		rec := doRequest(t, server.Handler(), http.MethodGet, path, "")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestListChecks_UnknownUrl(t *testing.T) {
+	server := New(newMockUrlManager(), &mockCheckRepository{})
+
+	rec := doRequest(t, server.Handler(), http.MethodGet, "/urls/1/checks", "")
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestGetStats_DefaultWindow(t *testing.T) {
+	uptime := 99.5
+	checks := &mockCheckRepository{
+		stats: models.CheckStats{TotalChecks: 200, UptimePercent: &uptime},
+	}
+	server := New(newMockUrlManager(monitoredExample), checks)
+
+	rec := doRequest(t, server.Handler(), http.MethodGet, "/urls/1/stats", "")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if window := checks.lastStatsTo.Sub(checks.lastFrom); window != defaultStatsWindow {
+		t.Errorf("Expected default window of %s, got %s", defaultStatsWindow, window)
+	}
+
+	var stats models.CheckStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Expected JSON response, got error: %v", err)
+	}
+
+	if stats.URL != monitoredExample.Url || stats.UptimePercent == nil || *stats.UptimePercent != 99.5 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestGetStats_InvalidWindow(t *testing.T) {
+	server := New(newMockUrlManager(monitoredExample), &mockCheckRepository{})
+
+	rec := doRequest(t, server.Handler(), http.MethodGet, "/urls/1/stats?from=2024-01-02T00:00:00Z&to=2024-01-01T00:00:00Z", "")
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", rec.Code)
+	}
+}
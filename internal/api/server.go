@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"website-monitor/internal/check_repository"
+	"website-monitor/internal/url_repository"
+)
+
+// maxRequestBodyBytes limits the size of JSON request bodies
+const maxRequestBodyBytes = 1 << 20
+
+// shutdownTimeout bounds how long Stop waits for in-flight requests
+const shutdownTimeout = 5 * time.Second
+
+// LoadErrorSource reports why stored urls are not monitored, e.g. because of an invalid regex
+type LoadErrorSource interface {
+	LoadErrors() map[int]string
+}
+
+// Server serves the HTTP API for managing the monitor
+type Server struct {
+	urls       url_repository.UrlManager
+	checks     check_repository.CheckRepository
+	loadErrors LoadErrorSource
+	// token must be sent as a bearer token to change urls, urls cannot be changed through the API if empty
+	token string
+	// agentTokens holds the token of the agent at every location, collector records the results they push
+	agentTokens map[string]string
+	collector   Collector
+	// router locates the instance owning a url, forwarder sends it the results of agents pushed here
+	router    Router
+	forwarder *http.Client
+	mux       *http.ServeMux
+	server    *http.Server
+}
+
+func New(urls url_repository.UrlManager, checks check_repository.CheckRepository) *Server {
+	s := &Server{
+		urls:   urls,
+		checks: checks,
+		mux:    http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/urls", s.handleUrls)
+	s.mux.HandleFunc("/urls/", s.handleUrl)
+
+	return s
+}
+
+// Handle mounts an additional handler on the API, e.g. the metrics endpoint. It must be called before Start
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// SetLoadErrors sets the source of the load errors returned with urls. It must be called before Start
+func (s *Server) SetLoadErrors(source LoadErrorSource) {
+	s.loadErrors = source
+}
+
+// SetToken sets the token requests changing urls must send. It must be called before Start
+func (s *Server) SetToken(token string) {
+	s.token = token
+}
+
+// Handler returns the HTTP handler of the API
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// Start begins serving the API on the given address in the background
+func (s *Server) Start(addr string) error {
+	// Listen synchronously, so a busy port is reported to the caller instead of being logged later
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	s.server = &http.Server{
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("API server failed: %v", err)
+		}
+	}()
+
+	log.Printf("API listening on %s", listener.Addr())
+
+	return nil
+}
+
+// Stop gracefully shuts the API down, waiting for in-flight requests to finish
+func (s *Server) Stop() {
+	if s.server == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		log.Printf("Failed to shut down API server: %v", err)
+	}
+}
+
+// authorize checks the bearer token of a request changing urls, writing an error response if it is missing or wrong
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if s.token == "" {
+		writeError(w, http.StatusForbidden, "changing urls through the API needs API_TOKEN to be set")
+
+		return false
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	// Tokens are compared in constant time, so they cannot be guessed from response times
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="website-monitor"`)
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+
+		return false
+	}
+
+	return true
+}
+
+// errorResponse is the JSON body of error responses
+type errorResponse struct {
+	Error   string   `json:"error"`
+	Details []string `json:"details,omitempty"`
+}
+
+// writeJSON writes the value as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("Failed to write API response: %v", err)
+	}
+}
+
+// writeError writes a JSON error response with the given status
+func writeError(w http.ResponseWriter, status int, message string, details ...string) {
+	writeJSON(w, status, errorResponse{Error: message, Details: details})
+}
+
+// writeMethodNotAllowed writes a 405 response listing the allowed methods
+func writeMethodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
+
+// decodeJSON decodes the request body into the value, rejecting unknown fields
+func decodeJSON(w http.ResponseWriter, r *http.Request, value interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(value); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+
+	return nil
+}
+
+// parseID parses a positive resource id from a path segment
+func parseID(value string) (int, error) {
+	id, err := strconv.Atoi(value)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid id %q", value)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"website-monitor/internal/models"
+	"website-monitor/internal/url_repository"
+	"website-monitor/internal/validator"
+)
+
+// urlResponse is a monitored url together with the error that keeps it from being monitored, if any
+type urlResponse struct {
+	models.MonitoredUrl
+	LoadError string `json:"load_error,omitempty"`
+}
+
+// handleUrls serves the /urls collection
+func (s *Server) handleUrls(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.listUrls(w, r)
+	case http.MethodPost:
+		if s.authorize(w, r) {
+			s.createUrl(w, r)
+		}
+	default:
+		writeMethodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+// handleUrl serves /urls/{id} and its sub-resources
+func (s *Server) handleUrl(w http.ResponseWriter, r *http.Request) {
+	idStr, rest, _ := strings.Cut(strings.Trim(strings.TrimPrefix(r.URL.Path, "/urls/"), "/"), "/")
+
+	id, err := parseID(idStr)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+
+		return
+	}
+
+	switch rest {
+	case "":
+		switch r.Method {
+		case http.MethodGet:
+			s.getUrl(w, r, id)
+		case http.MethodPut:
+			if s.authorize(w, r) {
+				s.updateUrl(w, r, id)
+			}
+		case http.MethodDelete:
+			if s.authorize(w, r) {
+				s.deleteUrl(w, r, id)
+			}
+		default:
+			writeMethodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodDelete)
+		}
+	case "checks":
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, http.MethodGet)
+
+			return
+		}
+		s.listChecks(w, r, id)
+	case "stats":
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, http.MethodGet)
+
+			return
+		}
+		s.getStats(w, r, id)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) listUrls(w http.ResponseWriter, r *http.Request) {
+	urls, err := s.urls.ListMonitoredUrls(r.Context())
+	if err != nil {
+		s.writeRepositoryError(w, err)
+
+		return
+	}
+
+	loadErrors := s.currentLoadErrors()
+	responses := make([]urlResponse, 0, len(urls))
+	for _, url := range urls {
+		responses = append(responses, urlResponse{MonitoredUrl: url, LoadError: loadErrors[url.ID]})
+	}
+
+	writeJSON(w, http.StatusOK, responses)
+}
+
+func (s *Server) getUrl(w http.ResponseWriter, r *http.Request, id int) {
+	url, err := s.urls.GetMonitoredUrl(r.Context(), id)
+	if err != nil {
+		s.writeRepositoryError(w, err)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, urlResponse{MonitoredUrl: url, LoadError: s.currentLoadErrors()[url.ID]})
+}
+
+// currentLoadErrors returns the load errors by url id, none if no source is set
+func (s *Server) currentLoadErrors() map[int]string {
+	if s.loadErrors == nil {
+		return nil
+	}
+
+	return s.loadErrors.LoadErrors()
+}
+
+func (s *Server) createUrl(w http.ResponseWriter, r *http.Request) {
+	url, ok := decodeMonitoredUrl(w, r)
+	if !ok {
+		return
+	}
+
+	created, err := s.urls.CreateMonitoredUrl(r.Context(), url)
+	if err != nil {
+		s.writeRepositoryError(w, err)
+
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (s *Server) updateUrl(w http.ResponseWriter, r *http.Request, id int) {
+	url, ok := decodeMonitoredUrl(w, r)
+	if !ok {
+		return
+	}
+	url.ID = id
+
+	updated, err := s.urls.UpdateMonitoredUrl(r.Context(), url)
+	if err != nil {
+		s.writeRepositoryError(w, err)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (s *Server) deleteUrl(w http.ResponseWriter, r *http.Request, id int) {
+	if err := s.urls.DeleteMonitoredUrl(r.Context(), id); err != nil {
+		s.writeRepositoryError(w, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeMonitoredUrl decodes, normalizes and validates a monitored url from the request body.
+// It writes the error response itself and returns false if the url cannot be used
+func decodeMonitoredUrl(w http.ResponseWriter, r *http.Request) (models.MonitoredUrl, bool) {
+	var url models.MonitoredUrl
+	if err := decodeJSON(w, r, &url); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+
+		return url, false
+	}
+
+	url = validator.Normalize(url)
+
+	if err := validator.ValidateMonitoredUrl(url); err != nil {
+		var errs validator.Errors
+		if errors.As(err, &errs) {
+			writeError(w, http.StatusUnprocessableEntity, "invalid monitored url", errs...)
+		} else {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+		}
+
+		return url, false
+	}
+
+	return url, true
+}
+
+// writeRepositoryError maps repository errors to HTTP responses
+func (s *Server) writeRepositoryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, url_repository.ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, url_repository.ErrDuplicateUrl):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		log.Printf("API request failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
@@ -0,0 +1,350 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"website-monitor/internal/models"
+	"website-monitor/internal/url_repository"
+)
+
+type mockUrlManager struct {
+	urls      map[int]models.MonitoredUrl
+	nextID    int
+	err       error
+	lastSaved models.MonitoredUrl
+}
+
+func newMockUrlManager(urls ...models.MonitoredUrl) *mockUrlManager {
+	m := &mockUrlManager{urls: make(map[int]models.MonitoredUrl)}
+	for _, url := range urls {
+		m.urls[url.ID] = url
+		if url.ID > m.nextID {
+			m.nextID = url.ID
+		}
+	}
+	return m
+}
+
+func (m *mockUrlManager) ListMonitoredUrls(ctx context.Context) ([]models.MonitoredUrl, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var urls []models.MonitoredUrl
+	for id := 1; id <= m.nextID; id++ {
+		if url, ok := m.urls[id]; ok {
+			urls = append(urls, url)
+		}
+	}
+	return urls, nil
+}
+
+func (m *mockUrlManager) GetMonitoredUrl(ctx context.Context, id int) (models.MonitoredUrl, error) {
+	url, ok := m.urls[id]
+	if !ok {
+		return url, url_repository.ErrNotFound
+	}
+	return url, nil
+}
+
+func (m *mockUrlManager) CreateMonitoredUrl(ctx context.Context, url models.MonitoredUrl) (models.MonitoredUrl, error) {
+	if m.err != nil {
+		return url, m.err
+	}
+	m.nextID++
+	url.ID = m.nextID
+	m.urls[url.ID] = url
+	m.lastSaved = url
+	return url, nil
+}
+
+func (m *mockUrlManager) UpdateMonitoredUrl(ctx context.Context, url models.MonitoredUrl) (models.MonitoredUrl, error) {
+	if _, ok := m.urls[url.ID]; !ok {
+		return url, url_repository.ErrNotFound
+	}
+	m.urls[url.ID] = url
+	m.lastSaved = url
+	return url, nil
+}
+
+func (m *mockUrlManager) DeleteMonitoredUrl(ctx context.Context, id int) error {
+	if _, ok := m.urls[id]; !ok {
+		return url_repository.ErrNotFound
+	}
+	delete(m.urls, id)
+	return nil
+}
+
+func (m *mockUrlManager) SetPaused(ctx context.Context, id int, paused bool) error {
+	url, ok := m.urls[id]
+	if !ok {
+		return url_repository.ErrNotFound
+	}
+	url.Paused = paused
+	m.urls[id] = url
+	return nil
+}
+
+func (m *mockUrlManager) ApplyChanges(ctx context.Context, changes url_repository.Changes) error {
+	return m.err
+}
+
+// testToken is the API token of the servers in the tests, doRequest sends it with every request
+const testToken = "api-s3cret"
+
+func doRequest(t *testing.T, handler http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestListUrls_Empty(t *testing.T) {
+	server := New(newMockUrlManager(), nil)
+
+	rec := doRequest(t, server.Handler(), http.MethodGet, "/urls", "")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	if strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("Expected empty JSON array, got %s", rec.Body.String())
+	}
+}
+
+func TestCreateUrl_HappyPath(t *testing.T) {
+	urls := newMockUrlManager()
+	server := New(urls, nil)
+	server.SetToken(testToken)
+
+	rec := doRequest(t, server.Handler(), http.MethodPost, "/urls",
+		`{"url":"https://example.com","check_interval_sec":30,"method":"head","expected_status_codes":"200-299"}`)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var created models.MonitoredUrl
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Expected JSON response, got error: %v", err)
+	}
+
+	if created.ID != 1 {
+		t.Errorf("Expected id 1, got %d", created.ID)
+	}
+
+	if urls.lastSaved.Method != "HEAD" {
+		t.Errorf("Expected method to be normalized to HEAD, got %s", urls.lastSaved.Method)
+	}
+}
+
+func TestCreateUrl_ValidationError(t *testing.T) {
+	urls := newMockUrlManager()
+	server := New(urls, nil)
+	server.SetToken(testToken)
+
+	rec := doRequest(t, server.Handler(), http.MethodPost, "/urls",
+		`{"url":"example.com","check_interval_sec":1,"regex_pattern":"[invalid"}`)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, got %d", rec.Code)
+	}
+
+	var resp errorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Expected JSON error response, got error: %v", err)
+	}
+
+	if len(resp.Details) != 3 {
+		t.Errorf("Expected 3 validation details, got %v", resp.Details)
+	}
+
+	if len(urls.urls) != 0 {
+		t.Error("Expected invalid url not to be stored")
+	}
+}
+
+func TestCreateUrl_UnknownField(t *testing.T) {
+	server := New(newMockUrlManager(), nil)
+	server.SetToken(testToken)
+
+	rec := doRequest(t, server.Handler(), http.MethodPost, "/urls",
+		`{"url":"https://example.com","check_interval_sec":30,"interval":30}`)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestCreateUrl_Duplicate(t *testing.T) {
+	urls := newMockUrlManager()
+	urls.err = url_repository.ErrDuplicateUrl
+	server := New(urls, nil)
+	server.SetToken(testToken)
+
+	rec := doRequest(t, server.Handler(), http.MethodPost, "/urls",
+		`{"url":"https://example.com","check_interval_sec":30}`)
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409, got %d", rec.Code)
+	}
+}
+
+func TestGetUrl_NotFound(t *testing.T) {
+	server := New(newMockUrlManager(), nil)
+
+	rec := doRequest(t, server.Handler(), http.MethodGet, "/urls/42", "")
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestGetUrl_InvalidID(t *testing.T) {
+	server := New(newMockUrlManager(), nil)
+
+	rec := doRequest(t, server.Handler(), http.MethodGet, "/urls/abc", "")
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestUpdateUrl_HappyPath(t *testing.T) {
+	urls := newMockUrlManager(models.MonitoredUrl{ID: 3, Url: "https://example.com", CheckIntervalSec: 30, Method: "GET"})
+	server := New(urls, nil)
+	server.SetToken(testToken)
+
+	rec := doRequest(t, server.Handler(), http.MethodPut, "/urls/3",
+		`{"url":"https://example.com","check_interval_sec":60}`)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if urls.urls[3].CheckIntervalSec != 60 {
+		t.Errorf("Expected interval to be updated to 60, got %d", urls.urls[3].CheckIntervalSec)
+	}
+}
+
+func TestDeleteUrl_HappyPath(t *testing.T) {
+	urls := newMockUrlManager(models.MonitoredUrl{ID: 3, Url: "https://example.com", CheckIntervalSec: 30})
+	server := New(urls, nil)
+	server.SetToken(testToken)
+
+	rec := doRequest(t, server.Handler(), http.MethodDelete, "/urls/3", "")
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", rec.Code)
+	}
+
+	if _, ok := urls.urls[3]; ok {
+		t.Error("Expected url to be deleted")
+	}
+}
+
+func TestUrls_ChangesNeedToken(t *testing.T) {
+	urls := newMockUrlManager(models.MonitoredUrl{ID: 3, Url: "https://example.com", CheckIntervalSec: 30})
+	server := New(urls, nil)
+
+	// Without a configured token urls cannot be changed at all
+	rec := doRequest(t, server.Handler(), http.MethodDelete, "/urls/3", "")
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 without a configured token, got %d", rec.Code)
+	}
+
+	server.SetToken("other-token")
+	requests := []struct{ method, path, body string }{
+		{http.MethodPost, "/urls", `{"url":"https://attacker.example.net","check_interval_sec":30}`},
+		{http.MethodPut, "/urls/3", `{"url":"https://example.com","check_interval_sec":60}`},
+		{http.MethodDelete, "/urls/3", ""},
+	}
+	for _, r := range requests {
+		rec := doRequest(t, server.Handler(), r.method, r.path, r.body)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected %s %s with a wrong token to return 401, got %d", r.method, r.path, rec.Code)
+		}
+	}
+
+	if len(urls.urls) != 1 || urls.urls[3].CheckIntervalSec != 30 {
+		t.Errorf("Expected the urls to be unchanged, got %v", urls.urls)
+	}
+
+	// Reading needs no token
+	if rec := doRequest(t, server.Handler(), http.MethodGet, "/urls/3", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for reading, got %d", rec.Code)
+	}
+}
+
+func TestUrls_MethodNotAllowed(t *testing.T) {
+	server := New(newMockUrlManager(), nil)
+
+	rec := doRequest(t, server.Handler(), http.MethodPatch, "/urls", "")
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected status 405, got %d", rec.Code)
+	}
+
+	if rec.Header().Get("Allow") == "" {
+		t.Error("Expected Allow header to be set")
+	}
+}
+
+func TestListUrls_RepositoryError(t *testing.T) {
+	urls := newMockUrlManager()
+	urls.err = errors.New("connection refused")
+	server := New(urls, nil)
+
+	rec := doRequest(t, server.Handler(), http.MethodGet, "/urls", "")
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, got %d", rec.Code)
+	}
+
+	if strings.Contains(rec.Body.String(), "connection refused") {
+		t.Error("Expected internal error details not to be exposed")
+	}
+}
+
+type mockLoadErrors map[int]string
+
+func (m mockLoadErrors) LoadErrors() map[int]string {
+	return m
+}
+
+func TestUrls_LoadErrors(t *testing.T) {
+	server := New(newMockUrlManager(
+		models.MonitoredUrl{ID: 1, Url: "https://example.com", CheckIntervalSec: 30, Method: "GET"},
+		models.MonitoredUrl{ID: 2, Url: "https://google.com", CheckIntervalSec: 30, Method: "GET", RegexPattern: "[invalid"},
+	), nil)
+	server.SetLoadErrors(mockLoadErrors{2: "invalid regex: regex_pattern is invalid"})
+
+	rec := doRequest(t, server.Handler(), http.MethodGet, "/urls", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+
+	var urls []urlResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &urls); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(urls) != 2 || urls[0].LoadError != "" || urls[1].LoadError != "invalid regex: regex_pattern is invalid" {
+		t.Errorf("Unexpected urls %+v", urls)
+	}
+
+	rec = doRequest(t, server.Handler(), http.MethodGet, "/urls/2", "")
+	if !strings.Contains(rec.Body.String(), `"load_error":"invalid regex: regex_pattern is invalid"`) {
+		t.Errorf("Expected load error in response, got %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,48 @@
+package assertion
+
+import (
+	"fmt"
+
+	"website-monitor/internal/models"
+)
+
+// FirstFailure describes the first failed assertion, or returns an empty string if all passed
+func FirstFailure(results []models.AssertionResult) string {
+	for _, r := range results {
+		if r.Passed {
+			continue
+		}
+
+		if r.Error != "" {
+			return fmt.Sprintf("%s: %s", Describe(r), r.Error)
+		}
+
+		// Text assertions have no value to show, the description says it all
+		if r.Actual == nil {
+			return Describe(r)
+		}
+
+		return fmt.Sprintf("%s, got %s", Describe(r), format(r.Actual))
+	}
+
+	return ""
+}
+
+// Describe prints the assertion a result belongs to, e.g. $.status equals "ok" or not_contains "Maintenance"
+func Describe(r models.AssertionResult) string {
+	var description string
+	switch r.Type {
+	case TypeJSON:
+		description = r.Path + " " + r.Op
+	case TypeHeaderEquals, TypeHeaderMatches:
+		description = r.Type + " " + r.Header
+	default:
+		description = r.Type
+	}
+
+	if r.Expected != nil {
+		description += " " + format(r.Expected)
+	}
+
+	return description
+}
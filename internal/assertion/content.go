@@ -0,0 +1,168 @@
+package assertion
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"website-monitor/internal/models"
+)
+
+// Types of content assertions
+const (
+	TypeContains      = "contains"
+	TypeNotContains   = "not_contains"
+	TypeRegex         = "regex"
+	TypeBodySize      = "body_size"
+	TypeHeaderEquals  = "header_equals"
+	TypeHeaderMatches = "header_matches"
+)
+
+// MaxBodySizeBytes is the largest body size the checker measures, so body_size bounds must not exceed it
+const MaxBodySizeBytes = 10 * 1024 * 1024
+
+// Response is the part of a response content assertions are evaluated against
+type Response struct {
+	Header http.Header
+	// Text holds the outcome of matching the body for contains, not_contains and regex assertions,
+	// indexed like the assertions. The checker matches the body, so it is not kept here
+	Text []TextMatch
+	// Size is the full body size, counted up to MaxBodySizeBytes+1
+	Size int64
+	// Compile compiles the regexes of header assertions, e.g. from a cache. regexp.Compile is used if nil
+	Compile func(pattern string) (*regexp.Regexp, error)
+}
+
+// TextMatch is the outcome of matching the body against a text assertion
+type TextMatch struct {
+	Matched bool
+	Err     error
+}
+
+// sizeBounds is the expected value of body_size results
+type sizeBounds struct {
+	Min *int `json:"min_bytes,omitempty"`
+	Max *int `json:"max_bytes,omitempty"`
+}
+
+// NeedsSize reports whether any of the assertions needs the full body size
+func NeedsSize(assertions []models.ContentAssertion) bool {
+	for _, a := range assertions {
+		if a.Type == TypeBodySize {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateContent checks that the assertion has a known type and the fields the type needs
+func ValidateContent(a models.ContentAssertion) error {
+	switch a.Type {
+	case TypeContains, TypeNotContains:
+		if a.Value == "" {
+			return fmt.Errorf("%s needs a value", a.Type)
+		}
+	case TypeRegex:
+		if _, err := regexp.Compile(a.Value); err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+	case TypeHeaderEquals, TypeHeaderMatches:
+		if a.Header == "" {
+			return fmt.Errorf("%s needs a header", a.Type)
+		}
+		if a.Type == TypeHeaderMatches {
+			if _, err := regexp.Compile(a.Value); err != nil {
+				return fmt.Errorf("invalid regex: %w", err)
+			}
+		}
+	case TypeBodySize:
+		if a.MinBytes == nil && a.MaxBytes == nil {
+			return fmt.Errorf("body_size needs min_bytes or max_bytes")
+		}
+		for _, bound := range []*int{a.MinBytes, a.MaxBytes} {
+			if bound != nil && (*bound < 0 || *bound > MaxBodySizeBytes) {
+				return fmt.Errorf("body size bounds must be between 0 and %d", MaxBodySizeBytes)
+			}
+		}
+		if a.MinBytes != nil && a.MaxBytes != nil && *a.MinBytes > *a.MaxBytes {
+			return fmt.Errorf("min_bytes must not be greater than max_bytes")
+		}
+	default:
+		return fmt.Errorf("unknown assertion type %q", a.Type)
+	}
+
+	return nil
+}
+
+// EvaluateContent evaluates every assertion against the response
+func EvaluateContent(resp Response, assertions []models.ContentAssertion) []models.AssertionResult {
+	results := make([]models.AssertionResult, 0, len(assertions))
+
+	for i, a := range assertions {
+		result := models.AssertionResult{Type: a.Type, Header: a.Header}
+		if a.Type == TypeBodySize {
+			result.Expected = sizeBounds{Min: a.MinBytes, Max: a.MaxBytes}
+		} else {
+			result.Expected = a.Value
+		}
+
+		var text TextMatch
+		if i < len(resp.Text) {
+			text = resp.Text[i]
+		}
+
+		evaluateContent(a, resp, text, &result)
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// evaluateContent checks a single assertion against the response
+func evaluateContent(a models.ContentAssertion, resp Response, text TextMatch, result *models.AssertionResult) {
+	switch a.Type {
+	case TypeContains, TypeNotContains, TypeRegex:
+		if text.Err != nil {
+			result.Error = text.Err.Error()
+			return
+		}
+
+		result.Passed = text.Matched
+		if a.Type == TypeNotContains {
+			result.Passed = !text.Matched
+		}
+	case TypeHeaderEquals, TypeHeaderMatches:
+		values, ok := resp.Header[http.CanonicalHeaderKey(a.Header)]
+		if !ok || len(values) == 0 {
+			result.Error = "header not set"
+			return
+		}
+
+		actual := values[0]
+		result.Actual = actual
+
+		if a.Type == TypeHeaderEquals {
+			result.Passed = actual == a.Value
+			return
+		}
+
+		compile := resp.Compile
+		if compile == nil {
+			compile = regexp.Compile
+		}
+
+		regex, err := compile(a.Value)
+		if err != nil {
+			result.Error = fmt.Sprintf("invalid regex: %s", err.Error())
+			return
+		}
+		result.Passed = regex.MatchString(actual)
+	case TypeBodySize:
+		result.Actual = resp.Size
+		result.Passed = (a.MinBytes == nil || resp.Size >= int64(*a.MinBytes)) &&
+			(a.MaxBytes == nil || resp.Size <= int64(*a.MaxBytes))
+	default:
+		result.Error = fmt.Sprintf("unknown assertion type %q", a.Type)
+	}
+}
@@ -0,0 +1,133 @@
+package assertion
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"website-monitor/internal/models"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// textMatches matches the whole body against the text assertions, the way the checker does while streaming it
+func textMatches(body string, assertions []models.ContentAssertion) []TextMatch {
+	matches := make([]TextMatch, len(assertions))
+	for i, a := range assertions {
+		pattern := a.Value
+		if a.Type != TypeRegex {
+			pattern = regexp.QuoteMeta(a.Value)
+		}
+
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			matches[i].Err = err
+			continue
+		}
+		matches[i].Matched = regex.MatchString(body)
+	}
+
+	return matches
+}
+
+func TestEvaluateContent(t *testing.T) {
+	body := "<html><body>Welcome back</body></html>"
+	header := http.Header{"Content-Type": []string{"text/html; charset=utf-8"}, "X-Version": []string{"1.2.3"}}
+
+	tests := []struct {
+		name      string
+		assertion models.ContentAssertion
+		passed    bool
+	}{
+		{"contains", models.ContentAssertion{Type: TypeContains, Value: "Welcome"}, true},
+		{"contains missing", models.ContentAssertion{Type: TypeContains, Value: "Goodbye"}, false},
+		{"not contains", models.ContentAssertion{Type: TypeNotContains, Value: "Maintenance"}, true},
+		{"not contains present", models.ContentAssertion{Type: TypeNotContains, Value: "Welcome"}, false},
+		{"regex", models.ContentAssertion{Type: TypeRegex, Value: `<body>\w+`}, true},
+		{"regex no match", models.ContentAssertion{Type: TypeRegex, Value: `Database \w+`}, false},
+		{"header equals", models.ContentAssertion{Type: TypeHeaderEquals, Header: "x-version", Value: "1.2.3"}, true},
+		{"header differs", models.ContentAssertion{Type: TypeHeaderEquals, Header: "X-Version", Value: "1.2.4"}, false},
+		{"header missing", models.ContentAssertion{Type: TypeHeaderEquals, Header: "X-Missing", Value: "1"}, false},
+		{"header matches", models.ContentAssertion{Type: TypeHeaderMatches, Header: "Content-Type", Value: `^text/html`}, true},
+		{"body size within", models.ContentAssertion{Type: TypeBodySize, MinBytes: intPtr(10), MaxBytes: intPtr(100)}, true},
+		{"body size too small", models.ContentAssertion{Type: TypeBodySize, MinBytes: intPtr(100)}, false},
+		{"body size too large", models.ContentAssertion{Type: TypeBodySize, MaxBytes: intPtr(10)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertions := []models.ContentAssertion{tt.assertion}
+			resp := Response{Header: header, Text: textMatches(body, assertions), Size: int64(len(body))}
+			results := EvaluateContent(resp, assertions)
+
+			if results[0].Passed != tt.passed {
+				t.Errorf("Expected passed %v, got %+v", tt.passed, results[0])
+			}
+
+			if results[0].Type != tt.assertion.Type {
+				t.Errorf("Expected type %s, got %s", tt.assertion.Type, results[0].Type)
+			}
+		})
+	}
+}
+
+func TestFirstFailure_ContentAssertions(t *testing.T) {
+	assertions := []models.ContentAssertion{
+		{Type: TypeContains, Value: "Maintenance"},
+		{Type: TypeNotContains, Value: "Maintenance"},
+	}
+	resp := Response{
+		Header: http.Header{"Content-Type": []string{"text/html"}},
+		Text:   textMatches("Down for Maintenance", assertions),
+		Size:   20,
+	}
+
+	results := EvaluateContent(resp, assertions)
+	if failure := FirstFailure(results); failure != `not_contains "Maintenance"` {
+		t.Errorf("Unexpected failure %q", failure)
+	}
+
+	results = EvaluateContent(resp, []models.ContentAssertion{{Type: TypeHeaderEquals, Header: "Content-Type", Value: "application/json"}})
+	if failure := FirstFailure(results); failure != `header_equals Content-Type "application/json", got "text/html"` {
+		t.Errorf("Unexpected failure %q", failure)
+	}
+
+	results = EvaluateContent(resp, []models.ContentAssertion{{Type: TypeBodySize, MinBytes: intPtr(1024)}})
+	if failure := FirstFailure(results); failure != `body_size {"min_bytes":1024}, got 20` {
+		t.Errorf("Unexpected failure %q", failure)
+	}
+}
+
+func TestValidateContent(t *testing.T) {
+	valid := []models.ContentAssertion{
+		{Type: TypeContains, Value: "ok"},
+		{Type: TypeNotContains, Value: "Database error"},
+		{Type: TypeRegex, Value: `v\d+`},
+		{Type: TypeHeaderEquals, Header: "Content-Type", Value: "application/json"},
+		{Type: TypeHeaderMatches, Header: "Cache-Control", Value: "max-age=\\d+"},
+		{Type: TypeBodySize, MinBytes: intPtr(1), MaxBytes: intPtr(1024)},
+	}
+	for _, a := range valid {
+		if err := ValidateContent(a); err != nil {
+			t.Errorf("Expected %+v to be valid, got: %v", a, err)
+		}
+	}
+
+	invalid := []models.ContentAssertion{
+		{Type: "matches", Value: "ok"},
+		{Type: TypeContains},
+		{Type: TypeRegex, Value: "[invalid"},
+		{Type: TypeHeaderEquals, Value: "application/json"},
+		{Type: TypeHeaderMatches, Header: "Content-Type", Value: "("},
+		{Type: TypeBodySize},
+		{Type: TypeBodySize, MinBytes: intPtr(10), MaxBytes: intPtr(1)},
+		{Type: TypeBodySize, MaxBytes: intPtr(MaxBodySizeBytes + 1)},
+	}
+	for _, a := range invalid {
+		if err := ValidateContent(a); err == nil {
+			t.Errorf("Expected %+v to be invalid", a)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package assertion
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"website-monitor/internal/models"
+)
+
+// Operators of JSON assertions
+const (
+	OpExists    = "exists"
+	OpNotExists = "not_exists"
+	OpEquals    = "equals"
+	OpNotEquals = "not_equals"
+	OpGreater   = "gt"
+	OpGreaterEq = "gte"
+	OpLess      = "lt"
+	OpLessEq    = "lte"
+)
+
+// TypeJSON is the result type of JSON assertions
+const TypeJSON = "json"
+
+// numericOps compare the selected value with a number
+var numericOps = map[string]bool{OpGreater: true, OpGreaterEq: true, OpLess: true, OpLessEq: true}
+
+// ValidateJSON checks that the assertion has a valid path, a known operator and a value fitting the operator
+func ValidateJSON(a models.JSONAssertion) error {
+	if _, err := parsePath(a.Path); err != nil {
+		return fmt.Errorf("invalid path %q: %w", a.Path, err)
+	}
+
+	switch {
+	case a.Op == OpExists || a.Op == OpNotExists:
+		if a.Value != nil {
+			return fmt.Errorf("%s takes no value", a.Op)
+		}
+	case a.Op == OpEquals || a.Op == OpNotEquals:
+	case numericOps[a.Op]:
+		if _, ok := normalize(a.Value).(float64); !ok {
+			return fmt.Errorf("%s needs a numeric value", a.Op)
+		}
+	default:
+		return fmt.Errorf("unknown operator %q, expected one of %s", a.Op,
+			strings.Join([]string{OpExists, OpNotExists, OpEquals, OpNotEquals, OpGreater, OpGreaterEq, OpLess, OpLessEq}, ", "))
+	}
+
+	return nil
+}
+
+// EvaluateJSON parses the body as JSON and evaluates every assertion against it.
+// If the body is not valid JSON all assertions fail with the parse error
+func EvaluateJSON(body []byte, assertions []models.JSONAssertion) []models.AssertionResult {
+	results := make([]models.AssertionResult, 0, len(assertions))
+
+	var document interface{}
+	parseErr := json.Unmarshal(body, &document)
+
+	for _, a := range assertions {
+		result := models.AssertionResult{Type: TypeJSON, Path: a.Path, Op: a.Op, Expected: a.Value}
+
+		if parseErr != nil {
+			result.Error = fmt.Sprintf("response is not valid JSON: %s", parseErr.Error())
+		} else {
+			evaluate(a, document, &result)
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// evaluate checks a single assertion against the parsed document
+func evaluate(a models.JSONAssertion, document interface{}, result *models.AssertionResult) {
+	p, err := parsePath(a.Path)
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+
+	actual, found := p.lookup(document)
+	if found && isScalar(actual) {
+		result.Actual = actual
+	}
+
+	expected := normalize(a.Value)
+
+	switch {
+	case a.Op == OpExists:
+		result.Passed = found
+	case a.Op == OpNotExists:
+		result.Passed = !found
+	case !found:
+		result.Error = "path not found"
+	case a.Op == OpEquals:
+		result.Passed = reflect.DeepEqual(actual, expected)
+	case a.Op == OpNotEquals:
+		result.Passed = !reflect.DeepEqual(actual, expected)
+	case numericOps[a.Op]:
+		number, ok := actual.(float64)
+		limit, limitOk := expected.(float64)
+		if !ok || !limitOk {
+			result.Error = "value is not a number"
+			return
+		}
+
+		switch a.Op {
+		case OpGreater:
+			result.Passed = number > limit
+		case OpGreaterEq:
+			result.Passed = number >= limit
+		case OpLess:
+			result.Passed = number < limit
+		case OpLessEq:
+			result.Passed = number <= limit
+		}
+	default:
+		result.Error = fmt.Sprintf("unknown operator %q", a.Op)
+	}
+}
+
+// normalize converts a value to the types encoding/json decodes into, so ints compare equal to float64 numbers
+func normalize(value interface{}) interface{} {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var normalized interface{}
+	if err := json.Unmarshal(encoded, &normalized); err != nil {
+		return value
+	}
+
+	return normalized
+}
+
+// isScalar reports whether the value is not an object or array, which are left out of results to keep them small
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	default:
+		return true
+	}
+}
+
+// format prints a value as JSON
+func format(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+
+	return string(encoded)
+}
@@ -0,0 +1,109 @@
+package assertion
+
+import (
+	"strings"
+	"testing"
+
+	"website-monitor/internal/models"
+)
+
+const healthBody = `{
+	"status": "ok",
+	"uptime": 3600,
+	"checks": [{"name": "db", "status": "ok"}, {"name": "cache", "status": "degraded"}],
+	"meta": {"build version": "1.2.3", "ready": true}
+}`
+
+func TestEvaluateJSON(t *testing.T) {
+	tests := []struct {
+		assertion models.JSONAssertion
+		passed    bool
+	}{
+		{models.JSONAssertion{Path: "$.status", Op: OpEquals, Value: "ok"}, true},
+		{models.JSONAssertion{Path: "$.status", Op: OpNotEquals, Value: "ok"}, false},
+		{models.JSONAssertion{Path: "$.uptime", Op: OpEquals, Value: 3600}, true},
+		{models.JSONAssertion{Path: "$.uptime", Op: OpGreater, Value: 60}, true},
+		{models.JSONAssertion{Path: "$.uptime", Op: OpLessEq, Value: 60}, false},
+		{models.JSONAssertion{Path: "$.checks[1].status", Op: OpEquals, Value: "ok"}, false},
+		{models.JSONAssertion{Path: "$.checks[0]['name']", Op: OpEquals, Value: "db"}, true},
+		{models.JSONAssertion{Path: "$.checks.length()", Op: OpGreaterEq, Value: 2}, true},
+		{models.JSONAssertion{Path: "$.checks.length()", Op: OpEquals, Value: 3}, false},
+		{models.JSONAssertion{Path: "$.meta['build version']", Op: OpExists}, true},
+		{models.JSONAssertion{Path: "$.meta.ready", Op: OpEquals, Value: true}, true},
+		{models.JSONAssertion{Path: "$.meta.missing", Op: OpExists}, false},
+		{models.JSONAssertion{Path: "$.meta.missing", Op: OpNotExists}, true},
+		{models.JSONAssertion{Path: "$.checks[5]", Op: OpEquals, Value: "db"}, false},
+		{models.JSONAssertion{Path: "$.status", Op: OpGreater, Value: 1}, false},
+		{models.JSONAssertion{Path: "$", Op: OpExists}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.assertion.Path+" "+tt.assertion.Op, func(t *testing.T) {
+			results := EvaluateJSON([]byte(healthBody), []models.JSONAssertion{tt.assertion})
+
+			if len(results) != 1 {
+				t.Fatalf("Expected 1 result, got %d", len(results))
+			}
+
+			if results[0].Passed != tt.passed {
+				t.Errorf("Expected passed %v, got %+v", tt.passed, results[0])
+			}
+		})
+	}
+}
+
+func TestEvaluateJSON_InvalidBody(t *testing.T) {
+	results := EvaluateJSON([]byte("<html>"), []models.JSONAssertion{{Path: "$.status", Op: OpExists}})
+
+	if results[0].Passed || !strings.Contains(results[0].Error, "not valid JSON") {
+		t.Errorf("Expected parse error, got %+v", results[0])
+	}
+}
+
+func TestFirstFailure(t *testing.T) {
+	results := EvaluateJSON([]byte(healthBody), []models.JSONAssertion{
+		{Path: "$.status", Op: OpEquals, Value: "ok"},
+		{Path: "$.checks[1].status", Op: OpEquals, Value: "ok"},
+		{Path: "$.missing", Op: OpEquals, Value: 1},
+	})
+
+	if failure := FirstFailure(results); failure != `$.checks[1].status equals "ok", got "degraded"` {
+		t.Errorf("Unexpected failure %q", failure)
+	}
+
+	if failure := FirstFailure(results[2:]); failure != "$.missing equals 1: path not found" {
+		t.Errorf("Unexpected failure %q", failure)
+	}
+
+	if failure := FirstFailure(results[:1]); failure != "" {
+		t.Errorf("Expected no failure, got %q", failure)
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	valid := []models.JSONAssertion{
+		{Path: "$.status", Op: OpEquals, Value: "ok"},
+		{Path: "$.items.length()", Op: OpGreater, Value: 0},
+		{Path: `$["a"][0].b`, Op: OpExists},
+	}
+	for _, a := range valid {
+		if err := ValidateJSON(a); err != nil {
+			t.Errorf("Expected %+v to be valid, got: %v", a, err)
+		}
+	}
+
+	invalid := []models.JSONAssertion{
+		{Path: "status", Op: OpEquals, Value: "ok"},
+		{Path: "$.items[", Op: OpExists},
+		{Path: "$.items[-1]", Op: OpExists},
+		{Path: "$..items", Op: OpExists},
+		{Path: "$.status", Op: "matches", Value: "ok"},
+		{Path: "$.status", Op: OpExists, Value: "ok"},
+		{Path: "$.uptime", Op: OpGreater, Value: "60"},
+	}
+	for _, a := range invalid {
+		if err := ValidateJSON(a); err == nil {
+			t.Errorf("Expected %+v to be invalid", a)
+		}
+	}
+}
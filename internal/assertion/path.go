@@ -0,0 +1,122 @@
+package assertion
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// lengthSuffix turns the value selected by a path into its length
+const lengthSuffix = ".length()"
+
+// path is a parsed JSONPath-style expression like $.data.items[0]['display name'].length()
+type path struct {
+	steps  []step
+	length bool
+}
+
+// step selects an object member by key or an array element by index
+type step struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parsePath parses the supported subset of JSONPath: the root $, dotted and bracketed member names,
+// array indexes and a trailing .length()
+func parsePath(expr string) (path, error) {
+	var p path
+
+	if !strings.HasPrefix(expr, "$") {
+		return p, fmt.Errorf("path must start with $")
+	}
+	rest := expr[1:]
+
+	if strings.HasSuffix(rest, lengthSuffix) {
+		p.length = true
+		rest = strings.TrimSuffix(rest, lengthSuffix)
+	}
+
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end == -1 {
+				end = len(rest) - 1
+			}
+
+			key := rest[1 : end+1]
+			if key == "" {
+				return p, fmt.Errorf("empty member name in %q", expr)
+			}
+
+			p.steps = append(p.steps, step{key: key})
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return p, fmt.Errorf("unclosed bracket in %q", expr)
+			}
+
+			inner := rest[1:end]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				p.steps = append(p.steps, step{key: inner[1 : len(inner)-1]})
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return p, fmt.Errorf("invalid array index %q in %q", inner, expr)
+				}
+
+				p.steps = append(p.steps, step{index: index, isIndex: true})
+			}
+
+			rest = rest[end+1:]
+		default:
+			return p, fmt.Errorf("unexpected %q in %q", rest[0], expr)
+		}
+	}
+
+	return p, nil
+}
+
+// lookup returns the value selected by the path and whether it exists
+func (p path) lookup(document interface{}) (interface{}, bool) {
+	current := document
+
+	for _, s := range p.steps {
+		if s.isIndex {
+			array, ok := current.([]interface{})
+			if !ok || s.index >= len(array) {
+				return nil, false
+			}
+			current = array[s.index]
+
+			continue
+		}
+
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		current, ok = object[s.key]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	if !p.length {
+		return current, true
+	}
+
+	switch value := current.(type) {
+	case []interface{}:
+		return float64(len(value)), true
+	case map[string]interface{}:
+		return float64(len(value)), true
+	case string:
+		return float64(len([]rune(value))), true
+	default:
+		return nil, false
+	}
+}
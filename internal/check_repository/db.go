@@ -0,0 +1,193 @@
+package check_repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"website-monitor/internal/db"
+	"website-monitor/internal/models"
+)
+
+// successCondition matches checks that models.CheckResult.Failed would consider successful
+const successCondition = `COALESCE(error, '') = '' AND regex_match IS DISTINCT FROM false`
+
+// DbCheckRepository implements CheckRepository using database as the data source
+type DbCheckRepository struct {
+	db *db.DB
+}
+
+func New(database *db.DB) *DbCheckRepository {
+	return &DbCheckRepository{
+		db: database,
+	}
+}
+
+// GetChecks returns checks matching the filter, newest first
+func (r *DbCheckRepository) GetChecks(ctx context.Context, filter CheckFilter) ([]models.CheckResult, error) {
+	query := `
+		SELECT id, url, check_timestamp, response_time_ms, http_status, regex_match, COALESCE(error, ''),
+			tls_certificates, tls_expires_at, tls_days_remaining, COALESCE(warning, ''),
+			dns_ms, connect_ms, tls_handshake_ms, ttfb_ms, transfer_ms, assertion_results, body_truncated,
+			attempts, attempt_errors, redirects, skipped, COALESCE(location, '')
+		FROM checks
+		WHERE url = $1
+			AND ($2::timestamptz IS NULL OR check_timestamp >= $2)
+			AND ($3::timestamptz IS NULL OR check_timestamp < $3)
+			AND ($6 = '' OR location = $6)
+		ORDER BY check_timestamp DESC, id DESC
+		LIMIT $4 OFFSET $5`
+
+	rows, err := r.db.QueryContext(ctx, query, filter.URL, filter.From, filter.To, filter.Limit, filter.Offset, filter.Location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query checks: %w", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var checks []models.CheckResult
+	for rows.Next() {
+		var check models.CheckResult
+		var certificates, assertionResults, attemptErrors, redirects []byte
+		err := rows.Scan(
+			&check.ID,
+			&check.URL,
+			&check.CheckTimestamp,
+			&check.ResponseTimeMs,
+			&check.HttpStatus,
+			&check.RegexMatch,
+			&check.Error,
+			&certificates,
+			&check.CertExpiresAt,
+			&check.CertDaysRemaining,
+			&check.Warning,
+			&check.DnsMs,
+			&check.ConnectMs,
+			&check.TlsHandshakeMs,
+			&check.TimeToFirstByteMs,
+			&check.TransferMs,
+			&assertionResults,
+			&check.BodyTruncated,
+			&check.Attempts,
+			&attemptErrors,
+			&redirects,
+			&check.Skipped,
+			&check.Location,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan check: %w", err)
+		}
+
+		if certificates != nil {
+			if err := json.Unmarshal(certificates, &check.Certificates); err != nil {
+				return nil, fmt.Errorf("failed to decode certificates of check %d: %w", check.ID, err)
+			}
+		}
+
+		if assertionResults != nil {
+			if err := json.Unmarshal(assertionResults, &check.AssertionResults); err != nil {
+				return nil, fmt.Errorf("failed to decode assertion results of check %d: %w", check.ID, err)
+			}
+		}
+
+		if attemptErrors != nil {
+			if err := json.Unmarshal(attemptErrors, &check.AttemptErrors); err != nil {
+				return nil, fmt.Errorf("failed to decode attempt errors of check %d: %w", check.ID, err)
+			}
+		}
+
+		if redirects != nil {
+			if err := json.Unmarshal(redirects, &check.Redirects); err != nil {
+				return nil, fmt.Errorf("failed to decode redirects of check %d: %w", check.ID, err)
+			}
+		}
+		checks = append(checks, check)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over checks: %w", err)
+	}
+
+	return checks, nil
+}
+
+// GetStats aggregates checks of the url with timestamps in [from, to). Skipped checks are only counted as such
+func (r *DbCheckRepository) GetStats(ctx context.Context, url string, from, to time.Time) (models.CheckStats, error) {
+	stats := models.CheckStats{
+		URL:         url,
+		From:        from,
+		To:          to,
+		StatusCodes: map[string]int{},
+	}
+
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE NOT skipped),
+			COUNT(*) FILTER (WHERE NOT skipped AND ` + successCondition + `),
+			COUNT(*) FILTER (WHERE COALESCE(error, '') <> ''),
+			COUNT(*) FILTER (WHERE skipped),
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY response_time_ms),
+			percentile_cont(0.95) WITHIN GROUP (ORDER BY response_time_ms),
+			percentile_cont(0.99) WITHIN GROUP (ORDER BY response_time_ms)
+		FROM checks
+		WHERE url = $1 AND check_timestamp >= $2 AND check_timestamp < $3`
+
+	err := r.db.QueryRowContext(ctx, query, url, from, to).Scan(
+		&stats.TotalChecks,
+		&stats.SuccessfulChecks,
+		&stats.ErrorCount,
+		&stats.SkippedChecks,
+		&stats.ResponseTimeP50Ms,
+		&stats.ResponseTimeP95Ms,
+		&stats.ResponseTimeP99Ms,
+	)
+	if err != nil {
+		return stats, fmt.Errorf("failed to query check stats: %w", err)
+	}
+
+	if stats.TotalChecks > 0 {
+		uptime := float64(stats.SuccessfulChecks) / float64(stats.TotalChecks) * 100
+		stats.UptimePercent = &uptime
+	}
+
+	if err := r.queryStatusCodes(ctx, &stats); err != nil {
+		return stats, err
+	}
+
+	return stats, nil
+}
+
+// queryStatusCodes fills in the distribution of HTTP status codes, checks without a response are not counted
+func (r *DbCheckRepository) queryStatusCodes(ctx context.Context, stats *models.CheckStats) error {
+	query := `
+		SELECT http_status, COUNT(*)
+		FROM checks
+		WHERE url = $1 AND check_timestamp >= $2 AND check_timestamp < $3 AND http_status IS NOT NULL
+		GROUP BY http_status`
+
+	rows, err := r.db.QueryContext(ctx, query, stats.URL, stats.From, stats.To)
+	if err != nil {
+		return fmt.Errorf("failed to query status codes: %w", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	for rows.Next() {
+		var status, count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return fmt.Errorf("failed to scan status code: %w", err)
+		}
+		stats.StatusCodes[strconv.Itoa(status)] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over status codes: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,196 @@
+package check_repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"website-monitor/internal/check_repository"
+	"website-monitor/internal/db"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetChecks_HappyPath(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating mock db: %v", err)
+	}
+	defer sqlDB.Close()
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	timestamp := from.Add(time.Hour)
+	expiresAt := time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT (.+) FROM checks WHERE url = \$1`).
+		WithArgs("https://example.com", &from, nil, 10, 20, "eu-west").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "url", "check_timestamp", "response_time_ms", "http_status", "regex_match", "error",
+				"tls_certificates", "tls_expires_at", "tls_days_remaining", "warning",
+				"dns_ms", "connect_ms", "tls_handshake_ms", "ttfb_ms", "transfer_ms", "assertion_results", "body_truncated",
+				"attempts", "attempt_errors", "redirects", "skipped", "location"}).
+				AddRow(2, "https://example.com", timestamp, 120, 200, true, "",
+					[]byte(`[{"subject":"CN=example.com","issuer":"CN=Example CA","not_before":"2023-01-01T00:00:00Z","not_after":"2024-01-06T00:00:00Z"}]`),
+					expiresAt, 4, "TLS certificate expires in 4 days, critical threshold is 7 days",
+					5, 10, 30, 110, 10, []byte(`[{"path":"$.status","op":"equals","expected":"ok","actual":"ok","passed":true}]`), true,
+					2, []byte(`["connection reset by peer"]`),
+					[]byte(`[{"url":"http://example.com","status":301,"location":"https://example.com"}]`), false, "eu-west").
+				AddRow(1, "https://example.com", timestamp, 30000, nil, nil, "timeout", nil, nil, nil, "", 5, nil, nil, nil, nil, nil, false, 1, nil, nil, false, "eu-west").
+				AddRow(3, "https://example.com", timestamp, nil, nil, nil, "", nil, nil, nil, "skipped, 1 check still running", nil, nil, nil, nil, nil, nil, false, 1, nil, nil, true, "eu-west"),
+		)
+
+	repo := check_repository.New(db.New(sqlDB))
+
+	checks, err := repo.GetChecks(context.Background(), check_repository.CheckFilter{
+		URL:      "https://example.com",
+		From:     &from,
+		Location: "eu-west",
+		Limit:    10,
+		Offset:   20,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(checks) != 3 {
+		t.Fatalf("expected 3 checks, got %d", len(checks))
+	}
+
+	if checks[0].Location != "eu-west" {
+		t.Errorf("expected location eu-west, got %q", checks[0].Location)
+	}
+
+	if checks[0].HttpStatus == nil || *checks[0].HttpStatus != 200 {
+		t.Errorf("expected status 200, got %v", checks[0].HttpStatus)
+	}
+
+	if len(checks[0].Certificates) != 1 || checks[0].Certificates[0].Subject != "CN=example.com" {
+		t.Errorf("expected certificate chain to be decoded, got %+v", checks[0].Certificates)
+	}
+
+	if checks[0].CertDaysRemaining == nil || *checks[0].CertDaysRemaining != 4 || checks[0].Warning == "" {
+		t.Errorf("expected expiry warning, got %+v", checks[0])
+	}
+
+	if checks[0].TimeToFirstByteMs == nil || *checks[0].TimeToFirstByteMs != 110 || checks[1].ConnectMs != nil {
+		t.Errorf("expected timings to be scanned, got %+v and %+v", checks[0], checks[1])
+	}
+
+	if checks[0].Attempts != 2 || len(checks[0].AttemptErrors) != 1 || checks[1].AttemptErrors != nil {
+		t.Errorf("expected attempts to be scanned, got %d %v and %v", checks[0].Attempts, checks[0].AttemptErrors, checks[1].AttemptErrors)
+	}
+
+	if len(checks[0].Redirects) != 1 || checks[0].Redirects[0].Status != 301 || checks[1].Redirects != nil {
+		t.Errorf("expected redirects to be decoded, got %+v and %+v", checks[0].Redirects, checks[1].Redirects)
+	}
+
+	if !checks[0].BodyTruncated || checks[1].BodyTruncated {
+		t.Errorf("expected body truncation to be scanned, got %v and %v", checks[0].BodyTruncated, checks[1].BodyTruncated)
+	}
+
+	if len(checks[0].AssertionResults) != 1 || !checks[0].AssertionResults[0].Passed {
+		t.Errorf("expected assertion results to be decoded, got %+v", checks[0].AssertionResults)
+	}
+
+	if checks[1].HttpStatus != nil || checks[1].Error != "timeout" {
+		t.Errorf("expected failed check without status, got %+v", checks[1])
+	}
+
+	if checks[1].Certificates != nil || checks[1].CertExpiresAt != nil {
+		t.Errorf("expected no certificate info, got %+v", checks[1])
+	}
+
+	if checks[1].Skipped || !checks[2].Skipped || checks[2].ResponseTimeMs != nil {
+		t.Errorf("expected skipped check to be scanned, got %+v and %+v", checks[1], checks[2])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet sqlmock expectations: %v", err)
+	}
+}
+
+func TestGetStats_HappyPath(t *testing.T) {
+	sqlDB, mock, _ := sqlmock.New()
+	defer sqlDB.Close()
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	mock.ExpectQuery(`SELECT (.+) percentile_cont(.+) FROM checks`).
+		WithArgs("https://example.com", from, to).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"count", "successful", "errors", "skipped", "p50", "p95", "p99"}).
+				AddRow(4, 3, 1, 2, 100.0, 450.5, 900.0),
+		)
+	mock.ExpectQuery(`SELECT http_status, COUNT\(\*\) FROM checks`).
+		WithArgs("https://example.com", from, to).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"http_status", "count"}).
+				AddRow(200, 3),
+		)
+
+	repo := check_repository.New(db.New(sqlDB))
+
+	stats, err := repo.GetStats(context.Background(), "https://example.com", from, to)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if stats.TotalChecks != 4 || stats.SuccessfulChecks != 3 || stats.ErrorCount != 1 || stats.SkippedChecks != 2 {
+		t.Errorf("unexpected counts %+v", stats)
+	}
+
+	if stats.UptimePercent == nil || *stats.UptimePercent != 75 {
+		t.Errorf("expected uptime 75%%, got %v", stats.UptimePercent)
+	}
+
+	if stats.ResponseTimeP95Ms == nil || *stats.ResponseTimeP95Ms != 450.5 {
+		t.Errorf("expected p95 450.5, got %v", stats.ResponseTimeP95Ms)
+	}
+
+	if stats.StatusCodes["200"] != 3 {
+		t.Errorf("expected 3 checks with status 200, got %v", stats.StatusCodes)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet sqlmock expectations: %v", err)
+	}
+}
+
+func TestGetStats_NoChecks(t *testing.T) {
+	sqlDB, mock, _ := sqlmock.New()
+	defer sqlDB.Close()
+
+	mock.ExpectQuery(`SELECT (.+) percentile_cont(.+) FROM checks`).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"count", "successful", "errors", "skipped", "p50", "p95", "p99"}).
+				AddRow(0, 0, 0, 0, nil, nil, nil),
+		)
+	mock.ExpectQuery(`SELECT http_status, COUNT\(\*\) FROM checks`).
+		WillReturnRows(sqlmock.NewRows([]string{"http_status", "count"}))
+
+	repo := check_repository.New(db.New(sqlDB))
+
+	stats, err := repo.GetStats(context.Background(), "https://example.com", time.Now().Add(-time.Hour), time.Now())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if stats.UptimePercent != nil {
+		t.Errorf("expected no uptime without checks, got %v", *stats.UptimePercent)
+	}
+}
+
+func TestGetChecks_QueryError(t *testing.T) {
+	sqlDB, mock, _ := sqlmock.New()
+	defer sqlDB.Close()
+
+	mock.ExpectQuery(`SELECT (.+) FROM checks`).WillReturnError(sql.ErrConnDone)
+
+	repo := check_repository.New(db.New(sqlDB))
+
+	if _, err := repo.GetChecks(context.Background(), check_repository.CheckFilter{URL: "https://example.com"}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
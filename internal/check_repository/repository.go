@@ -0,0 +1,28 @@
+package check_repository
+
+import (
+	"context"
+	"time"
+
+	"website-monitor/internal/models"
+)
+
+// CheckFilter selects a page of checks of a single url
+type CheckFilter struct {
+	URL string
+	// From and To limit the check timestamps to [From, To) if set
+	From *time.Time
+	To   *time.Time
+	// Location limits the checks to those run at the location if set
+	Location string
+	Limit    int
+	Offset   int
+}
+
+// CheckRepository defines the interface for reading stored checks
+type CheckRepository interface {
+	// GetChecks returns checks matching the filter, newest first
+	GetChecks(ctx context.Context, filter CheckFilter) ([]models.CheckResult, error)
+	// GetStats aggregates checks of the url with timestamps in [from, to)
+	GetStats(ctx context.Context, url string, from, to time.Time) (models.CheckStats, error)
+}
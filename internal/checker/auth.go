@@ -0,0 +1,303 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"website-monitor/internal/models"
+)
+
+// Authentication types
+const (
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	AuthOAuth2 = "oauth2"
+)
+
+const (
+	// tokenExpiryMargin renews oauth2 tokens this long before they expire, so they do not expire mid check
+	tokenExpiryMargin = 30 * time.Second
+	// defaultTokenLifetime is used for tokens returned without expires_in
+	defaultTokenLifetime = 5 * time.Minute
+	// maxTokenResponseBytes limits how much of a token response is read
+	maxTokenResponseBytes = 64 * 1024
+)
+
+// SecretEnvPrefix starts the names of the environment variables auth secrets are read from. Urls are created through
+// the API, so they must not be able to read the configuration of the monitor itself, like DB_PASSWORD or AGENT_TOKEN
+const SecretEnvPrefix = "MONITOR_SECRET_"
+
+// envNameRegex matches the names of environment variables auth secrets may be read from
+var envNameRegex = regexp.MustCompile(`^` + SecretEnvPrefix + `[A-Za-z0-9_]+$`)
+
+// HostsEnvSuffix is appended to the name of a secret variable to get the variable listing the hosts the secret may be
+// sent to. Urls are created through the API, so without it anyone could send a secret to their own host
+const HostsEnvSuffix = "_HOSTS"
+
+// ValidateAuth checks that the auth has a known type, the fields the type needs and that its secret may be sent to the
+// host of rawURL
+func ValidateAuth(auth models.Auth, rawURL string) error {
+	var envs []string
+
+	switch auth.Type {
+	case AuthBasic:
+		if auth.Username == "" {
+			return fmt.Errorf("basic needs a username")
+		}
+		envs = []string{auth.PasswordEnv}
+	case AuthBearer:
+		envs = []string{auth.TokenEnv}
+	case AuthOAuth2:
+		parsed, err := neturl.Parse(auth.TokenURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("oauth2 needs an absolute http or https token_url")
+		}
+		if auth.ClientID == "" {
+			return fmt.Errorf("oauth2 needs a client_id")
+		}
+		envs = []string{auth.ClientSecretEnv}
+	default:
+		return fmt.Errorf("unknown auth type %q", auth.Type)
+	}
+
+	for _, env := range envs {
+		if !envNameRegex.MatchString(env) {
+			return fmt.Errorf("%s needs the name of an environment variable starting with %s holding its secret, got %q", auth.Type, SecretEnvPrefix, env)
+		}
+	}
+
+	// A missing url is reported by the caller, there is no host to check yet
+	if rawURL == "" {
+		return nil
+	}
+
+	return allowHosts(auth, rawURL)
+}
+
+// allowHosts checks that the secret of the auth may be sent to the host of rawURL and, for oauth2, of the token_url
+func allowHosts(auth models.Auth, rawURL string) error {
+	var env string
+	urls := []string{rawURL}
+
+	switch auth.Type {
+	case AuthBasic:
+		env = auth.PasswordEnv
+	case AuthBearer:
+		env = auth.TokenEnv
+	case AuthOAuth2:
+		env = auth.ClientSecretEnv
+		urls = append(urls, auth.TokenURL)
+	default:
+		return fmt.Errorf("unknown auth type %q", auth.Type)
+	}
+
+	if !envNameRegex.MatchString(env) {
+		return fmt.Errorf("environment variable %s does not start with %s", env, SecretEnvPrefix)
+	}
+
+	hosts := allowedHosts(env)
+	if len(hosts) == 0 {
+		return fmt.Errorf("environment variable %s%s listing the hosts %s may be sent to is not set", env, HostsEnvSuffix, env)
+	}
+
+	for _, u := range urls {
+		parsed, err := neturl.Parse(u)
+		if err != nil || parsed.Hostname() == "" {
+			return fmt.Errorf("%s needs an absolute url, got %q", auth.Type, u)
+		}
+
+		host := strings.ToLower(parsed.Hostname())
+		if !hosts[host] {
+			return fmt.Errorf("host %s is not listed in %s%s", host, env, HostsEnvSuffix)
+		}
+	}
+
+	return nil
+}
+
+// allowedHosts reads the comma separated hosts the secret in env may be sent to
+func allowedHosts(env string) map[string]bool {
+	hosts := make(map[string]bool)
+	for _, host := range strings.Split(os.Getenv(env+HostsEnvSuffix), ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts[host] = true
+		}
+	}
+
+	return hosts
+}
+
+// authenticate adds the credentials configured for the url to the request
+func (c *Checker) authenticate(ctx context.Context, req *http.Request, auth *models.Auth) error {
+	if auth == nil {
+		return nil
+	}
+
+	// Checked on every request, urls may have been stored or edited without validation, and the hosts may have changed
+	if err := allowHosts(*auth, req.URL.String()); err != nil {
+		return err
+	}
+
+	switch auth.Type {
+	case AuthBasic:
+		password, err := secret(auth.PasswordEnv)
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(auth.Username, password)
+	case AuthBearer:
+		token, err := secret(auth.TokenEnv)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case AuthOAuth2:
+		token, err := c.tokens.token(ctx, c.client, *auth)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	default:
+		return fmt.Errorf("unknown auth type %q", auth.Type)
+	}
+
+	return nil
+}
+
+// secret reads a secret from the environment of the monitor
+func secret(env string) (string, error) {
+	// Urls stored before the prefix was required, or edited in the database, are not validated again
+	if !envNameRegex.MatchString(env) {
+		return "", fmt.Errorf("environment variable %s does not start with %s", env, SecretEnvPrefix)
+	}
+
+	value, ok := os.LookupEnv(env)
+	if !ok || value == "" {
+		return "", fmt.Errorf("environment variable %s is not set", env)
+	}
+
+	return value, nil
+}
+
+// tokenCache keeps oauth2 access tokens until shortly before they expire, shared by all urls with the same client.
+// A nil tokenCache requests a new token every time
+type tokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]cachedToken
+}
+
+type cachedToken struct {
+	value     string
+	expiresAt time.Time
+}
+
+func newTokenCache() *tokenCache {
+	return &tokenCache{tokens: make(map[string]cachedToken)}
+}
+
+// tokenResponse is the part of a token endpoint response that is used
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// token returns a cached token of the client or requests a new one with the client credentials grant
+func (t *tokenCache) token(ctx context.Context, client *http.Client, auth models.Auth) (string, error) {
+	key := cacheKey(auth)
+
+	if t != nil {
+		t.mu.Lock()
+		cached, ok := t.tokens[key]
+		t.mu.Unlock()
+
+		if ok && time.Now().Before(cached.expiresAt) {
+			return cached.value, nil
+		}
+	}
+
+	token, err := requestToken(ctx, client, auth)
+	if err != nil {
+		return "", fmt.Errorf("failed to get oauth2 token: %w", err)
+	}
+
+	if t != nil {
+		t.mu.Lock()
+		t.tokens[key] = token
+		t.mu.Unlock()
+	}
+
+	return token.value, nil
+}
+
+// invalidate drops the cached token of the client, e.g. after it was rejected
+func (t *tokenCache) invalidate(auth models.Auth) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	delete(t.tokens, cacheKey(auth))
+	t.mu.Unlock()
+}
+
+// cacheKey identifies the tokens of a client. The secret is left out, it is the same for the same env variable
+func cacheKey(auth models.Auth) string {
+	return strings.Join([]string{auth.TokenURL, auth.ClientID, auth.ClientSecretEnv, strings.Join(auth.Scopes, " ")}, "\n")
+}
+
+// requestToken requests an access token from the token endpoint using the client credentials grant
+func requestToken(ctx context.Context, client *http.Client, auth models.Auth) (cachedToken, error) {
+	clientSecret, err := secret(auth.ClientSecretEnv)
+	if err != nil {
+		return cachedToken{}, err
+	}
+
+	form := neturl.Values{"grant_type": {"client_credentials"}}
+	if len(auth.Scopes) > 0 {
+		form.Set("scope", strings.Join(auth.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, auth.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return cachedToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(neturl.QueryEscape(auth.ClientID), neturl.QueryEscape(clientSecret))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return cachedToken{}, err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return cachedToken{}, fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var body tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxTokenResponseBytes)).Decode(&body); err != nil {
+		return cachedToken{}, fmt.Errorf("invalid token response: %w", err)
+	}
+	if body.AccessToken == "" {
+		return cachedToken{}, fmt.Errorf("token response has no access_token")
+	}
+
+	lifetime := defaultTokenLifetime
+	if body.ExpiresIn > 0 {
+		lifetime = time.Duration(body.ExpiresIn) * time.Second
+	}
+
+	return cachedToken{value: body.AccessToken, expiresAt: time.Now().Add(lifetime - tokenExpiryMargin)}, nil
+}
@@ -0,0 +1,215 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"website-monitor/internal/models"
+)
+
+func TestValidateAuth(t *testing.T) {
+	t.Setenv("MONITOR_SECRET_HEALTH_PASSWORD_HOSTS", "example.com")
+	t.Setenv("MONITOR_SECRET_HEALTH_TOKEN_HOSTS", "other.example.com, Example.com")
+	t.Setenv("MONITOR_SECRET_CLIENT_HOSTS", "example.com,auth.example.com")
+
+	valid := []models.Auth{
+		{Type: "basic", Username: "monitor", PasswordEnv: "MONITOR_SECRET_HEALTH_PASSWORD"},
+		{Type: "bearer", TokenEnv: "MONITOR_SECRET_HEALTH_TOKEN"},
+		{Type: "oauth2", TokenURL: "https://auth.example.com/token", ClientID: "monitor", ClientSecretEnv: "MONITOR_SECRET_CLIENT"},
+	}
+	for _, auth := range valid {
+		if err := ValidateAuth(auth, "https://example.com:8443/health"); err != nil {
+			t.Errorf("Expected %+v to be valid, got: %v", auth, err)
+		}
+	}
+
+	invalid := []models.Auth{
+		{Type: "digest"},
+		{Type: "basic", PasswordEnv: "MONITOR_SECRET_HEALTH_PASSWORD"},
+		{Type: "basic", Username: "monitor", PasswordEnv: "s3cr3t password"},
+		{Type: "bearer"},
+		{Type: "oauth2", TokenURL: "/token", ClientID: "monitor", ClientSecretEnv: "MONITOR_SECRET_CLIENT"},
+		{Type: "oauth2", TokenURL: "https://auth.example.com/token", ClientSecretEnv: "MONITOR_SECRET_CLIENT"},
+	}
+	for _, auth := range invalid {
+		if err := ValidateAuth(auth, "https://example.com/health"); err == nil {
+			t.Errorf("Expected %+v to be invalid", auth)
+		}
+	}
+}
+
+func TestValidateAuth_RejectsMonitorConfig(t *testing.T) {
+	for _, env := range []string{"DB_PASSWORD", "WEBHOOK_SECRET", "AGENT_TOKEN", "AGENT_TOKENS", "MONITOR_SECRET_"} {
+		auth := models.Auth{Type: "bearer", TokenEnv: env}
+		if err := ValidateAuth(auth, "https://example.com/health"); err == nil {
+			t.Errorf("Expected %s to be rejected", env)
+		}
+	}
+}
+
+func TestValidateAuth_RejectsUnlistedHosts(t *testing.T) {
+	t.Setenv("MONITOR_SECRET_HEALTH_TOKEN_HOSTS", "example.com")
+	t.Setenv("MONITOR_SECRET_CLIENT_HOSTS", "example.com")
+
+	tests := []struct {
+		name string
+		auth models.Auth
+		url  string
+	}{
+		{"other host", models.Auth{Type: "bearer", TokenEnv: "MONITOR_SECRET_HEALTH_TOKEN"}, "https://attacker.example.net/"},
+		{"subdomain", models.Auth{Type: "bearer", TokenEnv: "MONITOR_SECRET_HEALTH_TOKEN"}, "https://evil.example.com/"},
+		{"hosts not set", models.Auth{Type: "bearer", TokenEnv: "MONITOR_SECRET_UNLISTED"}, "https://example.com/"},
+		{"other token_url host", models.Auth{Type: "oauth2", TokenURL: "https://attacker.example.net/token", ClientID: "monitor",
+			ClientSecretEnv: "MONITOR_SECRET_CLIENT"}, "https://example.com/"},
+	}
+	for _, tt := range tests {
+		if err := ValidateAuth(tt.auth, tt.url); err == nil {
+			t.Errorf("%s: expected %s to be rejected", tt.name, tt.url)
+		}
+	}
+}
+
+func TestChecker_Check_RefusesUnlistedHost(t *testing.T) {
+	t.Setenv("MONITOR_SECRET_TEST_TOKEN", "token-1")
+	t.Setenv("MONITOR_SECRET_TEST_TOKEN_HOSTS", "example.com")
+
+	var authorization atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization.Store(r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	url := models.MonitoredUrl{ID: 1, Url: server.URL, CheckIntervalSec: 60, Auth: &models.Auth{Type: "bearer", TokenEnv: "MONITOR_SECRET_TEST_TOKEN"}}
+	result := checker.Check(context.Background(), url)
+	if result.Error != "failed to authenticate: host 127.0.0.1 is not listed in MONITOR_SECRET_TEST_TOKEN_HOSTS" {
+		t.Errorf("Unexpected error %q", result.Error)
+	}
+	if authorization.Load() != nil {
+		t.Errorf("Expected no request to be sent, got authorization %v", authorization.Load())
+	}
+}
+
+func TestChecker_Check_RefusesUnprefixedSecret(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "postgres")
+
+	var authorization atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization.Store(r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	url := models.MonitoredUrl{ID: 1, Url: server.URL, CheckIntervalSec: 60, Auth: &models.Auth{Type: "bearer", TokenEnv: "DB_PASSWORD"}}
+	result := checker.Check(context.Background(), url)
+	if result.Error != "failed to authenticate: environment variable DB_PASSWORD does not start with MONITOR_SECRET_" {
+		t.Errorf("Unexpected error %q", result.Error)
+	}
+	if authorization.Load() != nil {
+		t.Errorf("Expected no request to be sent, got authorization %v", authorization.Load())
+	}
+}
+
+func TestChecker_Check_BasicAndBearerAuth(t *testing.T) {
+	t.Setenv("MONITOR_SECRET_TEST_PASSWORD", "s3cret")
+	t.Setenv("MONITOR_SECRET_TEST_TOKEN", "token-1")
+	t.Setenv("MONITOR_SECRET_TEST_PASSWORD_HOSTS", "127.0.0.1")
+	t.Setenv("MONITOR_SECRET_TEST_TOKEN_HOSTS", "127.0.0.1")
+	t.Setenv("MONITOR_SECRET_TEST_MISSING_TOKEN_HOSTS", "127.0.0.1")
+
+	var authorization atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization.Store(r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	url := models.MonitoredUrl{ID: 1, Url: server.URL, CheckIntervalSec: 60,
+		Auth: &models.Auth{Type: "basic", Username: "monitor", PasswordEnv: "MONITOR_SECRET_TEST_PASSWORD"}}
+	if result := checker.Check(context.Background(), url); result.Error != "" {
+		t.Fatalf("Expected no error, got: %s", result.Error)
+	}
+	if authorization.Load() != "Basic bW9uaXRvcjpzM2NyZXQ=" {
+		t.Errorf("Unexpected basic authorization %v", authorization.Load())
+	}
+
+	url.Auth = &models.Auth{Type: "bearer", TokenEnv: "MONITOR_SECRET_TEST_TOKEN"}
+	checker.Check(context.Background(), url)
+	if authorization.Load() != "Bearer token-1" {
+		t.Errorf("Unexpected bearer authorization %v", authorization.Load())
+	}
+
+	url.Auth = &models.Auth{Type: "bearer", TokenEnv: "MONITOR_SECRET_TEST_MISSING_TOKEN"}
+	if result := checker.Check(context.Background(), url); result.Error != "failed to authenticate: environment variable MONITOR_SECRET_TEST_MISSING_TOKEN is not set" {
+		t.Errorf("Expected missing secret error, got: %s", result.Error)
+	}
+}
+
+func TestChecker_Check_OAuth2CachesToken(t *testing.T) {
+	t.Setenv("MONITOR_SECRET_TEST_CLIENT", "client-secret")
+	t.Setenv("MONITOR_SECRET_TEST_CLIENT_HOSTS", "127.0.0.1")
+
+	var tokenRequests int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientID, secret, _ := r.BasicAuth()
+		if r.FormValue("grant_type") != "client_credentials" || clientID != "monitor" || secret != "client-secret" ||
+			r.FormValue("scope") != "health:read" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if atomic.AddInt32(&tokenRequests, 1) == 1 {
+			w.Write([]byte(`{"access_token": "token-1", "token_type": "Bearer", "expires_in": 3600}`))
+			return
+		}
+		w.Write([]byte(`{"access_token": "token-2", "token_type": "Bearer", "expires_in": 3600}`))
+	}))
+	defer tokenServer.Close()
+
+	var accepted atomic.Value
+	accepted.Store("Bearer token-1")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != accepted.Load() {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+		tokens: newTokenCache(),
+	}
+
+	url := models.MonitoredUrl{ID: 1, Url: server.URL, CheckIntervalSec: 60, ExpectedStatusCodes: "200",
+		Auth: &models.Auth{Type: "oauth2", TokenURL: tokenServer.URL, ClientID: "monitor", ClientSecretEnv: "MONITOR_SECRET_TEST_CLIENT", Scopes: []string{"health:read"}}}
+
+	for i := 0; i < 2; i++ {
+		if result := checker.Check(context.Background(), url); result.Error != "" {
+			t.Fatalf("Expected no error, got: %s", result.Error)
+		}
+	}
+	if tokenRequests != 1 {
+		t.Errorf("Expected the token to be cached, got %d token requests", tokenRequests)
+	}
+
+	// A revoked token is rejected once, then replaced
+	accepted.Store("Bearer token-2")
+	if result := checker.Check(context.Background(), url); result.Error == "" {
+		t.Error("Expected the revoked token to be rejected")
+	}
+	if result := checker.Check(context.Background(), url); result.Error != "" {
+		t.Errorf("Expected a new token to be requested, got: %s", result.Error)
+	}
+}
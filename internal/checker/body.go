@@ -0,0 +1,307 @@
+package checker
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"regexp/syntax"
+	"unicode/utf8"
+
+	"website-monitor/internal/assertion"
+	"website-monitor/internal/models"
+)
+
+const (
+	// DefaultMaxBodyBytes is how much of the body is read if the url sets no max_body_bytes
+	DefaultMaxBodyBytes = 64 * 1024
+	// MaxBodyBytesLimit is the largest max_body_bytes a url can set
+	MaxBodyBytesLimit = 10 * 1024 * 1024
+)
+
+// bodyScan is the outcome of reading a response body through the matchers of a url
+type bodyScan struct {
+	// size is the number of bytes read, beyond the read limit only if a body_size assertion needs it
+	size int64
+	// truncated is set if the body was longer than the read limit
+	truncated bool
+	// regexMatch is the result of the regex_pattern, nil if the url has none or it is invalid
+	regexMatch *bool
+	regexErr   error
+	// text holds the results of the text content assertions, indexed like the assertions
+	text []assertion.TextMatch
+	// json is the buffered body for JSON assertions, nil if the url has none
+	json []byte
+}
+
+// bodyLimit returns how many bytes of the body are read for the url
+func bodyLimit(url models.MonitoredUrl) int64 {
+	if url.MaxBodyBytes > 0 {
+		return int64(url.MaxBodyBytes)
+	}
+
+	return DefaultMaxBodyBytes
+}
+
+// scanBody reads up to the read limit of the body and matches the regex and text assertions of the url against it.
+// Literal values and regexes whose matches span a bounded number of bytes are matched while the body streams
+// through. The body is only buffered for JSON assertions and for regexes that need all of it, like those with
+// unbounded repetitions or anchors like $, so they behave as on the whole page. The rest of the body is only
+// streamed to count its size
+func scanBody(body io.Reader, url models.MonitoredUrl, patterns *Patterns) (bodyScan, error) {
+	var scan bodyScan
+	var buf bytes.Buffer
+	var writers []io.Writer
+
+	// matchers hold the regex_pattern last and the text assertions by index, nil for those not matched against the body
+	matchers := make([]*bodyMatcher, len(url.Assertions)+1)
+	needsBuffer := len(url.JSONAssertions) > 0
+	add := func(i int, entry compiledPattern) {
+		matchers[i] = &bodyMatcher{regex: entry.regex}
+		if entry.window < 0 {
+			needsBuffer = true
+
+			return
+		}
+
+		matchers[i].stream = newStreamMatcher(entry.regex, entry.window)
+		writers = append(writers, matchers[i].stream)
+	}
+
+	if url.RegexPattern != "" {
+		if entry := patterns.compile(url.RegexPattern); entry.err != nil {
+			scan.regexErr = fmt.Errorf("invalid regex pattern: %w", entry.err)
+		} else {
+			add(len(url.Assertions), entry)
+		}
+	}
+
+	scan.text = make([]assertion.TextMatch, len(url.Assertions))
+	for i, a := range url.Assertions {
+		if a.Type == assertion.TypeHeaderMatches {
+			continue
+		}
+
+		pattern, ok := textPattern(a)
+		if !ok {
+			continue
+		}
+
+		entry := patterns.compile(pattern)
+		if entry.err != nil {
+			scan.text[i].Err = fmt.Errorf("invalid regex: %w", entry.err)
+			continue
+		}
+
+		add(i, entry)
+	}
+
+	// The body is only kept if something needs all of it
+	if needsBuffer {
+		writers = append(writers, &buf)
+	}
+
+	limit := bodyLimit(url)
+	read, err := io.Copy(io.MultiWriter(writers...), io.LimitReader(body, limit))
+	scan.size = read
+
+	for i, matcher := range matchers {
+		if matcher == nil {
+			continue
+		}
+
+		matched := matcher.matched(buf.Bytes())
+		if i == len(url.Assertions) {
+			scan.regexMatch = &matched
+		} else {
+			scan.text[i].Matched = matched
+		}
+	}
+	if len(url.JSONAssertions) > 0 {
+		scan.json = buf.Bytes()
+	}
+
+	if err != nil {
+		return scan, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if read < limit {
+		return scan, nil
+	}
+
+	// The body is as long as the limit, anything after it means it was cut off
+	sizeLimit := int64(1)
+	if assertion.NeedsSize(url.Assertions) {
+		// Body size bounds need the rest of the body, which is counted but not kept
+		sizeLimit = assertion.MaxBodySizeBytes + 1 - read
+	}
+
+	rest, err := io.Copy(io.Discard, io.LimitReader(body, sizeLimit))
+	scan.size += rest
+	scan.truncated = rest > 0
+	if err != nil {
+		return scan, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return scan, nil
+}
+
+// bodyMatcher matches a regex against the body, while it streams through if stream is set, else against the buffered body
+type bodyMatcher struct {
+	regex  *regexp.Regexp
+	stream *streamMatcher
+}
+
+// matched reports whether the regex matched the body, buffered is the body if the matcher does not stream
+func (m *bodyMatcher) matched(buffered []byte) bool {
+	if m.stream != nil {
+		return m.stream.close()
+	}
+
+	return m.regex.Match(buffered)
+}
+
+// streamMatcher matches a regex whose matches span at most window bytes against a body written to it in chunks.
+// It keeps the last bytes a match could still start in, so matches spanning chunks are found without keeping the body
+type streamMatcher struct {
+	regex *regexp.Regexp
+	// keep is the number of bytes carried over to the next chunk, with room to start them at a rune
+	keep    int
+	tail    []byte
+	found   bool
+	written bool
+}
+
+func newStreamMatcher(regex *regexp.Regexp, window int) *streamMatcher {
+	return &streamMatcher{regex: regex, keep: window - 1 + utf8.UTFMax}
+}
+
+func (m *streamMatcher) Write(p []byte) (int, error) {
+	if m.found {
+		return len(p), nil
+	}
+	m.written = true
+
+	data := append(m.tail, p...)
+
+	// A rune cut off at the end of the chunk is matched with the next one, so it is not seen as an invalid rune
+	end := len(data) - partialRune(data)
+	if m.regex.Match(data[:end]) {
+		m.found = true
+		m.tail = nil
+
+		return len(p), nil
+	}
+
+	// The carried over bytes start at a rune, like every match in the body does
+	start := max(0, end-m.keep)
+	for start < end && !utf8.RuneStart(data[start]) {
+		start++
+	}
+	m.tail = append(m.tail[:0], data[start:]...)
+
+	return len(p), nil
+}
+
+// close matches the bytes left at the end of the body and reports whether the regex matched
+func (m *streamMatcher) close() bool {
+	if !m.found && (len(m.tail) > 0 || !m.written) {
+		m.found = m.regex.Match(m.tail)
+	}
+
+	return m.found
+}
+
+// partialRune returns the number of bytes at the end of data that start a rune without completing it
+func partialRune(data []byte) int {
+	for n := 1; n < utf8.UTFMax && n <= len(data); n++ {
+		c := data[len(data)-n]
+		if !utf8.RuneStart(c) {
+			continue
+		}
+
+		if utf8.FullRune(data[len(data)-n:]) {
+			return 0
+		}
+
+		return n
+	}
+
+	return 0
+}
+
+// maxMatchWindow is the longest match a regex may span to be matched while the body streams, regexes with longer
+// matches are matched against the buffered body
+const maxMatchWindow = 64 * 1024
+
+// matchWindow returns the most bytes a match of the pattern can span, or -1 if it cannot be matched in windows of
+// the body: matches of unbounded or very long repetitions need all of the body, and anchors and word boundaries
+// depend on bytes before or after the window
+func matchWindow(pattern string) int {
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return -1
+	}
+
+	window, ok := maxMatchLen(parsed.Simplify())
+	if !ok {
+		return -1
+	}
+
+	return window
+}
+
+// maxMatchLen returns the most bytes a match of the regex can span, false if that is unbounded or above maxMatchWindow
+func maxMatchLen(re *syntax.Regexp) (int, bool) {
+	switch re.Op {
+	case syntax.OpNoMatch, syntax.OpEmptyMatch:
+		return 0, true
+	case syntax.OpLiteral:
+		// Case folded runes may match longer encodings, like the Kelvin sign for k
+		if re.Flags&syntax.FoldCase != 0 {
+			return boundMatchLen(len(re.Rune) * utf8.UTFMax)
+		}
+
+		n := 0
+		for _, r := range re.Rune {
+			n += utf8.RuneLen(r)
+		}
+
+		return boundMatchLen(n)
+	case syntax.OpCharClass, syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		return utf8.UTFMax, true
+	case syntax.OpCapture, syntax.OpQuest:
+		return maxMatchLen(re.Sub[0])
+	case syntax.OpRepeat:
+		n, ok := maxMatchLen(re.Sub[0])
+		if !ok || re.Max < 0 || (n > 0 && re.Max > maxMatchWindow/n) {
+			return 0, false
+		}
+
+		return n * re.Max, true
+	case syntax.OpConcat, syntax.OpAlternate:
+		total := 0
+		for _, sub := range re.Sub {
+			n, ok := maxMatchLen(sub)
+			if !ok {
+				return 0, false
+			}
+
+			if re.Op == syntax.OpConcat {
+				total += n
+			} else {
+				total = max(total, n)
+			}
+		}
+
+		return boundMatchLen(total)
+	default:
+		// Star, plus, anchors and word boundaries
+		return 0, false
+	}
+}
+
+// boundMatchLen accepts match lengths up to maxMatchWindow
+func boundMatchLen(n int) (int, bool) {
+	return n, n <= maxMatchWindow
+}
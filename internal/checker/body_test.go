@@ -0,0 +1,162 @@
+package checker
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"website-monitor/internal/models"
+)
+
+// chunkedReader returns the body in reads of at most size bytes, like a slow response
+type chunkedReader struct {
+	body string
+	size int
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	if r.body == "" {
+		return 0, io.EOF
+	}
+
+	n := copy(p[:min(len(p), r.size)], r.body)
+	r.body = r.body[n:]
+
+	return n, nil
+}
+
+func TestScanBody_MatchesAcrossChunks(t *testing.T) {
+	body := "START" + strings.Repeat("x", 20*1024) + "END"
+	url := models.MonitoredUrl{
+		RegexPattern: `(?s)START.*END`,
+		Assertions:   []models.ContentAssertion{{Type: "regex", Value: `(?s)START.*END`}},
+	}
+
+	scan, err := scanBody(&chunkedReader{body: body, size: 4 * 1024}, url, NewPatterns())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if scan.regexMatch == nil || !*scan.regexMatch {
+		t.Errorf("Expected regex to match across chunks, got %v", scan.regexMatch)
+	}
+	if !scan.text[0].Matched {
+		t.Error("Expected regex assertion to match across chunks")
+	}
+}
+
+func TestScanBody_EndAnchorMatchesOnlyBodyEnd(t *testing.T) {
+	body := strings.Repeat("x", 4*1024) + strings.Repeat("y", 4*1024)
+	url := models.MonitoredUrl{RegexPattern: `x$`}
+
+	scan, err := scanBody(&chunkedReader{body: body, size: 4 * 1024}, url, NewPatterns())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if scan.regexMatch == nil || *scan.regexMatch {
+		t.Errorf("Expected $ not to match at the end of a chunk, got %v", scan.regexMatch)
+	}
+}
+
+func TestScanBody(t *testing.T) {
+	body := strings.Repeat("x", 100) + "Maintenance"
+	url := models.MonitoredUrl{
+		RegexPattern: `Maint\w+`,
+		MaxBodyBytes: 50,
+		Assertions: []models.ContentAssertion{
+			{Type: "contains", Value: "xxx"},
+			{Type: "not_contains", Value: "Maintenance"},
+			{Type: "regex", Value: "("},
+			{Type: "header_equals", Header: "X-Version", Value: "1"},
+		},
+	}
+
+	scan, err := scanBody(strings.NewReader(body), url, NewPatterns())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !scan.truncated || scan.size != 51 {
+		t.Errorf("Expected truncated body of 51 read bytes, got truncated %v and size %d", scan.truncated, scan.size)
+	}
+	if scan.regexMatch == nil || *scan.regexMatch {
+		t.Errorf("Expected no regex match within the limit, got %v", scan.regexMatch)
+	}
+	if !scan.text[0].Matched || scan.text[1].Matched || scan.text[2].Err == nil {
+		t.Errorf("Unexpected text matches %+v", scan.text)
+	}
+	if scan.json != nil {
+		t.Error("Expected body not to be buffered without JSON assertions")
+	}
+}
+
+func TestScanBody_StreamsBoundedMatchesAcrossChunks(t *testing.T) {
+	// The chunks end in the middle of "Maintenance" and of the three bytes of "€"
+	body := strings.Repeat("x", 4*1024-5) + "Maintenance" + strings.Repeat("x", 4*1024-8) + "€" + strings.Repeat("x", 10)
+	url := models.MonitoredUrl{
+		RegexPattern: `x€x`,
+		Assertions: []models.ContentAssertion{
+			{Type: "contains", Value: "Maintenance"},
+			{Type: "not_contains", Value: "Error"},
+			{Type: "regex", Value: `[^xa-zA-Z]{2}`},
+			{Type: "regex", Value: `(?i)MAINT[a-z]{7}`},
+		},
+	}
+
+	scan, err := scanBody(&chunkedReader{body: body, size: 4 * 1024}, url, NewPatterns())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if scan.regexMatch == nil || !*scan.regexMatch {
+		t.Errorf("Expected regex to match a rune cut by the chunks, got %v", scan.regexMatch)
+	}
+	if !scan.text[0].Matched || scan.text[1].Matched || !scan.text[3].Matched {
+		t.Errorf("Expected text to match across chunks, got %+v", scan.text)
+	}
+	if scan.text[2].Matched {
+		t.Error("Expected a rune cut by the chunks not to match as two invalid runes")
+	}
+}
+
+func TestScanBody_EmptyBody(t *testing.T) {
+	url := models.MonitoredUrl{
+		RegexPattern: `x?`,
+		Assertions:   []models.ContentAssertion{{Type: "not_contains", Value: "Error"}},
+	}
+
+	scan, err := scanBody(strings.NewReader(""), url, NewPatterns())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if scan.regexMatch == nil || !*scan.regexMatch || scan.text[0].Matched {
+		t.Errorf("Expected an empty body to match like a buffered one, got %v and %+v", scan.regexMatch, scan.text)
+	}
+}
+
+func TestMatchWindow(t *testing.T) {
+	tests := []struct {
+		pattern string
+		window  int
+	}{
+		{`Maintenance`, 11},
+		{`€`, 3},
+		{`(?i)k`, 4},
+		{`a{1,3}|bcd`, 3},
+		{`status: (ok|degraded)`, 16},
+		{`Maint\w+`, -1},
+		{`(?s)START.*END`, -1},
+		{`x$`, -1},
+		{`^x`, -1},
+		{`\bx`, -1},
+		{`(.{1000}){1000}`, -1},
+	}
+
+	for _, tt := range tests {
+		if window := matchWindow(tt.pattern); window != tt.window {
+			t.Errorf("Expected window %d for %s, got %d", tt.window, tt.pattern, window)
+		}
+	}
+}
@@ -1,53 +1,145 @@
 package checker
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"regexp"
+	"strings"
 	"time"
 
+	"website-monitor/internal/assertion"
 	"website-monitor/internal/db"
 	"website-monitor/internal/models"
 )
 
 // IChecker defines the interface for performing HTTP checks
 type IChecker interface {
-	Check(url models.MonitoredUrl) models.CheckResult
-	InsertCheckResult(result models.CheckResult) error
+	// Prepare compiles the patterns of the urls before they are checked and returns the invalid urls by id
+	Prepare(urls []models.MonitoredUrl) map[int]error
+	Check(ctx context.Context, url models.MonitoredUrl) models.CheckResult
+	InsertCheckResult(ctx context.Context, result models.CheckResult) (int, error)
 }
 
 type Checker struct {
-	client *http.Client
-	db     *db.DB
+	client   *http.Client
+	db       *db.DB
+	cfg      models.CheckerConfig
+	patterns *Patterns
+	tokens   *tokenCache
 }
 
 // New creates a new checker with a configured client
-func New(database *db.DB) *Checker {
+func New(database *db.DB, cfg models.CheckerConfig) *Checker {
 	return &Checker{
+		// Timeouts differ per url, so they are applied to the context of each attempt instead of the client
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			// Every check opens a new connection, so DNS, connect and TLS timings are measured each time
+			Transport: &http.Transport{
+				Proxy:             http.ProxyFromEnvironment,
+				DisableKeepAlives: true,
+			},
 		},
-		db: database,
+		db:       database,
+		cfg:      cfg,
+		patterns: NewPatterns(),
+		tokens:   newTokenCache(),
 	}
 }
 
-// Check performs an HTTP check on the given url and returns the result
-func (c *Checker) Check(url models.MonitoredUrl) models.CheckResult {
+// Prepare compiles the patterns of the urls, so checks reuse them, and returns the urls with invalid patterns by id
+func (c *Checker) Prepare(urls []models.MonitoredUrl) map[int]error {
+	return c.patterns.Prepare(urls)
+}
+
+// Check performs an HTTP check on the given url and returns the result.
+// Failed attempts are retried as configured for the url, the result describes the last attempt.
+// Cancelling the context aborts the request, which is then reported as an error in the result.
+func (c *Checker) Check(ctx context.Context, url models.MonitoredUrl) models.CheckResult {
 	result := models.CheckResult{
 		URL:            url.Url,
 		CheckTimestamp: time.Now(),
+		Attempts:       1,
+	}
+
+	expectedStatus, err := ParseStatusCodes(url.ExpectedStatusCodes)
+	if err != nil {
+		result.Error = fmt.Sprintf("invalid expected status codes: %s", err.Error())
+
+		return result
+	}
+
+	retry, err := ParseRetryOn(url.RetryOn)
+	if err != nil {
+		result.Error = fmt.Sprintf("invalid retry conditions: %s", err.Error())
+
+		return result
+	}
+
+	var attemptErrors []string
+	for attempt := 1; ; attempt++ {
+		attemptResult, retryable := c.attempt(ctx, url, result.CheckTimestamp, expectedStatus, retry)
+		attemptResult.Attempts = attempt
+
+		if attemptResult.Error != "" {
+			attemptErrors = append(attemptErrors, attemptResult.Error)
+		}
+
+		if attemptResult.Error == "" || !retryable || attempt >= url.RetryAttempts ||
+			!waitForRetry(ctx, time.Duration(url.RetryDelayMs)*time.Millisecond) {
+			// Errors are only listed per attempt if the check was retried, otherwise Error says it all
+			if attempt > 1 {
+				attemptResult.AttemptErrors = attemptErrors
+			}
+
+			return attemptResult
+		}
 	}
+}
+
+// attempt sends a single check request and evaluates the response. All attempts of a check share its timestamp,
+// which the certificate expiry is measured from. It also reports whether a failure is retryable under the retry policy
+func (c *Checker) attempt(ctx context.Context, url models.MonitoredUrl, checkTimestamp time.Time, expectedStatus StatusCodes, retry RetryPolicy) (models.CheckResult, bool) {
+	result := models.CheckResult{URL: url.Url, CheckTimestamp: checkTimestamp}
+
+	// The timeout covers the whole attempt, reading the body included
+	timeout := Timeout(url)
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := newRequest(attemptCtx, url)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to create request: %s", err.Error())
+
+		return result, false
+	}
+
+	if err := c.authenticate(attemptCtx, req, url.Auth); err != nil {
+		result.Error = fmt.Sprintf("failed to authenticate: %s", err.Error())
+
+		return result, false
+	}
+
+	var timer requestTimer
+	req = req.WithContext(timer.trace(req.Context()))
 
 	start := time.Now()
-	resp, err := c.client.Get(url.Url)
+	redirects := newRedirectRecorder(url)
+	resp, err := redirects.client(c.client).Do(req)
 	responseTime := int(time.Since(start).Milliseconds())
 	result.ResponseTimeMs = &responseTime
 
 	if err != nil {
 		result.Error = err.Error()
+		if timedOut(ctx, attemptCtx) {
+			result.Error = timeoutError(timeout)
+		}
+		result.Redirects = redirects.hops
+		timer.record(&result)
 
-		return result
+		// A cancelled check is not retried, the caller is no longer waiting for it
+		return result, ctx.Err() == nil && !redirects.violated && retry.retryableError(err)
 	}
 
 	defer func(Body io.ReadCloser) {
@@ -55,53 +147,203 @@ func (c *Checker) Check(url models.MonitoredUrl) models.CheckResult {
 	}(resp.Body)
 
 	result.HttpStatus = &resp.StatusCode
+	result.Redirects = redirects.hops
+
+	// A rejected token may have been revoked before it expired, the next attempt requests a new one
+	if resp.StatusCode == http.StatusUnauthorized && url.Auth != nil && url.Auth.Type == AuthOAuth2 {
+		c.tokens.invalidate(*url.Auth)
+	}
+	c.recordCertificates(&result, resp.TLS)
+
+	retryable := false
+	if !expectedStatus.Contains(resp.StatusCode) {
+		result.Error = fmt.Sprintf("unexpected status code %d, expected %s", resp.StatusCode, url.ExpectedStatusCodes)
+		retryable = retry.retryableStatus(resp.StatusCode)
+	}
+
+	scan, readErr := scanBody(resp.Body, url, c.patterns)
+	result.BodyTruncated = scan.truncated
+	timer.bodyRead()
+	timer.record(&result)
+
+	// A body that is still arriving when the timeout expires fails the check like a late response
+	if readErr != nil && result.Error == "" && timedOut(ctx, attemptCtx) {
+		result.Error = timeoutError(timeout)
+		retryable = retry.timeout
+	}
 
 	// Check a regexp pattern if provided
 	if url.RegexPattern != "" {
-		regexMatch, err := c.checkRegexPattern(resp, url.RegexPattern)
-		if err != nil {
-			result.Error = fmt.Sprintf("regex check failed: %s", err.Error())
+		switch {
+		case scan.regexErr != nil:
+			if result.Error == "" {
+				result.Error = fmt.Sprintf("regex check failed: %s", scan.regexErr.Error())
+			}
+		case readErr != nil:
+			if result.Error == "" {
+				result.Error = fmt.Sprintf("regex check failed: %s", readErr.Error())
+			}
+		default:
+			result.RegexMatch = scan.regexMatch
+		}
+	}
+
+	if len(url.JSONAssertions) > 0 {
+		switch {
+		case readErr != nil:
+			if result.Error == "" {
+				result.Error = fmt.Sprintf("json assertions failed: %s", readErr.Error())
+			}
+		case scan.truncated:
+			// A cut off document cannot be parsed, so this is reported instead of a parse error
+			if result.Error == "" {
+				result.Error = fmt.Sprintf("json assertions failed: response body is larger than %d bytes", bodyLimit(url))
+			}
+		default:
+			result.AssertionResults = assertion.EvaluateJSON(scan.json, url.JSONAssertions)
+			if failure := assertion.FirstFailure(result.AssertionResults); failure != "" && result.Error == "" {
+				result.Error = fmt.Sprintf("json assertion failed: %s", failure)
+			}
+		}
+	}
+
+	if len(url.Assertions) > 0 {
+		if readErr != nil {
+			if result.Error == "" {
+				result.Error = fmt.Sprintf("content assertions failed: %s", readErr.Error())
+			}
 		} else {
-			result.RegexMatch = &regexMatch
+			response := assertion.Response{Header: resp.Header, Text: scan.text, Size: scan.size, Compile: c.patterns.Compile}
+			contentResults := assertion.EvaluateContent(response, url.Assertions)
+			result.AssertionResults = append(result.AssertionResults, contentResults...)
+			if failure := assertion.FirstFailure(contentResults); failure != "" && result.Error == "" {
+				result.Error = fmt.Sprintf("content assertion failed: %s", failure)
+			}
 		}
 	}
 
-	return result
+	if result.Error == "" {
+		if warning := slowWarning(url, result.ResponseTimeMs); warning != "" {
+			if result.Warning != "" {
+				warning = result.Warning + "; " + warning
+			}
+			result.Warning = warning
+		}
+	}
+
+	return result, retryable
 }
 
-// checkRegexPattern checks if the response body matches the given regex pattern
-func (c *Checker) checkRegexPattern(resp *http.Response, pattern string) (bool, error) {
-	regex, err := regexp.Compile(pattern)
-	if err != nil {
-		return false, fmt.Errorf("invalid regex pattern: %w", err)
+// newRequest builds the check request from the method, headers and body configured for the url
+func newRequest(ctx context.Context, url models.MonitoredUrl) (*http.Request, error) {
+	method := strings.ToUpper(url.Method)
+	if method == "" {
+		method = http.MethodGet
 	}
 
-	// Read response body (limit to 64KB for performance)
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var body io.Reader
+	if url.Body != "" {
+		body = strings.NewReader(url.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url.Url, body)
 	if err != nil {
-		return false, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
-	return regex.Match(body), nil
+	for name, value := range url.Headers {
+		// net/http ignores the Host entry of the header map, it has to be set on the request itself
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+
+			continue
+		}
+
+		req.Header.Set(name, value)
+	}
+
+	return req, nil
 }
 
-// InsertCheckResult inserts a check result into the database
-func (c *Checker) InsertCheckResult(result models.CheckResult) error {
+// InsertCheckResult inserts a check result into the database and returns the id of the stored check
+func (c *Checker) InsertCheckResult(ctx context.Context, result models.CheckResult) (int, error) {
 	query := `
-		INSERT INTO checks (url, check_timestamp, response_time_ms, http_status, regex_match, error)
-		VALUES ($1, $2, $3, $4, $5, $6)`
+		INSERT INTO checks (url, check_timestamp, response_time_ms, http_status, regex_match, error,
+			tls_certificates, tls_expires_at, tls_days_remaining, warning,
+			dns_ms, connect_ms, tls_handshake_ms, ttfb_ms, transfer_ms, assertion_results, body_truncated,
+			attempts, attempt_errors, redirects, skipped, location)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, NULLIF($22, ''))
+		RETURNING id`
+
+	// Plain HTTP checks store NULL instead of an empty chain
+	certificates, err := encodeOptional(result.Certificates, len(result.Certificates))
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode certificates: %w", err)
+	}
+
+	assertionResults, err := encodeOptional(result.AssertionResults, len(result.AssertionResults))
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode assertion results: %w", err)
+	}
+
+	attemptErrors, err := encodeOptional(result.AttemptErrors, len(result.AttemptErrors))
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode attempt errors: %w", err)
+	}
+
+	redirects, err := encodeOptional(result.Redirects, len(result.Redirects))
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode redirects: %w", err)
+	}
 
-	err := c.db.Exec(query,
+	// Results built by hand, e.g. in tests, count as a single attempt
+	attempts := result.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var id int
+	err = c.db.QueryRowContext(ctx, query,
 		result.URL,
 		result.CheckTimestamp,
 		result.ResponseTimeMs,
 		result.HttpStatus,
 		result.RegexMatch,
-		result.Error)
+		result.Error,
+		certificates,
+		result.CertExpiresAt,
+		result.CertDaysRemaining,
+		result.Warning,
+		result.DnsMs,
+		result.ConnectMs,
+		result.TlsHandshakeMs,
+		result.TimeToFirstByteMs,
+		result.TransferMs,
+		assertionResults,
+		result.BodyTruncated,
+		attempts,
+		attemptErrors,
+		redirects,
+		result.Skipped,
+		result.Location).Scan(&id)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert check result: %w", err)
+	}
+
+	return id, nil
+}
+
+// encodeOptional encodes a value for a nullable JSONB column, storing NULL if it has no elements
+func encodeOptional(value interface{}, length int) (interface{}, error) {
+	if length == 0 {
+		return nil, nil
+	}
 
+	encoded, err := json.Marshal(value)
 	if err != nil {
-		return fmt.Errorf("failed to insert check result: %w", err)
+		return nil, err
 	}
 
-	return nil
+	return string(encoded), nil
 }
@@ -1,6 +1,8 @@
 package checker
 
 import (
+	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -27,7 +29,7 @@ func TestChecker_Check_Success(t *testing.T) {
 		CheckIntervalSec: 60,
 	}
 
-	result := checker.Check(url)
+	result := checker.Check(context.Background(), url)
 
 	if result.URL != url.Url {
 		t.Errorf("Expected URL %s, got %s", url.Url, result.URL)
@@ -68,7 +70,7 @@ func TestChecker_Check_WithRegexMatch(t *testing.T) {
 		RegexPattern:     "Hi, my name is",
 	}
 
-	result := checker.Check(url)
+	result := checker.Check(context.Background(), url)
 
 	if result.Error != "" {
 		t.Errorf("Expected no error, got: %s", result.Error)
@@ -97,7 +99,7 @@ func TestChecker_Check_WithRegexNoMatch(t *testing.T) {
 		RegexPattern:     "Princess",
 	}
 
-	result := checker.Check(url)
+	result := checker.Check(context.Background(), url)
 
 	if result.Error != "" {
 		t.Errorf("Expected no error, got: %s", result.Error)
@@ -126,7 +128,7 @@ func TestChecker_Check_InvalidRegex(t *testing.T) {
 		RegexPattern:     "[invalid",
 	}
 
-	result := checker.Check(url)
+	result := checker.Check(context.Background(), url)
 
 	if result.Error == "" {
 		t.Error("Expected error")
@@ -148,7 +150,7 @@ func TestChecker_Check_HttpError(t *testing.T) {
 		CheckIntervalSec: 60,
 	}
 
-	result := checker.Check(url)
+	result := checker.Check(context.Background(), url)
 
 	if result.Error == "" {
 		t.Error("Expected error for invalid URL")
@@ -180,7 +182,7 @@ func TestChecker_Check_404Status(t *testing.T) {
 		CheckIntervalSec: 60,
 	}
 
-	result := checker.Check(url)
+	result := checker.Check(context.Background(), url)
 
 	if result.Error != "" {
 		t.Errorf("Expected no error, got: %s", result.Error)
@@ -190,3 +192,260 @@ func TestChecker_Check_404Status(t *testing.T) {
 		t.Errorf("Expected HTTP status 404, got %v", result.HttpStatus)
 	}
 }
+
+func TestChecker_Check_ContextCancelled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	url := models.MonitoredUrl{
+		ID:               1,
+		Url:              server.URL,
+		CheckIntervalSec: 60,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result := checker.Check(ctx, url)
+
+	if time.Since(start) > 5*time.Second {
+		t.Error("Expected check to return as soon as the context is cancelled")
+	}
+
+	if !strings.Contains(result.Error, "context deadline exceeded") {
+		t.Errorf("Expected context error, got: %s", result.Error)
+	}
+
+	if result.HttpStatus != nil {
+		t.Error("Expected no HTTP status for cancelled request")
+	}
+}
+
+func TestChecker_Check_RequestConfig(t *testing.T) {
+	var gotMethod, gotHeader, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotMethod = r.Method
+		gotHeader = r.Header.Get("X-Api-Key")
+		gotBody = string(body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	url := models.MonitoredUrl{
+		ID:                  1,
+		Url:                 server.URL,
+		CheckIntervalSec:    60,
+		Method:              "post",
+		Headers:             map[string]string{"X-Api-Key": "secret"},
+		Body:                `{"ping":true}`,
+		ExpectedStatusCodes: "201",
+	}
+
+	result := checker.Check(context.Background(), url)
+
+	if result.Error != "" {
+		t.Errorf("Expected no error, got: %s", result.Error)
+	}
+
+	if gotMethod != http.MethodPost {
+		t.Errorf("Expected method POST, got %s", gotMethod)
+	}
+
+	if gotHeader != "secret" {
+		t.Errorf("Expected X-Api-Key header to be sent, got %q", gotHeader)
+	}
+
+	if gotBody != url.Body {
+		t.Errorf("Expected body %s, got %s", url.Body, gotBody)
+	}
+}
+
+func TestChecker_Check_UnexpectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	url := models.MonitoredUrl{
+		ID:                  1,
+		Url:                 server.URL,
+		CheckIntervalSec:    60,
+		ExpectedStatusCodes: "200-299",
+	}
+
+	result := checker.Check(context.Background(), url)
+
+	if !strings.Contains(result.Error, "unexpected status code 503") {
+		t.Errorf("Expected status mismatch error, got: %s", result.Error)
+	}
+
+	if result.HttpStatus == nil || *result.HttpStatus != 503 {
+		t.Errorf("Expected HTTP status 503 to be recorded, got %v", result.HttpStatus)
+	}
+}
+
+func TestChecker_Check_JSONAssertions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status": "degraded", "checks": [{"name": "db"}]}`))
+	}))
+	defer server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	url := models.MonitoredUrl{
+		ID:               1,
+		Url:              server.URL,
+		CheckIntervalSec: 60,
+		JSONAssertions: []models.JSONAssertion{
+			{Path: "$.checks.length()", Op: "gte", Value: 1},
+			{Path: "$.status", Op: "equals", Value: "ok"},
+		},
+	}
+
+	result := checker.Check(context.Background(), url)
+
+	if len(result.AssertionResults) != 2 {
+		t.Fatalf("Expected 2 assertion results, got %+v", result.AssertionResults)
+	}
+
+	if !result.AssertionResults[0].Passed || result.AssertionResults[1].Passed {
+		t.Errorf("Expected first assertion to pass and second to fail, got %+v", result.AssertionResults)
+	}
+
+	if result.Error != `json assertion failed: $.status equals "ok", got "degraded"` {
+		t.Errorf("Expected assertion failure as error, got: %s", result.Error)
+	}
+
+	if !result.Failed() {
+		t.Error("Expected check to fail")
+	}
+}
+
+func TestChecker_Check_ContentAssertions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("Welcome! " + strings.Repeat("x", 100*1024) + " Maintenance"))
+	}))
+	defer server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	minBytes := 100 * 1024
+	url := models.MonitoredUrl{
+		ID:               1,
+		Url:              server.URL,
+		CheckIntervalSec: 60,
+		Assertions: []models.ContentAssertion{
+			{Type: "contains", Value: "Welcome"},
+			{Type: "header_equals", Header: "Content-Type", Value: "text/html"},
+			{Type: "body_size", MinBytes: &minBytes},
+			{Type: "not_contains", Value: "Database error"},
+			{Type: "header_matches", Header: "Content-Type", Value: "json"},
+		},
+	}
+
+	result := checker.Check(context.Background(), url)
+
+	if len(result.AssertionResults) != 5 {
+		t.Fatalf("Expected 5 assertion results, got %+v", result.AssertionResults)
+	}
+
+	for i, passed := range []bool{true, true, true, true, false} {
+		if result.AssertionResults[i].Passed != passed {
+			t.Errorf("Expected assertion %d to have passed %v, got %+v", i, passed, result.AssertionResults[i])
+		}
+	}
+
+	if result.AssertionResults[2].Actual != int64(100*1024+21) {
+		t.Errorf("Expected the full body size to be measured, got %v", result.AssertionResults[2].Actual)
+	}
+
+	if result.Error != `content assertion failed: header_matches Content-Type "json", got "text/html"` {
+		t.Errorf("Expected failed assertion as error, got: %s", result.Error)
+	}
+}
+
+func TestChecker_Check_MaxBodyBytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 200*1024) + "build: ok"))
+	}))
+	defer server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	url := models.MonitoredUrl{
+		ID:               1,
+		Url:              server.URL,
+		CheckIntervalSec: 60,
+		RegexPattern:     `build: \w+`,
+	}
+
+	result := checker.Check(context.Background(), url)
+
+	if result.RegexMatch == nil || *result.RegexMatch {
+		t.Errorf("Expected no match within the default limit, got %v", result.RegexMatch)
+	}
+	if !result.BodyTruncated {
+		t.Error("Expected body to be reported as truncated")
+	}
+
+	url.MaxBodyBytes = 1024 * 1024
+	result = checker.Check(context.Background(), url)
+
+	if result.RegexMatch == nil || !*result.RegexMatch {
+		t.Errorf("Expected regex to match with a larger limit, got %v", result.RegexMatch)
+	}
+	if result.BodyTruncated {
+		t.Error("Expected body not to be truncated")
+	}
+}
+
+func TestChecker_Check_JSONAssertionsTruncatedBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status": "` + strings.Repeat("x", 2048) + `"}`))
+	}))
+	defer server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	url := models.MonitoredUrl{
+		ID:               1,
+		Url:              server.URL,
+		CheckIntervalSec: 60,
+		MaxBodyBytes:     1024,
+		JSONAssertions:   []models.JSONAssertion{{Path: "$.status", Op: "exists"}},
+	}
+
+	result := checker.Check(context.Background(), url)
+
+	if result.Error != "json assertions failed: response body is larger than 1024 bytes" {
+		t.Errorf("Expected truncation error, got: %s", result.Error)
+	}
+}
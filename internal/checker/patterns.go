@@ -0,0 +1,131 @@
+package checker
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"website-monitor/internal/assertion"
+	"website-monitor/internal/models"
+)
+
+// Patterns caches compiled regexes, so patterns shared by urls and checks are only compiled once.
+// A nil Patterns compiles every pattern on use
+type Patterns struct {
+	mu       sync.RWMutex
+	compiled map[string]compiledPattern
+}
+
+// compiledPattern is a cache entry, invalid patterns are cached with their error
+type compiledPattern struct {
+	regex *regexp.Regexp
+	err   error
+	// window is the most bytes a match can span, negative if the regex cannot be matched while streaming the body
+	window int
+}
+
+// NewPatterns creates an empty pattern cache
+func NewPatterns() *Patterns {
+	return &Patterns{compiled: make(map[string]compiledPattern)}
+}
+
+// Compile returns the compiled regex of the pattern, compiling it on first use
+func (p *Patterns) Compile(pattern string) (*regexp.Regexp, error) {
+	entry := p.compile(pattern)
+
+	return entry.regex, entry.err
+}
+
+// compile returns the cache entry of the pattern, compiling it on first use
+func (p *Patterns) compile(pattern string) compiledPattern {
+	if p != nil {
+		p.mu.RLock()
+		entry, ok := p.compiled[pattern]
+		p.mu.RUnlock()
+		if ok {
+			return entry
+		}
+	}
+
+	entry := compiledPattern{window: -1}
+	entry.regex, entry.err = regexp.Compile(pattern)
+	if entry.err == nil {
+		entry.window = matchWindow(pattern)
+	}
+
+	if p != nil {
+		p.mu.Lock()
+		p.compiled[pattern] = entry
+		p.mu.Unlock()
+	}
+
+	return entry
+}
+
+// Prepare compiles the patterns of every url and drops cached patterns no url uses anymore.
+// It returns the urls with invalid patterns by id, those urls cannot be checked
+func (p *Patterns) Prepare(urls []models.MonitoredUrl) map[int]error {
+	errs := make(map[int]error)
+	used := make(map[string]bool)
+
+	for _, url := range urls {
+		var invalid []string
+		for _, pattern := range urlPatterns(url) {
+			used[pattern.pattern] = true
+			if _, err := p.Compile(pattern.pattern); err != nil {
+				invalid = append(invalid, fmt.Sprintf("%s is invalid: %s", pattern.field, err.Error()))
+			}
+		}
+
+		if len(invalid) > 0 {
+			errs[url.ID] = fmt.Errorf("invalid regex: %s", strings.Join(invalid, "; "))
+		}
+	}
+
+	if p != nil {
+		p.mu.Lock()
+		for pattern := range p.compiled {
+			if !used[pattern] {
+				delete(p.compiled, pattern)
+			}
+		}
+		p.mu.Unlock()
+	}
+
+	return errs
+}
+
+// urlPattern is a regex of a url together with the field it is configured in
+type urlPattern struct {
+	field   string
+	pattern string
+}
+
+// urlPatterns lists the regexes the checks of the url match against, text assertions are matched as regexes too
+func urlPatterns(url models.MonitoredUrl) []urlPattern {
+	var patterns []urlPattern
+	if url.RegexPattern != "" {
+		patterns = append(patterns, urlPattern{field: "regex_pattern", pattern: url.RegexPattern})
+	}
+
+	for i, a := range url.Assertions {
+		if pattern, ok := textPattern(a); ok {
+			patterns = append(patterns, urlPattern{field: fmt.Sprintf("assertions[%d]", i), pattern: pattern})
+		}
+	}
+
+	return patterns
+}
+
+// textPattern returns the regex an assertion is matched with, contains and not_contains match their value literally
+func textPattern(a models.ContentAssertion) (string, bool) {
+	switch a.Type {
+	case assertion.TypeContains, assertion.TypeNotContains:
+		return regexp.QuoteMeta(a.Value), true
+	case assertion.TypeRegex, assertion.TypeHeaderMatches:
+		return a.Value, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,56 @@
+package checker
+
+import (
+	"strings"
+	"testing"
+
+	"website-monitor/internal/models"
+)
+
+func TestPatterns_CompileCaches(t *testing.T) {
+	patterns := NewPatterns()
+
+	first, err := patterns.Compile(`ok|healthy`)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	second, _ := patterns.Compile(`ok|healthy`)
+	if first != second {
+		t.Error("Expected the compiled regex to be shared")
+	}
+
+	if _, err := patterns.Compile(`[invalid`); err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+}
+
+func TestPatterns_Prepare(t *testing.T) {
+	patterns := NewPatterns()
+	patterns.Compile(`unused`)
+
+	errs := patterns.Prepare([]models.MonitoredUrl{
+		{ID: 1, RegexPattern: `ok|healthy`, Assertions: []models.ContentAssertion{{Type: "contains", Value: "(literal"}}},
+		{ID: 2, RegexPattern: `[invalid`, Assertions: []models.ContentAssertion{
+			{Type: "header_equals", Header: "X-Version", Value: "("},
+			{Type: "header_matches", Header: "Content-Type", Value: "(json"},
+		}},
+	})
+
+	if len(errs) != 1 || errs[2] == nil {
+		t.Fatalf("Expected only url 2 to be invalid, got %v", errs)
+	}
+
+	for _, expected := range []string{"regex_pattern is invalid", "assertions[1] is invalid"} {
+		if !strings.Contains(errs[2].Error(), expected) {
+			t.Errorf("Expected error to contain %q, got: %v", expected, errs[2])
+		}
+	}
+
+	if _, ok := patterns.compiled[`unused`]; ok {
+		t.Error("Expected patterns no url uses to be dropped")
+	}
+	if _, ok := patterns.compiled[`ok|healthy`]; !ok {
+		t.Error("Expected patterns of urls to be cached")
+	}
+}
@@ -0,0 +1,86 @@
+package checker
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"website-monitor/internal/models"
+)
+
+// Redirect policies of a url
+const (
+	// RedirectFollow follows redirects to any host, it is the default
+	RedirectFollow = "follow"
+	// RedirectNone does not follow redirects, the redirect response itself is checked
+	RedirectNone = "none"
+	// RedirectSameHost follows redirects as long as they stay on the host of the url
+	RedirectSameHost = "same_host"
+
+	// DefaultMaxRedirects is how many redirects are followed if the url sets no max_redirects
+	DefaultMaxRedirects = 10
+	// MaxRedirectsLimit is the largest max_redirects a url can set
+	MaxRedirectsLimit = 20
+)
+
+// ValidRedirectPolicy reports whether the policy is known, an empty policy means follow
+func ValidRedirectPolicy(policy string) bool {
+	switch policy {
+	case "", RedirectFollow, RedirectNone, RedirectSameHost:
+		return true
+	default:
+		return false
+	}
+}
+
+// redirectRecorder applies the redirect policy of a url to a single request and records every hop
+type redirectRecorder struct {
+	policy       string
+	maxRedirects int
+	hops         []models.Redirect
+	// violated is set if the request was stopped by the policy, such failures are not retried
+	violated bool
+}
+
+func newRedirectRecorder(url models.MonitoredUrl) *redirectRecorder {
+	maxRedirects := url.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = DefaultMaxRedirects
+	}
+
+	return &redirectRecorder{policy: url.RedirectPolicy, maxRedirects: maxRedirects}
+}
+
+// checkRedirect is used as CheckRedirect of the client. req is the next request, via the requests sent so far
+func (r *redirectRecorder) checkRedirect(req *http.Request, via []*http.Request) error {
+	previous := via[len(via)-1]
+	hop := models.Redirect{URL: previous.URL.String(), Location: req.URL.String()}
+	if req.Response != nil {
+		hop.Status = req.Response.StatusCode
+	}
+	r.hops = append(r.hops, hop)
+
+	switch {
+	case r.policy == RedirectNone:
+		// The redirect response becomes the response of the check
+		return http.ErrUseLastResponse
+	case len(via) > r.maxRedirects:
+		r.violated = true
+
+		return fmt.Errorf("stopped after %d redirects", r.maxRedirects)
+	case r.policy == RedirectSameHost && !strings.EqualFold(req.URL.Host, via[0].URL.Host):
+		r.violated = true
+
+		return fmt.Errorf("redirected to a different host %s", req.URL.Host)
+	}
+
+	return nil
+}
+
+// client returns a copy of the client applying the policy, so concurrent checks of other urls are not affected
+func (r *redirectRecorder) client(base *http.Client) *http.Client {
+	client := *base
+	client.CheckRedirect = r.checkRedirect
+
+	return &client
+}
@@ -0,0 +1,138 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"website-monitor/internal/models"
+)
+
+func newRedirectServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/next", http.StatusFound)
+	})
+	mux.HandleFunc("/next", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/final", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestChecker_Check_FollowsRedirects(t *testing.T) {
+	server := newRedirectServer()
+	defer server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	result := checker.Check(context.Background(), models.MonitoredUrl{ID: 1, Url: server.URL + "/start", CheckIntervalSec: 60})
+
+	if result.Error != "" || result.HttpStatus == nil || *result.HttpStatus != http.StatusOK {
+		t.Fatalf("Expected successful check, got %+v", result)
+	}
+
+	expected := []models.Redirect{
+		{URL: server.URL + "/start", Status: http.StatusFound, Location: server.URL + "/next"},
+		{URL: server.URL + "/next", Status: http.StatusMovedPermanently, Location: server.URL + "/final"},
+	}
+	if len(result.Redirects) != 2 || result.Redirects[0] != expected[0] || result.Redirects[1] != expected[1] {
+		t.Errorf("Expected redirect chain %+v, got %+v", expected, result.Redirects)
+	}
+}
+
+func TestChecker_Check_RedirectPolicyNone(t *testing.T) {
+	server := newRedirectServer()
+	defer server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	result := checker.Check(context.Background(), models.MonitoredUrl{
+		ID:                  1,
+		Url:                 server.URL + "/start",
+		CheckIntervalSec:    60,
+		ExpectedStatusCodes: "200",
+		RedirectPolicy:      RedirectNone,
+	})
+
+	if result.HttpStatus == nil || *result.HttpStatus != http.StatusFound {
+		t.Fatalf("Expected the redirect response to be checked, got %+v", result)
+	}
+	if result.Error != "unexpected status code 302, expected 200" {
+		t.Errorf("Expected unexpected status error, got: %s", result.Error)
+	}
+	if len(result.Redirects) != 1 || result.Redirects[0].Location != server.URL+"/next" {
+		t.Errorf("Expected the redirect to be recorded, got %+v", result.Redirects)
+	}
+}
+
+func TestChecker_Check_MaxRedirects(t *testing.T) {
+	server := newRedirectServer()
+	defer server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	result := checker.Check(context.Background(), models.MonitoredUrl{
+		ID:               1,
+		Url:              server.URL + "/start",
+		CheckIntervalSec: 60,
+		MaxRedirects:     1,
+		RetryAttempts:    3,
+	})
+
+	if !strings.Contains(result.Error, "stopped after 1 redirects") {
+		t.Errorf("Expected redirect limit error, got: %s", result.Error)
+	}
+	if result.Attempts != 1 {
+		t.Errorf("Expected policy violations not to be retried, got %d attempts", result.Attempts)
+	}
+	if len(result.Redirects) != 2 {
+		t.Errorf("Expected both hops to be recorded, got %+v", result.Redirects)
+	}
+}
+
+func TestChecker_Check_RedirectSameHost(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("login"))
+	}))
+	defer other.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/start":
+			http.Redirect(w, r, "/local", http.StatusFound)
+		case "/local":
+			http.Redirect(w, r, other.URL+"/login", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	result := checker.Check(context.Background(), models.MonitoredUrl{
+		ID:               1,
+		Url:              server.URL + "/start",
+		CheckIntervalSec: 60,
+		RedirectPolicy:   RedirectSameHost,
+	})
+
+	if !strings.Contains(result.Error, "redirected to a different host") {
+		t.Errorf("Expected different host error, got: %s", result.Error)
+	}
+	if len(result.Redirects) != 2 || result.Redirects[1].Location != other.URL+"/login" {
+		t.Errorf("Expected redirect chain up to the other host, got %+v", result.Redirects)
+	}
+}
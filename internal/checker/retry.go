@@ -0,0 +1,108 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+)
+
+// Retry conditions besides status codes
+const (
+	// RetryOnNetwork retries requests that failed before a response was received, e.g. DNS, connect or TLS errors
+	RetryOnNetwork = "network"
+	// RetryOnTimeout retries requests that timed out
+	RetryOnTimeout = "timeout"
+
+	// DefaultRetryOn is used if a url has more than one attempt but no retry conditions
+	DefaultRetryOn = RetryOnNetwork + "," + RetryOnTimeout
+
+	// MaxRetryAttempts bounds the attempts of a single check
+	MaxRetryAttempts = 5
+	// MaxRetryDelayMs bounds the delay between attempts
+	MaxRetryDelayMs = 30000
+)
+
+// RetryPolicy decides which failed attempts of a check are retried
+type RetryPolicy struct {
+	network  bool
+	timeout  bool
+	statuses StatusCodes
+}
+
+// ParseRetryOn parses a comma separated list of retry conditions, e.g. "network,timeout,502-504".
+// Entries are network, timeout or status codes and ranges. An empty spec uses DefaultRetryOn
+func ParseRetryOn(spec string) (RetryPolicy, error) {
+	if strings.TrimSpace(spec) == "" {
+		spec = DefaultRetryOn
+	}
+
+	var policy RetryPolicy
+	var codes []string
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+
+		switch strings.ToLower(part) {
+		case "":
+		case RetryOnNetwork:
+			policy.network = true
+		case RetryOnTimeout:
+			policy.timeout = true
+		default:
+			codes = append(codes, part)
+		}
+	}
+
+	statuses, err := ParseStatusCodes(strings.Join(codes, ","))
+	if err != nil {
+		return policy, err
+	}
+	policy.statuses = statuses
+
+	return policy, nil
+}
+
+// retryableError reports whether an attempt that got no response is retried
+func (p RetryPolicy) retryableError(err error) bool {
+	if isTimeout(err) {
+		return p.timeout
+	}
+
+	return p.network
+}
+
+// retryableStatus reports whether an attempt with an unexpected status is retried
+func (p RetryPolicy) retryableStatus(code int) bool {
+	// An empty list accepts any status, but here it means no status is retried
+	return len(p.statuses) > 0 && p.statuses.Contains(code)
+}
+
+// isTimeout reports whether a request error is a timeout of the client or the connection
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// waitForRetry waits for the retry delay and reports whether the check should go on
+func waitForRetry(ctx context.Context, delay time.Duration) bool {
+	if delay <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
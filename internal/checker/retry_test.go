@@ -0,0 +1,143 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"website-monitor/internal/models"
+)
+
+func TestParseRetryOn(t *testing.T) {
+	policy, err := ParseRetryOn("")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !policy.network || !policy.timeout || policy.retryableStatus(503) {
+		t.Errorf("Expected network errors and timeouts to be retried by default, got %+v", policy)
+	}
+
+	policy, err = ParseRetryOn("timeout, 502-504,429")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if policy.network || !policy.timeout || !policy.retryableStatus(503) || !policy.retryableStatus(429) || policy.retryableStatus(500) {
+		t.Errorf("Unexpected policy %+v", policy)
+	}
+
+	if _, err := ParseRetryOn("network,dns"); err == nil {
+		t.Error("Expected error for unknown condition")
+	}
+}
+
+func TestChecker_Check_RetriesRetryableStatus(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	url := models.MonitoredUrl{
+		ID:                  1,
+		Url:                 server.URL,
+		CheckIntervalSec:    60,
+		ExpectedStatusCodes: "200",
+		RetryAttempts:       3,
+		RetryOn:             "502-504",
+	}
+
+	result := checker.Check(context.Background(), url)
+
+	if result.Error != "" {
+		t.Errorf("Expected retry to succeed, got: %s", result.Error)
+	}
+	if result.Attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", result.Attempts)
+	}
+	if len(result.AttemptErrors) != 1 || result.AttemptErrors[0] != "unexpected status code 503, expected 200" {
+		t.Errorf("Unexpected attempt errors %v", result.AttemptErrors)
+	}
+}
+
+func TestChecker_Check_DoesNotRetryOtherStatus(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	url := models.MonitoredUrl{
+		ID:                  1,
+		Url:                 server.URL,
+		CheckIntervalSec:    60,
+		ExpectedStatusCodes: "200",
+		RetryAttempts:       3,
+	}
+
+	result := checker.Check(context.Background(), url)
+
+	if atomic.LoadInt32(&requests) != 1 || result.Attempts != 1 || result.AttemptErrors != nil {
+		t.Errorf("Expected a single attempt, got %d requests and result %+v", requests, result)
+	}
+}
+
+func TestChecker_Check_RetriesNetworkErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	address := server.URL
+	server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	url := models.MonitoredUrl{
+		ID:               1,
+		Url:              address,
+		CheckIntervalSec: 60,
+		RetryAttempts:    3,
+		RetryDelayMs:     1,
+	}
+
+	result := checker.Check(context.Background(), url)
+
+	if result.Error == "" {
+		t.Fatal("Expected error for a closed server")
+	}
+	if result.Attempts != 3 || len(result.AttemptErrors) != 3 {
+		t.Errorf("Expected 3 failed attempts, got %d with errors %v", result.Attempts, result.AttemptErrors)
+	}
+}
+
+func TestChecker_Check_NoRetryAfterCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	address := server.URL
+	server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result := checker.Check(ctx, models.MonitoredUrl{ID: 1, Url: address, CheckIntervalSec: 60, RetryAttempts: 5, RetryDelayMs: 1000})
+
+	if result.Attempts != 1 {
+		t.Errorf("Expected cancelled check not to be retried, got %d attempts", result.Attempts)
+	}
+}
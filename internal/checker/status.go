@@ -0,0 +1,80 @@
+package checker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StatusCodes is a parsed list of acceptable HTTP status codes and ranges
+type StatusCodes []statusRange
+
+type statusRange struct {
+	from int
+	to   int
+}
+
+// ParseStatusCodes parses a comma separated list of status codes and ranges, e.g. "200,201,300-399".
+// An empty spec yields an empty list that accepts any status.
+func ParseStatusCodes(spec string) (StatusCodes, error) {
+	var codes StatusCodes
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		fromStr, toStr, isRange := strings.Cut(part, "-")
+		if !isRange {
+			toStr = fromStr
+		}
+
+		from, err := parseStatusCode(fromStr)
+		if err != nil {
+			return nil, err
+		}
+
+		to, err := parseStatusCode(toStr)
+		if err != nil {
+			return nil, err
+		}
+
+		if from > to {
+			return nil, fmt.Errorf("invalid status code range %q", part)
+		}
+
+		codes = append(codes, statusRange{from: from, to: to})
+	}
+
+	return codes, nil
+}
+
+// Contains reports whether the status code is acceptable
+func (c StatusCodes) Contains(code int) bool {
+	if len(c) == 0 {
+		return true
+	}
+
+	for _, r := range c {
+		if code >= r.from && code <= r.to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseStatusCode parses a single status code and checks it is within the valid HTTP range
+func parseStatusCode(value string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid status code %q", value)
+	}
+
+	if code < 100 || code > 599 {
+		return 0, fmt.Errorf("status code %d is out of range 100-599", code)
+	}
+
+	return code, nil
+}
@@ -0,0 +1,49 @@
+package checker
+
+import (
+	"testing"
+)
+
+func TestParseStatusCodes_Empty(t *testing.T) {
+	codes, err := ParseStatusCodes("")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for _, code := range []int{200, 302, 404, 500} {
+		if !codes.Contains(code) {
+			t.Errorf("Expected empty list to accept %d", code)
+		}
+	}
+}
+
+func TestParseStatusCodes_ListAndRanges(t *testing.T) {
+	codes, err := ParseStatusCodes("200, 204,300-399")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	accepted := []int{200, 204, 300, 301, 399}
+	for _, code := range accepted {
+		if !codes.Contains(code) {
+			t.Errorf("Expected %d to be accepted", code)
+		}
+	}
+
+	rejected := []int{201, 299, 400, 500}
+	for _, code := range rejected {
+		if codes.Contains(code) {
+			t.Errorf("Expected %d to be rejected", code)
+		}
+	}
+}
+
+func TestParseStatusCodes_Invalid(t *testing.T) {
+	specs := []string{"abc", "200-", "399-300", "99", "600", "200-700"}
+
+	for _, spec := range specs {
+		if _, err := ParseStatusCodes(spec); err == nil {
+			t.Errorf("Expected error for spec %q", spec)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"website-monitor/internal/models"
+)
+
+const (
+	// DefaultTimeoutMs is the timeout of a url without timeout_ms, unless its check interval is shorter
+	DefaultTimeoutMs = 30000
+	// MinTimeoutMs is the shortest timeout_ms a url can set
+	MinTimeoutMs = 100
+)
+
+// Timeout returns how long a single attempt of a check of the url may take, including reading the body.
+// Without timeout_ms it is DefaultTimeoutMs, capped to the check interval
+func Timeout(url models.MonitoredUrl) time.Duration {
+	if url.TimeoutMs > 0 {
+		return time.Duration(url.TimeoutMs) * time.Millisecond
+	}
+
+	timeout := DefaultTimeoutMs * time.Millisecond
+	if interval := time.Duration(url.CheckIntervalSec) * time.Second; interval > 0 && interval < timeout {
+		return interval
+	}
+
+	return timeout
+}
+
+// slowWarning returns a warning if the response took longer than the slow threshold of the url
+func slowWarning(url models.MonitoredUrl, responseTimeMs *int) string {
+	if url.SlowThresholdMs <= 0 || responseTimeMs == nil || *responseTimeMs <= url.SlowThresholdMs {
+		return ""
+	}
+
+	return fmt.Sprintf("response time %d ms exceeds the slow threshold of %d ms", *responseTimeMs, url.SlowThresholdMs)
+}
+
+// timedOut reports whether the attempt context expired while the check itself was not cancelled
+func timedOut(ctx, attemptCtx context.Context) bool {
+	return ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded)
+}
+
+// timeoutError is the error of an attempt that exceeded the timeout of the url
+func timeoutError(timeout time.Duration) string {
+	return fmt.Sprintf("timeout of %d ms exceeded", timeout.Milliseconds())
+}
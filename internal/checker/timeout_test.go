@@ -0,0 +1,100 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"website-monitor/internal/models"
+)
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      models.MonitoredUrl
+		expected time.Duration
+	}{
+		{"default", models.MonitoredUrl{CheckIntervalSec: 60}, 30 * time.Second},
+		{"capped to interval", models.MonitoredUrl{CheckIntervalSec: 10}, 10 * time.Second},
+		{"configured", models.MonitoredUrl{CheckIntervalSec: 10, TimeoutMs: 2500}, 2500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if timeout := Timeout(tt.url); timeout != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, timeout)
+			}
+		})
+	}
+}
+
+func TestChecker_Check_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	result := checker.Check(context.Background(), models.MonitoredUrl{ID: 1, Url: server.URL, CheckIntervalSec: 60, TimeoutMs: 100})
+
+	if result.Error != "timeout of 100 ms exceeded" {
+		t.Errorf("Expected timeout error, got: %s", result.Error)
+	}
+}
+
+func TestChecker_Check_TimeoutWhileReadingBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	result := checker.Check(context.Background(), models.MonitoredUrl{ID: 1, Url: server.URL, CheckIntervalSec: 60, TimeoutMs: 100})
+
+	if result.Error != "timeout of 100 ms exceeded" {
+		t.Errorf("Expected timeout error, got: %s", result.Error)
+	}
+}
+
+func TestChecker_Check_SlowThreshold(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	checker := &Checker{
+		client: &http.Client{},
+	}
+
+	url := models.MonitoredUrl{ID: 1, Url: server.URL, CheckIntervalSec: 60, SlowThresholdMs: 10}
+	result := checker.Check(context.Background(), url)
+
+	if result.Error != "" {
+		t.Errorf("Expected slow check to succeed, got: %s", result.Error)
+	}
+	if result.Warning == "" {
+		t.Error("Expected slow response warning")
+	}
+
+	url.SlowThresholdMs = 5000
+	if result := checker.Check(context.Background(), url); result.Warning != "" {
+		t.Errorf("Expected no warning below the threshold, got: %s", result.Warning)
+	}
+}
@@ -0,0 +1,44 @@
+package checker
+
+import (
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"website-monitor/internal/models"
+)
+
+// recordCertificates stores the peer certificate chain of an HTTPS response in the result
+// and sets a warning if the chain expires within the configured thresholds
+func (c *Checker) recordCertificates(result *models.CheckResult, state *tls.ConnectionState) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return
+	}
+
+	var expiresAt time.Time
+	for _, cert := range state.PeerCertificates {
+		result.Certificates = append(result.Certificates, models.Certificate{
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			DNSNames:  cert.DNSNames,
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+		})
+
+		// An intermediate expiring before the leaf breaks the chain just the same
+		if expiresAt.IsZero() || cert.NotAfter.Before(expiresAt) {
+			expiresAt = cert.NotAfter
+		}
+	}
+
+	daysRemaining := int(expiresAt.Sub(result.CheckTimestamp).Hours() / 24)
+	result.CertExpiresAt = &expiresAt
+	result.CertDaysRemaining = &daysRemaining
+
+	switch {
+	case daysRemaining <= c.cfg.TLSExpiryCriticalDays:
+		result.Warning = fmt.Sprintf("TLS certificate expires in %d days, critical threshold is %d days", daysRemaining, c.cfg.TLSExpiryCriticalDays)
+	case daysRemaining <= c.cfg.TLSExpiryWarningDays:
+		result.Warning = fmt.Sprintf("TLS certificate expires in %d days, warning threshold is %d days", daysRemaining, c.cfg.TLSExpiryWarningDays)
+	}
+}
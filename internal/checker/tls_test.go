@@ -0,0 +1,116 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"website-monitor/internal/models"
+)
+
+func TestChecker_Check_RecordsCertificates(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	checker := &Checker{
+		client: server.Client(),
+		cfg:    models.CheckerConfig{TLSExpiryWarningDays: 30, TLSExpiryCriticalDays: 7},
+	}
+
+	result := checker.Check(context.Background(), models.MonitoredUrl{ID: 1, Url: server.URL, CheckIntervalSec: 60})
+
+	if result.Error != "" {
+		t.Fatalf("Expected no error, got: %s", result.Error)
+	}
+
+	if len(result.Certificates) == 0 {
+		t.Fatal("Expected certificate chain to be recorded")
+	}
+
+	leaf := server.Certificate()
+	if !result.Certificates[0].NotAfter.Equal(leaf.NotAfter) || result.Certificates[0].Subject != leaf.Subject.String() {
+		t.Errorf("Unexpected leaf certificate %+v", result.Certificates[0])
+	}
+
+	if result.CertExpiresAt == nil || !result.CertExpiresAt.Equal(leaf.NotAfter) {
+		t.Errorf("Expected expiry %v, got %v", leaf.NotAfter, result.CertExpiresAt)
+	}
+
+	expectedDays := int(leaf.NotAfter.Sub(result.CheckTimestamp).Hours() / 24)
+	if result.CertDaysRemaining == nil || *result.CertDaysRemaining != expectedDays {
+		t.Errorf("Expected %d days remaining, got %v", expectedDays, result.CertDaysRemaining)
+	}
+
+	if result.Warning != "" {
+		t.Errorf("Expected no warning, got: %s", result.Warning)
+	}
+}
+
+func TestChecker_Check_PlainHttpHasNoCertificates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	checker := &Checker{client: &http.Client{}}
+
+	result := checker.Check(context.Background(), models.MonitoredUrl{ID: 1, Url: server.URL, CheckIntervalSec: 60})
+
+	if result.Certificates != nil || result.CertExpiresAt != nil || result.Warning != "" {
+		t.Errorf("Expected no certificate info, got %+v", result)
+	}
+}
+
+func TestRecordCertificates_Thresholds(t *testing.T) {
+	now := time.Now()
+	checker := &Checker{cfg: models.CheckerConfig{TLSExpiryWarningDays: 30, TLSExpiryCriticalDays: 7}}
+
+	tests := []struct {
+		name            string
+		leafExpiry      time.Duration
+		intermediateExp time.Duration
+		expectedDays    int
+		expectedWarning string
+	}{
+		{"healthy", 90 * 24 * time.Hour, 365 * 24 * time.Hour, 90, ""},
+		{"warning", 20*24*time.Hour + time.Hour, 365 * 24 * time.Hour, 20, "warning threshold"},
+		{"critical", 3*24*time.Hour + time.Hour, 365 * 24 * time.Hour, 3, "critical threshold"},
+		{"intermediate expires first", 90 * 24 * time.Hour, 5*24*time.Hour + time.Hour, 5, "critical threshold"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := models.CheckResult{CheckTimestamp: now}
+			state := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+				{Subject: pkix.Name{CommonName: "example.com"}, DNSNames: []string{"example.com"}, NotAfter: now.Add(tt.leafExpiry)},
+				{Subject: pkix.Name{CommonName: "Intermediate CA"}, NotAfter: now.Add(tt.intermediateExp)},
+			}}
+
+			checker.recordCertificates(&result, state)
+
+			if len(result.Certificates) != 2 || result.Certificates[0].Subject != "CN=example.com" {
+				t.Errorf("Unexpected certificates %+v", result.Certificates)
+			}
+
+			if result.CertDaysRemaining == nil || *result.CertDaysRemaining != tt.expectedDays {
+				t.Errorf("Expected %d days remaining, got %v", tt.expectedDays, result.CertDaysRemaining)
+			}
+
+			if tt.expectedWarning == "" && result.Warning != "" {
+				t.Errorf("Expected no warning, got: %s", result.Warning)
+			}
+
+			if !strings.Contains(result.Warning, tt.expectedWarning) {
+				t.Errorf("Expected warning containing %q, got: %q", tt.expectedWarning, result.Warning)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"website-monitor/internal/models"
+)
+
+// requestTimer collects the phases of a request through httptrace.
+// With redirects every phase is overwritten by the following request, so the timings describe the last hop
+type requestTimer struct {
+	mu sync.Mutex
+
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+	bodyDone     time.Time
+}
+
+// trace returns a context that reports the request phases to the timer and starts the clock
+func (t *requestTimer) trace(ctx context.Context) context.Context {
+	t.start = time.Now()
+
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.set(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.set(&t.dnsDone) },
+		ConnectStart: func(network, addr string) {
+			// Dual stack dialing may start several connections, the first start counts
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.connectStart.IsZero() || !t.connectDone.IsZero() {
+				t.connectStart = time.Now()
+				t.connectDone = time.Time{}
+			}
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				t.set(&t.connectDone)
+			}
+		},
+		TLSHandshakeStart:    func() { t.set(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.set(&t.tlsDone) },
+		GotFirstResponseByte: func() { t.set(&t.firstByte) },
+	})
+}
+
+// set stores the current time in the field
+func (t *requestTimer) set(field *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	*field = time.Now()
+}
+
+// bodyRead marks the end of the content transfer
+func (t *requestTimer) bodyRead() {
+	t.set(&t.bodyDone)
+}
+
+// record copies the measured phases to the result. Phases that did not happen,
+// e.g. DNS for an IP address or TLS for plain HTTP, are left nil
+func (t *requestTimer) record(result *models.CheckResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result.DnsMs = durationMs(t.dnsStart, t.dnsDone)
+	result.ConnectMs = durationMs(t.connectStart, t.connectDone)
+	result.TlsHandshakeMs = durationMs(t.tlsStart, t.tlsDone)
+	result.TimeToFirstByteMs = durationMs(t.start, t.firstByte)
+	result.TransferMs = durationMs(t.firstByte, t.bodyDone)
+}
+
+// durationMs returns the milliseconds between start and end, or nil if either is missing
+func durationMs(start, end time.Time) *int {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return nil
+	}
+
+	ms := int(end.Sub(start).Milliseconds())
+
+	return &ms
+}
@@ -0,0 +1,90 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"website-monitor/internal/models"
+)
+
+func TestChecker_Check_RecordsTimings(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(strings.Repeat("x", 1024)))
+	}))
+	defer server.Close()
+
+	client := server.Client()
+	transport := client.Transport.(*http.Transport)
+	transport.DisableKeepAlives = true
+	// The test certificate is issued for example.com
+	transport.TLSClientConfig.ServerName = "example.com"
+	checker := &Checker{client: client}
+
+	// Going through localhost instead of 127.0.0.1 makes the check resolve a name
+	address := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	result := checker.Check(context.Background(), models.MonitoredUrl{ID: 1, Url: address, CheckIntervalSec: 60})
+
+	if result.Error != "" {
+		t.Fatalf("Expected no error, got: %s", result.Error)
+	}
+
+	for name, value := range map[string]*int{
+		"dns":      result.DnsMs,
+		"connect":  result.ConnectMs,
+		"tls":      result.TlsHandshakeMs,
+		"ttfb":     result.TimeToFirstByteMs,
+		"transfer": result.TransferMs,
+	} {
+		if value == nil || *value < 0 {
+			t.Errorf("Expected %s timing to be recorded, got %v", name, value)
+		}
+	}
+
+	if *result.TimeToFirstByteMs < *result.TlsHandshakeMs {
+		t.Errorf("Expected time to first byte to include the TLS handshake, got %d < %d", *result.TimeToFirstByteMs, *result.TlsHandshakeMs)
+	}
+}
+
+func TestChecker_Check_PlainHttpHasNoTlsTiming(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	checker := &Checker{client: &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}}
+
+	result := checker.Check(context.Background(), models.MonitoredUrl{ID: 1, Url: server.URL, CheckIntervalSec: 60})
+
+	if result.TlsHandshakeMs != nil {
+		t.Errorf("Expected no TLS timing, got %d", *result.TlsHandshakeMs)
+	}
+
+	if result.DnsMs != nil {
+		t.Errorf("Expected no DNS timing for an IP address, got %d", *result.DnsMs)
+	}
+
+	if result.ConnectMs == nil || result.TimeToFirstByteMs == nil || result.TransferMs == nil {
+		t.Errorf("Expected connect, ttfb and transfer timings, got %+v", result)
+	}
+}
+
+func TestDurationMs(t *testing.T) {
+	start := time.Now()
+
+	if ms := durationMs(start, start.Add(1500*time.Millisecond)); ms == nil || *ms != 1500 {
+		t.Errorf("Expected 1500ms, got %v", ms)
+	}
+
+	if ms := durationMs(start, time.Time{}); ms != nil {
+		t.Errorf("Expected nil for a missing end, got %d", *ms)
+	}
+
+	if ms := durationMs(start, start.Add(-time.Second)); ms != nil {
+		t.Errorf("Expected nil for an end before the start, got %d", *ms)
+	}
+}
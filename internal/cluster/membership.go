@@ -0,0 +1,332 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"website-monitor/internal/instance_repository"
+	"website-monitor/internal/models"
+)
+
+// leaseBuckets is the number of buckets urls are hashed into. Instances lease whole buckets, so the number of leases
+// does not grow with the number of urls
+const leaseBuckets = 256
+
+// Membership keeps this instance registered among the monitor instances sharing the database and assigns
+// every url to at most one live instance. Urls are hashed into buckets, and buckets are assigned by rendezvous
+// hashing over the instance ids, so when an instance joins or dies only the buckets it gains or loses move.
+// The views of the live instances can differ for a heartbeat, so an instance only checks the buckets it holds
+// a lease on in the database. A bucket moving away is released one heartbeat after the instance stopped owning it,
+// so its new owner cannot take it while its checks are still winding down
+type Membership struct {
+	repo              instance_repository.InstanceRepository
+	id                string
+	address           string
+	heartbeatInterval time.Duration
+	ttl               time.Duration
+	onChange          []func()
+	cancel            context.CancelFunc
+	wg                sync.WaitGroup
+
+	mu        sync.Mutex
+	instances []string
+	// held are the buckets leased by this instance
+	held map[int]bool
+	// draining are the buckets this instance gave up, their leases are released on the next heartbeat
+	draining []int
+	// lastHeartbeat is when the last successful refresh started, the leases it renewed expire a ttl after it
+	lastHeartbeat time.Time
+}
+
+func New(repo instance_repository.InstanceRepository, cfg models.ClusterConfig) *Membership {
+	return &Membership{
+		repo:              repo,
+		id:                cfg.InstanceID,
+		address:           cfg.AdvertiseURL,
+		heartbeatInterval: time.Duration(cfg.HeartbeatIntervalSec) * time.Second,
+		ttl:               time.Duration(cfg.InstanceTTLSec) * time.Second,
+	}
+}
+
+// OnChange registers a function that is called whenever the urls owned by this instance change. It must be called before Start
+func (m *Membership) OnChange(fn func()) {
+	m.onChange = append(m.onChange, fn)
+}
+
+// Start registers the instance and keeps sending heartbeats until Stop is called
+func (m *Membership) Start(ctx context.Context) error {
+	ctx, m.cancel = context.WithCancel(ctx)
+
+	if err := m.refresh(ctx); err != nil {
+		m.cancel()
+
+		return err
+	}
+
+	m.wg.Add(1)
+	go m.heartbeatLoop(ctx)
+
+	return nil
+}
+
+// Stop stops the heartbeats, releases the leases and deregisters the instance, so the other instances take over
+// its urls right away. The checks of the instance have to be stopped before
+func (m *Membership) Stop() {
+	if m.cancel == nil {
+		return
+	}
+
+	m.cancel()
+	m.wg.Wait()
+
+	m.mu.Lock()
+	buckets := append(m.draining, keys(m.held)...)
+	m.held, m.draining = nil, nil
+	m.mu.Unlock()
+
+	// The context of the instance is cancelled by now, leaving gets its own short deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := m.repo.ReleaseLeases(ctx, m.id, buckets); err != nil {
+		log.Printf("Failed to release leases of instance %s: %v", m.id, err)
+	}
+
+	if err := m.repo.Deregister(ctx, m.id); err != nil {
+		log.Printf("Failed to deregister instance %s: %v", m.id, err)
+	}
+}
+
+// ID returns the id of this instance
+func (m *Membership) ID() string {
+	return m.id
+}
+
+// Instances returns the ids of the live instances, ordered by id
+func (m *Membership) Instances() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]string(nil), m.instances...)
+}
+
+// Owns reports whether this instance checks the url, i.e. holds the lease of its bucket. An instance that could not
+// renew its leases within the ttl owns no urls, as the other instances may have taken them over by then. This is
+// checked here too, so a heartbeat that is still running does not keep the urls past the ttl
+func (m *Membership) Owns(urlID int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return time.Since(m.lastHeartbeat) < m.ttl && m.held[bucket(urlID)]
+}
+
+// Locate returns the address of the instance owning the url, or local if it is this instance.
+// It returns an error if nobody owns the url, e.g. while it moves between instances
+func (m *Membership) Locate(ctx context.Context, urlID int) (string, bool, error) {
+	if m.Owns(urlID) {
+		return m.address, true, nil
+	}
+
+	address, err := m.repo.LeaseHolder(ctx, bucket(urlID))
+	if err != nil {
+		return "", false, err
+	}
+
+	// The lease may still be held by this instance while it releases it
+	if address == "" || address == m.address {
+		return "", false, fmt.Errorf("url %d is not owned by any instance", urlID)
+	}
+
+	return address, false, nil
+}
+
+// heartbeatLoop renews the registration and the leases of the instance and picks up joined and dead instances
+func (m *Membership) heartbeatLoop(ctx context.Context) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := m.refresh(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to refresh cluster membership: %v", err)
+			m.expire()
+		}
+	}
+}
+
+// refresh sends a heartbeat, reloads the live instances, releases the buckets given up on the last heartbeat
+// and leases the buckets assigned to this instance. It gives up after a heartbeat interval, so a hanging database
+// call cannot keep the instance from noticing that its leases expire
+func (m *Membership) refresh(ctx context.Context) error {
+	started := time.Now()
+
+	ctx, cancel := context.WithTimeout(ctx, m.heartbeatInterval)
+	defer cancel()
+
+	if err := m.repo.Heartbeat(ctx, m.id, m.address); err != nil {
+		return err
+	}
+
+	if err := m.repo.PruneInstances(ctx, m.ttl); err != nil {
+		// Dead instances are not returned as live anyway, so pruning can wait for the next heartbeat
+		log.Printf("Failed to prune instances: %v", err)
+	}
+
+	instances, err := m.repo.LiveInstances(ctx, m.ttl)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	draining := m.draining
+	m.mu.Unlock()
+
+	if err := m.repo.ReleaseLeases(ctx, m.id, draining); err != nil {
+		// The leases expire by themselves, until then the buckets stay with nobody
+		log.Printf("Failed to release leases of instance %s: %v", m.id, err)
+	} else {
+		m.mu.Lock()
+		m.draining = m.draining[len(draining):]
+		m.mu.Unlock()
+	}
+
+	var assigned []int
+	for b := 0; b < leaseBuckets; b++ {
+		if owner(instances, b) == m.id {
+			assigned = append(assigned, b)
+		}
+	}
+
+	held, err := m.repo.AcquireLeases(ctx, m.id, assigned, m.ttl)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.lastHeartbeat = started
+	m.mu.Unlock()
+
+	m.update(instances, held)
+
+	return nil
+}
+
+// expire gives up all urls once the last heartbeat is older than the ttl
+func (m *Membership) expire() {
+	m.mu.Lock()
+	expired := time.Since(m.lastHeartbeat) >= m.ttl
+	m.mu.Unlock()
+
+	if expired {
+		m.update(nil, nil)
+	}
+}
+
+// update sets the live instances and the held buckets and notifies the change handlers if the held buckets changed.
+// Buckets that are no longer held are released on the next heartbeat
+func (m *Membership) update(instances []string, buckets []int) {
+	held := make(map[int]bool, len(buckets))
+	for _, b := range buckets {
+		held[b] = true
+	}
+
+	m.mu.Lock()
+	if !equal(m.instances, instances) {
+		log.Printf("Cluster membership of instance %s changed, live instances: %v", m.id, instances)
+	}
+	m.instances = instances
+
+	changed := len(held) != len(m.held)
+	for b := range m.held {
+		if !held[b] {
+			m.draining = append(m.draining, b)
+			changed = true
+		}
+	}
+	m.held = held
+	m.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	log.Printf("Instance %s now holds %d of %d url buckets", m.id, len(held), leaseBuckets)
+
+	for _, fn := range m.onChange {
+		fn()
+	}
+}
+
+// bucket returns the lease bucket of a url
+func bucket(urlID int) int {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(strconv.Itoa(urlID)))
+
+	return int(mix(hash.Sum64()) % leaseBuckets)
+}
+
+// owner returns the instance a bucket is assigned to, the one with the highest hash of instance and bucket
+func owner(instances []string, b int) string {
+	var best string
+	var bestScore uint64
+
+	for _, instance := range instances {
+		hash := fnv.New64a()
+		_, _ = hash.Write([]byte(instance))
+		_, _ = hash.Write([]byte{0})
+		_, _ = hash.Write([]byte(strconv.Itoa(b)))
+
+		if score := mix(hash.Sum64()); best == "" || score > bestScore {
+			best, bestScore = instance, score
+		}
+	}
+
+	return best
+}
+
+// mix scrambles the bits of a hash with the splitmix64 finalizer. FNV hashes of ids that only differ
+// in their last digits are close to each other, which would hand most urls to the same instance
+func mix(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+
+	return h
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func keys(set map[int]bool) []int {
+	result := make([]int, 0, len(set))
+	for key := range set {
+		result = append(result, key)
+	}
+
+	return result
+}
@@ -0,0 +1,339 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"website-monitor/internal/models"
+)
+
+type mockInstanceRepository struct {
+	mu           sync.Mutex
+	instances    []string
+	heartbeatErr error
+	// block makes heartbeats hang until it is closed or their context is done
+	block        chan struct{}
+	heartbeats   []string
+	deregistered []string
+	// leases maps the leased buckets to their instance, leases do not expire
+	leases map[int]string
+	// addresses maps the instances to the addresses sent with their heartbeats
+	addresses map[string]string
+}
+
+func (m *mockInstanceRepository) Heartbeat(ctx context.Context, id, address string) error {
+	m.mu.Lock()
+	block := m.block
+	m.mu.Unlock()
+
+	if block != nil {
+		select {
+		case <-block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.heartbeats = append(m.heartbeats, id)
+	if m.addresses == nil {
+		m.addresses = make(map[string]string)
+	}
+	m.addresses[id] = address
+	return m.heartbeatErr
+}
+
+func (m *mockInstanceRepository) LiveInstances(ctx context.Context, ttl time.Duration) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.instances...), nil
+}
+
+func (m *mockInstanceRepository) PruneInstances(ctx context.Context, ttl time.Duration) error {
+	return nil
+}
+
+func (m *mockInstanceRepository) Deregister(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deregistered = append(m.deregistered, id)
+	return nil
+}
+
+func (m *mockInstanceRepository) AcquireLeases(ctx context.Context, id string, buckets []int, ttl time.Duration) ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.leases == nil {
+		m.leases = make(map[int]string)
+	}
+
+	var held []int
+	for _, b := range buckets {
+		if holder, ok := m.leases[b]; !ok || holder == id {
+			m.leases[b] = id
+			held = append(held, b)
+		}
+	}
+	return held, nil
+}
+
+func (m *mockInstanceRepository) ReleaseLeases(ctx context.Context, id string, buckets []int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, b := range buckets {
+		if m.leases[b] == id {
+			delete(m.leases, b)
+		}
+	}
+	return nil
+}
+
+func (m *mockInstanceRepository) LeaseHolder(ctx context.Context, bucket int) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.addresses[m.leases[bucket]], nil
+}
+
+func (m *mockInstanceRepository) set(instances []string, heartbeatErr error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.instances = instances
+	m.heartbeatErr = heartbeatErr
+}
+
+func TestOwner_SpreadsUrlsAndMovesOnlyThoseOfLeavingInstance(t *testing.T) {
+	instances := []string{"monitor-a", "monitor-b", "monitor-c"}
+	counts := map[string]int{}
+
+	for id := 1; id <= 3000; id++ {
+		before := owner(instances, id)
+		counts[before]++
+
+		after := owner([]string{"monitor-a", "monitor-c"}, id)
+		if before != "monitor-b" && after != before {
+			t.Fatalf("Expected url %d to stay with %s, moved to %s", id, before, after)
+		}
+	}
+
+	for _, instance := range instances {
+		if counts[instance] < 800 || counts[instance] > 1200 {
+			t.Errorf("Expected urls to be spread evenly, got %v", counts)
+		}
+	}
+
+	if owner(nil, 1) != "" {
+		t.Error("Expected no owner without live instances")
+	}
+}
+
+func TestMembership_StartOwnsAndNotifiesChanges(t *testing.T) {
+	repo := &mockInstanceRepository{instances: []string{"monitor-a"}}
+	membership := New(repo, models.ClusterConfig{InstanceID: "monitor-a", HeartbeatIntervalSec: 10, InstanceTTLSec: 30})
+	membership.heartbeatInterval = 5 * time.Millisecond
+
+	changes := make(chan struct{}, 10)
+	membership.OnChange(func() { changes <- struct{}{} })
+
+	if err := membership.Start(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !membership.Owns(1) || !membership.Owns(2) {
+		t.Error("Expected single instance to own every url")
+	}
+
+	<-changes
+
+	repo.set([]string{"monitor-a", "monitor-b"}, nil)
+
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("Expected joined instance to be noticed")
+	}
+
+	owned := 0
+	for id := 1; id <= 100; id++ {
+		if membership.Owns(id) {
+			owned++
+		}
+	}
+
+	if owned == 0 || owned == 100 {
+		t.Errorf("Expected urls to be shared with the joined instance, own %d of 100", owned)
+	}
+
+	membership.Stop()
+
+	if len(repo.deregistered) != 1 || repo.deregistered[0] != "monitor-a" {
+		t.Errorf("Expected instance to deregister on stop, got %v", repo.deregistered)
+	}
+
+	if len(repo.leases) != 0 {
+		t.Errorf("Expected leases to be released on stop, got %v", repo.leases)
+	}
+}
+
+func TestMembership_HandsOverUrlsOnlyAfterRelease(t *testing.T) {
+	repo := &mockInstanceRepository{instances: []string{"monitor-a"}}
+	cfg := models.ClusterConfig{HeartbeatIntervalSec: 10, InstanceTTLSec: 30}
+	cfg.InstanceID = "monitor-a"
+	a := New(repo, cfg)
+	cfg.InstanceID = "monitor-b"
+	b := New(repo, cfg)
+	ctx := context.Background()
+
+	// owners counts the urls owned by each instance and fails on urls owned by both
+	owners := func() (int, int) {
+		ownedA, ownedB := 0, 0
+		for id := 1; id <= 1000; id++ {
+			if a.Owns(id) && b.Owns(id) {
+				t.Fatalf("Expected url %d to be owned by a single instance", id)
+			}
+			if a.Owns(id) {
+				ownedA++
+			}
+			if b.Owns(id) {
+				ownedB++
+			}
+		}
+		return ownedA, ownedB
+	}
+
+	_ = a.refresh(ctx)
+	if ownedA, _ := owners(); ownedA != 1000 {
+		t.Fatalf("Expected single instance to own every url, owns %d", ownedA)
+	}
+
+	repo.set([]string{"monitor-a", "monitor-b"}, nil)
+
+	// b is assigned its share, but a still holds the leases
+	_ = b.refresh(ctx)
+	if ownedA, ownedB := owners(); ownedA != 1000 || ownedB != 0 {
+		t.Fatalf("Expected urls to stay with a until it releases them, a owns %d, b owns %d", ownedA, ownedB)
+	}
+
+	// a stops owning the share of b, but keeps the leases while its checks wind down
+	_ = a.refresh(ctx)
+	_ = b.refresh(ctx)
+	ownedA, ownedB := owners()
+	if ownedA == 1000 || ownedB != 0 {
+		t.Fatalf("Expected share of b to be owned by nobody until released, a owns %d, b owns %d", ownedA, ownedB)
+	}
+
+	_ = a.refresh(ctx)
+	_ = b.refresh(ctx)
+	ownedA, ownedB = owners()
+	if ownedA+ownedB != 1000 || ownedB == 0 {
+		t.Errorf("Expected urls to be shared after the release, a owns %d, b owns %d", ownedA, ownedB)
+	}
+}
+
+func TestMembership_Start_HeartbeatError(t *testing.T) {
+	repo := &mockInstanceRepository{heartbeatErr: errors.New("database down")}
+	membership := New(repo, models.ClusterConfig{InstanceID: "monitor-a", HeartbeatIntervalSec: 10, InstanceTTLSec: 30})
+
+	if err := membership.Start(context.Background()); err == nil {
+		t.Fatal("Expected error from heartbeat")
+	}
+}
+
+func TestMembership_Expire_GivesUpUrlsAfterTTL(t *testing.T) {
+	repo := &mockInstanceRepository{instances: []string{"monitor-a"}}
+	membership := New(repo, models.ClusterConfig{InstanceID: "monitor-a", HeartbeatIntervalSec: 10, InstanceTTLSec: 30})
+
+	if err := membership.refresh(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	membership.expire()
+	if !membership.Owns(1) {
+		t.Fatal("Expected urls to be kept within the ttl")
+	}
+
+	membership.lastHeartbeat = time.Now().Add(-31 * time.Second)
+	membership.expire()
+
+	if membership.Owns(1) || len(membership.Instances()) != 0 {
+		t.Errorf("Expected no urls after the ttl, live instances %v", membership.Instances())
+	}
+}
+
+func TestMembership_HangingHeartbeat_GivesUpUrlsAfterTTL(t *testing.T) {
+	repo := &mockInstanceRepository{instances: []string{"monitor-a"}}
+	membership := New(repo, models.ClusterConfig{InstanceID: "monitor-a", HeartbeatIntervalSec: 10, InstanceTTLSec: 30})
+	membership.heartbeatInterval = 10 * time.Millisecond
+	membership.ttl = 50 * time.Millisecond
+
+	changes := make(chan struct{}, 10)
+	membership.OnChange(func() { changes <- struct{}{} })
+
+	if err := membership.Start(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer membership.Stop()
+	<-changes
+
+	block := make(chan struct{})
+	defer close(block)
+	repo.mu.Lock()
+	repo.block = block
+	repo.mu.Unlock()
+
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("Expected urls to be given up while the heartbeat hangs")
+	}
+
+	if membership.Owns(1) {
+		t.Error("Expected no urls once the ttl passed without a heartbeat")
+	}
+}
+
+func TestMembership_Owns_NotAfterTTL(t *testing.T) {
+	repo := &mockInstanceRepository{instances: []string{"monitor-a"}}
+	membership := New(repo, models.ClusterConfig{InstanceID: "monitor-a", HeartbeatIntervalSec: 10, InstanceTTLSec: 30})
+
+	if err := membership.refresh(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// The heartbeat that would have expired the urls is still running, so the leases are kept until the ttl check
+	membership.lastHeartbeat = time.Now().Add(-31 * time.Second)
+
+	if membership.Owns(1) {
+		t.Error("Expected no urls after the ttl, even before expire ran")
+	}
+}
+
+func TestMembership_Locate(t *testing.T) {
+	repo := &mockInstanceRepository{instances: []string{"monitor-a", "monitor-b"}}
+	a := New(repo, models.ClusterConfig{InstanceID: "monitor-a", HeartbeatIntervalSec: 10, InstanceTTLSec: 30, AdvertiseURL: "http://a:8080"})
+	b := New(repo, models.ClusterConfig{InstanceID: "monitor-b", HeartbeatIntervalSec: 10, InstanceTTLSec: 30, AdvertiseURL: "http://b:8080"})
+	ctx := context.Background()
+
+	_ = a.refresh(ctx)
+	_ = b.refresh(ctx)
+
+	for id := 1; id <= 100; id++ {
+		address, local, err := a.Locate(ctx, id)
+		if err != nil {
+			t.Fatalf("Expected url %d to be located, got: %v", id, err)
+		}
+
+		if local != a.Owns(id) || (!local && address != "http://b:8080") {
+			t.Errorf("Unexpected location of url %d: %q, local %v", id, address, local)
+		}
+	}
+
+	c := New(&mockInstanceRepository{}, models.ClusterConfig{InstanceID: "monitor-c", AdvertiseURL: "http://c:8080"})
+	if _, _, err := c.Locate(ctx, 1); err == nil {
+		t.Error("Expected error for a url nobody owns")
+	}
+}
@@ -2,11 +2,33 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"strconv"
+	"strings"
 
 	"website-monitor/internal/models"
 )
 
+const (
+	// ModeMonitor runs the monitor with its database, API and scheduler
+	ModeMonitor = "monitor"
+	// ModeAgent runs checks and pushes their results to a collector instead of storing them
+	ModeAgent = "agent"
+)
+
+// Mode returns whether the process runs as the monitor or as an agent, set by MONITOR_MODE
+func Mode() (string, error) {
+	switch mode := os.Getenv("MONITOR_MODE"); mode {
+	case "", ModeMonitor:
+		return ModeMonitor, nil
+	case ModeAgent:
+		return ModeAgent, nil
+	default:
+		return "", fmt.Errorf("MONITOR_MODE must be %s or %s, got %q", ModeMonitor, ModeAgent, mode)
+	}
+}
+
 // Load loads configuration from environment variables
 func Load() (*models.Config, error) {
 	dbConfig, err := loadDatabaseConfig()
@@ -14,8 +36,69 @@ func Load() (*models.Config, error) {
 		return nil, fmt.Errorf("failed to load database config: %w", err)
 	}
 
+	schedulerConfig, err := loadSchedulerConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scheduler config: %w", err)
+	}
+
+	stateConfig, err := loadStateConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state config: %w", err)
+	}
+
+	notifierConfig, err := loadNotifierConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notifier config: %w", err)
+	}
+
+	checkerConfig, err := LoadCheckerConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checker config: %w", err)
+	}
+
+	clusterConfig, err := loadClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cluster config: %w", err)
+	}
+
+	apiConfig, err := loadApiConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load api config: %w", err)
+	}
+
 	return &models.Config{
-		Database: *dbConfig,
+		Database:  *dbConfig,
+		Scheduler: *schedulerConfig,
+		State:     *stateConfig,
+		Notifier:  *notifierConfig,
+		Api:       *apiConfig,
+		Checker:   *checkerConfig,
+		Cluster:   *clusterConfig,
+	}, nil
+}
+
+// LoadAgent loads the configuration of the agent mode from environment variables.
+// Agents have no database, so only the settings of the checks and the collector are loaded
+func LoadAgent() (*models.Config, error) {
+	agentConfig, err := loadAgentConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load agent config: %w", err)
+	}
+
+	schedulerConfig, err := loadSchedulerConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scheduler config: %w", err)
+	}
+
+	checkerConfig, err := LoadCheckerConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checker config: %w", err)
+	}
+
+	return &models.Config{
+		Scheduler: *schedulerConfig,
+		Checker:   *checkerConfig,
+		Agent:     *agentConfig,
 	}, nil
 }
 
@@ -57,3 +140,291 @@ func loadDatabaseConfig() (*models.DatabaseConfig, error) {
 		SSLMode:  sslMode,
 	}, nil
 }
+
+// loadSchedulerConfig loads scheduler configuration from environment variables
+func loadSchedulerConfig() (*models.SchedulerConfig, error) {
+	reloadInterval, err := getEnvInt("SCHEDULER_RELOAD_INTERVAL_SEC", 30)
+	if err != nil {
+		return nil, err
+	}
+
+	if reloadInterval < 0 {
+		return nil, fmt.Errorf("SCHEDULER_RELOAD_INTERVAL_SEC must not be negative, got %d", reloadInterval)
+	}
+
+	workers, err := getEnvInt("SCHEDULER_WORKERS", 50)
+	if err != nil {
+		return nil, err
+	}
+
+	if workers < 1 {
+		return nil, fmt.Errorf("SCHEDULER_WORKERS must be at least 1, got %d", workers)
+	}
+
+	perHostLimit, err := getEnvInt("SCHEDULER_PER_HOST_LIMIT", 4)
+	if err != nil {
+		return nil, err
+	}
+
+	if perHostLimit < 0 {
+		return nil, fmt.Errorf("SCHEDULER_PER_HOST_LIMIT must not be negative, got %d", perHostLimit)
+	}
+
+	jitterPercent, err := getEnvInt("SCHEDULER_JITTER_PERCENT", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if jitterPercent < 0 || jitterPercent > 50 {
+		return nil, fmt.Errorf("SCHEDULER_JITTER_PERCENT must be between 0 and 50, got %d", jitterPercent)
+	}
+
+	location := os.Getenv("LOCATION")
+	if location != "" && !models.ValidLocation(location) {
+		return nil, fmt.Errorf("LOCATION must consist of letters, digits, '.', '_' and '-', got %q", location)
+	}
+
+	return &models.SchedulerConfig{
+		ReloadIntervalSec: reloadInterval,
+		Workers:           workers,
+		PerHostLimit:      perHostLimit,
+		JitterPercent:     jitterPercent,
+		Location:          location,
+	}, nil
+}
+
+// LoadCheckerConfig loads check settings from environment variables.
+// It is exported for tools that run checks without the rest of the configuration
+func LoadCheckerConfig() (*models.CheckerConfig, error) {
+	warningDays, err := getEnvInt("TLS_EXPIRY_WARNING_DAYS", 30)
+	if err != nil {
+		return nil, err
+	}
+
+	criticalDays, err := getEnvInt("TLS_EXPIRY_CRITICAL_DAYS", 7)
+	if err != nil {
+		return nil, err
+	}
+
+	if criticalDays < 0 {
+		return nil, fmt.Errorf("TLS_EXPIRY_CRITICAL_DAYS must not be negative, got %d", criticalDays)
+	}
+
+	if warningDays < criticalDays {
+		return nil, fmt.Errorf("TLS_EXPIRY_WARNING_DAYS must not be less than TLS_EXPIRY_CRITICAL_DAYS, got %d < %d", warningDays, criticalDays)
+	}
+
+	return &models.CheckerConfig{
+		TLSExpiryWarningDays:  warningDays,
+		TLSExpiryCriticalDays: criticalDays,
+	}, nil
+}
+
+// loadStateConfig loads up/down state machine thresholds from environment variables
+func loadStateConfig() (*models.StateConfig, error) {
+	failureThreshold, err := getEnvInt("STATE_FAILURE_THRESHOLD", 3)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryThreshold, err := getEnvInt("STATE_RECOVERY_THRESHOLD", 2)
+	if err != nil {
+		return nil, err
+	}
+
+	if failureThreshold < 1 {
+		return nil, fmt.Errorf("STATE_FAILURE_THRESHOLD must be at least 1, got %d", failureThreshold)
+	}
+
+	if recoveryThreshold < 1 {
+		return nil, fmt.Errorf("STATE_RECOVERY_THRESHOLD must be at least 1, got %d", recoveryThreshold)
+	}
+
+	return &models.StateConfig{
+		FailureThreshold:  failureThreshold,
+		RecoveryThreshold: recoveryThreshold,
+	}, nil
+}
+
+// loadNotifierConfig loads webhook notification configuration from environment variables
+func loadNotifierConfig() (*models.NotifierConfig, error) {
+	timeout, err := getEnvInt("WEBHOOK_TIMEOUT_SEC", 10)
+	if err != nil {
+		return nil, err
+	}
+
+	maxAttempts, err := getEnvInt("WEBHOOK_MAX_ATTEMPTS", 3)
+	if err != nil {
+		return nil, err
+	}
+
+	retryDelay, err := getEnvInt("WEBHOOK_RETRY_DELAY_SEC", 5)
+	if err != nil {
+		return nil, err
+	}
+
+	if timeout < 1 {
+		return nil, fmt.Errorf("WEBHOOK_TIMEOUT_SEC must be at least 1, got %d", timeout)
+	}
+
+	if maxAttempts < 1 {
+		return nil, fmt.Errorf("WEBHOOK_MAX_ATTEMPTS must be at least 1, got %d", maxAttempts)
+	}
+
+	if retryDelay < 0 {
+		return nil, fmt.Errorf("WEBHOOK_RETRY_DELAY_SEC must not be negative, got %d", retryDelay)
+	}
+
+	return &models.NotifierConfig{
+		WebhookURLs:   getEnvList("WEBHOOK_URLS"),
+		Secret:        os.Getenv("WEBHOOK_SECRET"),
+		TimeoutSec:    timeout,
+		MaxAttempts:   maxAttempts,
+		RetryDelaySec: retryDelay,
+	}, nil
+}
+
+// loadClusterConfig loads the coordination settings of monitor instances from environment variables
+func loadClusterConfig() (*models.ClusterConfig, error) {
+	heartbeatInterval, err := getEnvInt("CLUSTER_HEARTBEAT_INTERVAL_SEC", 10)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, err := getEnvInt("CLUSTER_INSTANCE_TTL_SEC", 30)
+	if err != nil {
+		return nil, err
+	}
+
+	if heartbeatInterval < 1 {
+		return nil, fmt.Errorf("CLUSTER_HEARTBEAT_INTERVAL_SEC must be at least 1, got %d", heartbeatInterval)
+	}
+
+	// A single late heartbeat must not make the other instances take over the urls
+	if ttl < 2*heartbeatInterval {
+		return nil, fmt.Errorf("CLUSTER_INSTANCE_TTL_SEC must be at least twice CLUSTER_HEARTBEAT_INTERVAL_SEC (%d), got %d", 2*heartbeatInterval, ttl)
+	}
+
+	instanceID := os.Getenv("INSTANCE_ID")
+	if instanceID == "" {
+		// Host name and process id tell apart containers as well as processes on the same host
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("INSTANCE_ID not set and host name unknown: %w", err)
+		}
+		instanceID = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+
+	advertiseURL := strings.TrimRight(os.Getenv("CLUSTER_ADVERTISE_URL"), "/")
+	if advertiseURL == "" {
+		// Replicas of a deployment reach each other by host name at the port of the API
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("CLUSTER_ADVERTISE_URL not set and host name unknown: %w", err)
+		}
+
+		addr := os.Getenv("API_ADDR")
+		if addr == "" {
+			addr = ":8080"
+		}
+
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("CLUSTER_ADVERTISE_URL not set and API_ADDR has no port: %w", err)
+		}
+		advertiseURL = "http://" + net.JoinHostPort(hostname, port)
+	}
+
+	if !strings.HasPrefix(advertiseURL, "http://") && !strings.HasPrefix(advertiseURL, "https://") {
+		return nil, fmt.Errorf("CLUSTER_ADVERTISE_URL must be an http or https url, got %q", advertiseURL)
+	}
+
+	return &models.ClusterConfig{
+		InstanceID:           instanceID,
+		HeartbeatIntervalSec: heartbeatInterval,
+		InstanceTTLSec:       ttl,
+		AdvertiseURL:         advertiseURL,
+	}, nil
+}
+
+// loadApiConfig loads HTTP API configuration from environment variables
+func loadApiConfig() (*models.ApiConfig, error) {
+	addr := os.Getenv("API_ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
+
+	// AGENT_TOKENS lists the agents as location:token pairs
+	tokens := make(map[string]string)
+	for _, pair := range getEnvList("AGENT_TOKENS") {
+		location, token, _ := strings.Cut(pair, ":")
+		if !models.ValidLocation(location) || token == "" {
+			return nil, fmt.Errorf("AGENT_TOKENS must list location:token pairs, got an invalid pair for %q", location)
+		}
+
+		if _, ok := tokens[location]; ok {
+			return nil, fmt.Errorf("AGENT_TOKENS lists location %q twice", location)
+		}
+		tokens[location] = token
+	}
+
+	return &models.ApiConfig{
+		Addr:        addr,
+		Token:       os.Getenv("API_TOKEN"),
+		AgentTokens: tokens,
+	}, nil
+}
+
+// loadAgentConfig loads the collector and credentials of the agent mode from environment variables
+func loadAgentConfig() (*models.AgentConfig, error) {
+	required := map[string]string{
+		"COLLECTOR_URL": os.Getenv("COLLECTOR_URL"),
+		"LOCATION":      os.Getenv("LOCATION"),
+		"AGENT_TOKEN":   os.Getenv("AGENT_TOKEN"),
+	}
+
+	for key, value := range required {
+		if value == "" {
+			return nil, fmt.Errorf("required environment variable %s not set", key)
+		}
+	}
+
+	collectorURL := strings.TrimRight(required["COLLECTOR_URL"], "/")
+	if !strings.HasPrefix(collectorURL, "http://") && !strings.HasPrefix(collectorURL, "https://") {
+		return nil, fmt.Errorf("COLLECTOR_URL must be an http or https url, got %q", collectorURL)
+	}
+
+	return &models.AgentConfig{
+		CollectorURL: collectorURL,
+		Location:     required["LOCATION"],
+		Token:        required["AGENT_TOKEN"],
+	}, nil
+}
+
+// getEnvList reads an optional comma separated environment variable, skipping empty items
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// getEnvInt reads an optional integer environment variable, falling back to the default if it is not set
+func getEnvInt(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("environment variable %s must be an integer, got %q", key, value)
+	}
+
+	return parsed, nil
+}
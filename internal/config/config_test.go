@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"testing"
 	"website-monitor/internal/models"
@@ -128,6 +129,307 @@ func TestLoadDatabaseConfig_MissingName(t *testing.T) {
 	}
 }
 
+func TestLoadSchedulerConfig_Default(t *testing.T) {
+	os.Unsetenv("SCHEDULER_RELOAD_INTERVAL_SEC")
+	os.Unsetenv("SCHEDULER_WORKERS")
+	os.Unsetenv("SCHEDULER_PER_HOST_LIMIT")
+	os.Unsetenv("SCHEDULER_JITTER_PERCENT")
+
+	config, err := loadSchedulerConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if config.ReloadIntervalSec != 30 {
+		t.Errorf("Expected default reload interval 30, got %d", config.ReloadIntervalSec)
+	}
+
+	if config.Workers != 50 || config.PerHostLimit != 4 {
+		t.Errorf("Expected default workers 50 and per-host limit 4, got %d and %d", config.Workers, config.PerHostLimit)
+	}
+}
+
+func TestLoadSchedulerConfig_FromEnv(t *testing.T) {
+	os.Setenv("SCHEDULER_RELOAD_INTERVAL_SEC", "0")
+	defer os.Unsetenv("SCHEDULER_RELOAD_INTERVAL_SEC")
+
+	config, err := loadSchedulerConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if config.ReloadIntervalSec != 0 {
+		t.Errorf("Expected reload interval 0, got %d", config.ReloadIntervalSec)
+	}
+}
+
+func TestLoadSchedulerConfig_InvalidValue(t *testing.T) {
+	os.Setenv("SCHEDULER_RELOAD_INTERVAL_SEC", "soon")
+	defer os.Unsetenv("SCHEDULER_RELOAD_INTERVAL_SEC")
+
+	_, err := loadSchedulerConfig()
+	if err == nil {
+		t.Fatal("Expected error for non-integer reload interval")
+	}
+}
+
+func TestLoadSchedulerConfig_InvalidWorkers(t *testing.T) {
+	os.Setenv("SCHEDULER_WORKERS", "0")
+	defer os.Unsetenv("SCHEDULER_WORKERS")
+
+	_, err := loadSchedulerConfig()
+	if err == nil {
+		t.Fatal("Expected error for zero workers")
+	}
+}
+
+func TestLoadSchedulerConfig_InvalidJitter(t *testing.T) {
+	os.Setenv("SCHEDULER_JITTER_PERCENT", "60")
+	defer os.Unsetenv("SCHEDULER_JITTER_PERCENT")
+
+	_, err := loadSchedulerConfig()
+	if err == nil {
+		t.Fatal("Expected error for jitter above 50 percent")
+	}
+}
+
+func TestLoadStateConfig_Default(t *testing.T) {
+	os.Unsetenv("STATE_FAILURE_THRESHOLD")
+	os.Unsetenv("STATE_RECOVERY_THRESHOLD")
+
+	config, err := loadStateConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := models.StateConfig{FailureThreshold: 3, RecoveryThreshold: 2}
+	if *config != expected {
+		t.Errorf("Expected state config %+v, got %+v", expected, *config)
+	}
+}
+
+func TestLoadStateConfig_InvalidThreshold(t *testing.T) {
+	os.Setenv("STATE_FAILURE_THRESHOLD", "0")
+	defer os.Unsetenv("STATE_FAILURE_THRESHOLD")
+
+	_, err := loadStateConfig()
+	if err == nil {
+		t.Fatal("Expected error for zero failure threshold")
+	}
+}
+
+func TestLoadCheckerConfig_Default(t *testing.T) {
+	os.Unsetenv("TLS_EXPIRY_WARNING_DAYS")
+	os.Unsetenv("TLS_EXPIRY_CRITICAL_DAYS")
+
+	config, err := LoadCheckerConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := models.CheckerConfig{TLSExpiryWarningDays: 30, TLSExpiryCriticalDays: 7}
+	if *config != expected {
+		t.Errorf("Expected checker config %+v, got %+v", expected, *config)
+	}
+}
+
+func TestLoadCheckerConfig_WarningBelowCritical(t *testing.T) {
+	os.Setenv("TLS_EXPIRY_WARNING_DAYS", "5")
+	defer os.Unsetenv("TLS_EXPIRY_WARNING_DAYS")
+
+	_, err := LoadCheckerConfig()
+	if err == nil {
+		t.Fatal("Expected error for warning threshold below critical threshold")
+	}
+}
+
+func TestLoadNotifierConfig_FromEnv(t *testing.T) {
+	os.Setenv("WEBHOOK_URLS", "https://hooks.example.com/a, ,https://hooks.example.com/b")
+	os.Setenv("WEBHOOK_SECRET", "s3cret")
+	defer os.Unsetenv("WEBHOOK_URLS")
+	defer os.Unsetenv("WEBHOOK_SECRET")
+
+	config, err := loadNotifierConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(config.WebhookURLs) != 2 || config.WebhookURLs[1] != "https://hooks.example.com/b" {
+		t.Errorf("Expected 2 webhook urls, got %v", config.WebhookURLs)
+	}
+
+	if config.Secret != "s3cret" {
+		t.Errorf("Expected secret to be loaded, got %q", config.Secret)
+	}
+
+	if config.TimeoutSec != 10 || config.MaxAttempts != 3 || config.RetryDelaySec != 5 {
+		t.Errorf("Expected default delivery settings, got %+v", config)
+	}
+}
+
+func TestLoadNotifierConfig_InvalidAttempts(t *testing.T) {
+	os.Setenv("WEBHOOK_MAX_ATTEMPTS", "0")
+	defer os.Unsetenv("WEBHOOK_MAX_ATTEMPTS")
+
+	_, err := loadNotifierConfig()
+	if err == nil {
+		t.Fatal("Expected error for zero max attempts")
+	}
+}
+
+func TestLoadClusterConfig_Default(t *testing.T) {
+	os.Unsetenv("INSTANCE_ID")
+	os.Unsetenv("CLUSTER_HEARTBEAT_INTERVAL_SEC")
+	os.Unsetenv("CLUSTER_INSTANCE_TTL_SEC")
+	os.Unsetenv("CLUSTER_ADVERTISE_URL")
+	os.Unsetenv("API_ADDR")
+
+	config, err := loadClusterConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	hostname, _ := os.Hostname()
+	if config.InstanceID != fmt.Sprintf("%s-%d", hostname, os.Getpid()) {
+		t.Errorf("Expected instance id from host name and pid, got %s", config.InstanceID)
+	}
+
+	if config.HeartbeatIntervalSec != 10 || config.InstanceTTLSec != 30 {
+		t.Errorf("Expected default heartbeat 10 and ttl 30, got %d and %d", config.HeartbeatIntervalSec, config.InstanceTTLSec)
+	}
+
+	if config.AdvertiseURL != "http://"+hostname+":8080" {
+		t.Errorf("Expected advertise url from host name and api port, got %s", config.AdvertiseURL)
+	}
+}
+
+func TestLoadClusterConfig_AdvertiseURL(t *testing.T) {
+	os.Setenv("CLUSTER_ADVERTISE_URL", "http://monitor-1:9090/")
+	defer os.Unsetenv("CLUSTER_ADVERTISE_URL")
+
+	config, err := loadClusterConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if config.AdvertiseURL != "http://monitor-1:9090" {
+		t.Errorf("Expected advertise url without trailing slash, got %s", config.AdvertiseURL)
+	}
+
+	os.Setenv("CLUSTER_ADVERTISE_URL", "monitor-1:9090")
+	if _, err := loadClusterConfig(); err == nil {
+		t.Error("Expected error for advertise url without scheme")
+	}
+}
+
+func TestLoadClusterConfig_TTLBelowTwoHeartbeats(t *testing.T) {
+	os.Setenv("INSTANCE_ID", "monitor-1")
+	os.Setenv("CLUSTER_HEARTBEAT_INTERVAL_SEC", "10")
+	os.Setenv("CLUSTER_INSTANCE_TTL_SEC", "15")
+	defer os.Unsetenv("INSTANCE_ID")
+	defer os.Unsetenv("CLUSTER_HEARTBEAT_INTERVAL_SEC")
+	defer os.Unsetenv("CLUSTER_INSTANCE_TTL_SEC")
+
+	_, err := loadClusterConfig()
+	if err == nil {
+		t.Fatal("Expected error for ttl below two heartbeat intervals")
+	}
+}
+
+func TestLoadApiConfig_Default(t *testing.T) {
+	os.Unsetenv("API_ADDR")
+
+	os.Unsetenv("AGENT_TOKENS")
+	os.Unsetenv("API_TOKEN")
+
+	config, err := loadApiConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if config.Addr != ":8080" || len(config.AgentTokens) != 0 || config.Token != "" {
+		t.Errorf("Expected default address :8080 without agents and token, got %s, %v and %q", config.Addr, config.AgentTokens, config.Token)
+	}
+}
+
+func TestLoadApiConfig_AgentTokens(t *testing.T) {
+	os.Setenv("AGENT_TOKENS", "eu-west:s3cret, us-east:other:secret")
+	defer os.Unsetenv("AGENT_TOKENS")
+
+	config, err := loadApiConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if config.AgentTokens["eu-west"] != "s3cret" || config.AgentTokens["us-east"] != "other:secret" {
+		t.Errorf("Expected tokens by location, got %v", config.AgentTokens)
+	}
+}
+
+func TestLoadApiConfig_InvalidAgentTokens(t *testing.T) {
+	for _, value := range []string{"eu-west", "eu-west:", "eu west:s3cret", "eu-west:a,eu-west:b"} {
+		os.Setenv("AGENT_TOKENS", value)
+
+		if _, err := loadApiConfig(); err == nil {
+			t.Errorf("Expected error for AGENT_TOKENS %q", value)
+		}
+	}
+	os.Unsetenv("AGENT_TOKENS")
+}
+
+func TestLoadAgent_Success(t *testing.T) {
+	os.Setenv("COLLECTOR_URL", "https://monitor.example.com/")
+	os.Setenv("LOCATION", "eu-west")
+	os.Setenv("AGENT_TOKEN", "s3cret")
+	defer os.Unsetenv("COLLECTOR_URL")
+	defer os.Unsetenv("LOCATION")
+	defer os.Unsetenv("AGENT_TOKEN")
+
+	config, err := LoadAgent()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := models.AgentConfig{CollectorURL: "https://monitor.example.com", Location: "eu-west", Token: "s3cret"}
+	if config.Agent != expected {
+		t.Errorf("Expected agent config %+v, got %+v", expected, config.Agent)
+	}
+
+	if config.Scheduler.Location != "eu-west" {
+		t.Errorf("Expected checks to run at eu-west, got %q", config.Scheduler.Location)
+	}
+}
+
+func TestLoadAgent_MissingToken(t *testing.T) {
+	os.Setenv("COLLECTOR_URL", "https://monitor.example.com")
+	os.Setenv("LOCATION", "eu-west")
+	os.Unsetenv("AGENT_TOKEN")
+	defer os.Unsetenv("COLLECTOR_URL")
+	defer os.Unsetenv("LOCATION")
+
+	if _, err := LoadAgent(); err == nil {
+		t.Fatal("Expected error for missing AGENT_TOKEN")
+	}
+}
+
+func TestMode(t *testing.T) {
+	tests := map[string]string{"": ModeMonitor, "monitor": ModeMonitor, "agent": ModeAgent}
+	for value, expected := range tests {
+		os.Setenv("MONITOR_MODE", value)
+
+		if mode, err := Mode(); err != nil || mode != expected {
+			t.Errorf("Expected mode %s for %q, got %s (%v)", expected, value, mode, err)
+		}
+	}
+
+	os.Setenv("MONITOR_MODE", "collector")
+	defer os.Unsetenv("MONITOR_MODE")
+
+	if _, err := Mode(); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+}
+
 func setTestEnvVars() {
 	os.Setenv("DB_HOST", "localhost")
 	os.Setenv("DB_PORT", "5432")
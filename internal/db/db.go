@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"website-monitor/internal/config"
@@ -56,6 +57,15 @@ func (db *DB) Exec(query string, args ...interface{}) error {
 	return nil
 }
 
+// ExecContext executes a query with parameters bound to the context and returns an error if it fails
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) error {
+	_, err := db.conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+	return nil
+}
+
 // Query executes a query and returns rows
 func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
 	rows, err := db.conn.Query(query, args...)
@@ -65,6 +75,41 @@ func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
 	return rows, nil
 }
 
+// QueryContext executes a query bound to the context and returns rows
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	return rows, nil
+}
+
+// QueryRowContext executes a query bound to the context that is expected to return at most one row.
+// Errors are deferred until the row is scanned
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.conn.QueryRowContext(ctx, query, args...)
+}
+
+// WithTx runs the function in a transaction, committing it if the function succeeds and rolling it back otherwise
+func (db *DB) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
 // GetUrl returns a database connection url for migration tools
 func GetUrl() (string, error) {
 	cfg, err := config.Load()
@@ -1,8 +1,13 @@
 package db
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"os"
 	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
 )
 
 func TestGetUrl_Success(t *testing.T) {
@@ -33,6 +38,50 @@ func TestGetUrl_MissingConfig(t *testing.T) {
 	}
 }
 
+func TestWithTx_Commit(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating mock db: %v", err)
+	}
+	defer sqlDB.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM monitored_urls`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = New(sqlDB).WithTx(context.Background(), func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM monitored_urls`)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet sqlmock expectations: %v", err)
+	}
+}
+
+func TestWithTx_RollbackOnError(t *testing.T) {
+	sqlDB, mock, _ := sqlmock.New()
+	defer sqlDB.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	failure := errors.New("failure")
+	err := New(sqlDB).WithTx(context.Background(), func(tx *sql.Tx) error {
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected function error, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet sqlmock expectations: %v", err)
+	}
+}
+
 func setTestEnvVars() {
 	os.Setenv("DB_HOST", "localhost")
 	os.Setenv("DB_PORT", "5432")
@@ -0,0 +1,43 @@
+package delivery_repository
+
+import (
+	"context"
+	"fmt"
+
+	"website-monitor/internal/db"
+	"website-monitor/internal/models"
+)
+
+// DbDeliveryRepository implements DeliveryRepository using database as the storage
+type DbDeliveryRepository struct {
+	db *db.DB
+}
+
+func New(database *db.DB) *DbDeliveryRepository {
+	return &DbDeliveryRepository{
+		db: database,
+	}
+}
+
+// InsertDelivery stores a single delivery attempt
+func (r *DbDeliveryRepository) InsertDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (endpoint, url_id, incident_id, payload, attempt, status_code, error, delivered_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	err := r.db.ExecContext(ctx, query,
+		delivery.Endpoint,
+		delivery.UrlID,
+		delivery.IncidentID,
+		delivery.Payload,
+		delivery.Attempt,
+		delivery.StatusCode,
+		delivery.Error,
+		delivery.DeliveredAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook delivery: %w", err)
+	}
+
+	return nil
+}
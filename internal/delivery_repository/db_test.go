@@ -0,0 +1,62 @@
+package delivery_repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"website-monitor/internal/db"
+	"website-monitor/internal/delivery_repository"
+	"website-monitor/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestInsertDelivery_HappyPath(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating mock db: %v", err)
+	}
+	defer sqlDB.Close()
+
+	incidentID := 3
+	status := 500
+	delivery := models.WebhookDelivery{
+		Endpoint:    "https://hooks.example.com",
+		UrlID:       1,
+		IncidentID:  &incidentID,
+		Payload:     []byte(`{"url":"https://example.com"}`),
+		Attempt:     2,
+		StatusCode:  &status,
+		Error:       "unexpected status code 500",
+		DeliveredAt: time.Now(),
+	}
+
+	mock.ExpectExec(`INSERT INTO webhook_deliveries`).
+		WithArgs(delivery.Endpoint, delivery.UrlID, delivery.IncidentID, delivery.Payload, delivery.Attempt, delivery.StatusCode, delivery.Error, delivery.DeliveredAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	repo := delivery_repository.New(db.New(sqlDB))
+
+	if err := repo.InsertDelivery(context.Background(), delivery); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet sqlmock expectations: %v", err)
+	}
+}
+
+func TestInsertDelivery_QueryError(t *testing.T) {
+	sqlDB, mock, _ := sqlmock.New()
+	defer sqlDB.Close()
+
+	mock.ExpectExec(`INSERT INTO webhook_deliveries`).WillReturnError(sql.ErrConnDone)
+
+	repo := delivery_repository.New(db.New(sqlDB))
+
+	if err := repo.InsertDelivery(context.Background(), models.WebhookDelivery{}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
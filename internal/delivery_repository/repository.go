@@ -0,0 +1,13 @@
+package delivery_repository
+
+import (
+	"context"
+
+	"website-monitor/internal/models"
+)
+
+// DeliveryRepository defines the interface for the webhook delivery log
+type DeliveryRepository interface {
+	// InsertDelivery stores a single delivery attempt
+	InsertDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+}
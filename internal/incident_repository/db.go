@@ -0,0 +1,83 @@
+package incident_repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"website-monitor/internal/db"
+	"website-monitor/internal/models"
+)
+
+// DbIncidentRepository implements IncidentRepository using database as the storage
+type DbIncidentRepository struct {
+	db *db.DB
+}
+
+func New(database *db.DB) *DbIncidentRepository {
+	return &DbIncidentRepository{
+		db: database,
+	}
+}
+
+// GetOpenIncidents returns all incidents that have not ended yet
+func (r *DbIncidentRepository) GetOpenIncidents(ctx context.Context) ([]models.Incident, error) {
+	query := `SELECT id, url_id, url, started_at, cause, first_check_id FROM incidents WHERE ended_at IS NULL`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query open incidents: %w", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var incidents []models.Incident
+	for rows.Next() {
+		var incident models.Incident
+		if err := rows.Scan(&incident.ID, &incident.UrlID, &incident.URL, &incident.StartedAt, &incident.Cause, &incident.FirstCheckID); err != nil {
+			return nil, fmt.Errorf("failed to scan incident: %w", err)
+		}
+		incidents = append(incidents, incident)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over incidents: %w", err)
+	}
+
+	return incidents, nil
+}
+
+// OpenIncident stores a new incident and returns its id
+func (r *DbIncidentRepository) OpenIncident(ctx context.Context, incident models.Incident) (int, error) {
+	query := `
+		INSERT INTO incidents (url_id, url, started_at, cause, first_check_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`
+
+	var id int
+	err := r.db.QueryRowContext(ctx, query,
+		incident.UrlID,
+		incident.URL,
+		incident.StartedAt,
+		incident.Cause,
+		incident.FirstCheckID).Scan(&id)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to open incident: %w", err)
+	}
+
+	return id, nil
+}
+
+// CloseIncident marks the incident as ended at the given time
+func (r *DbIncidentRepository) CloseIncident(ctx context.Context, id int, endedAt time.Time) error {
+	query := `UPDATE incidents SET ended_at = $1 WHERE id = $2`
+
+	if err := r.db.ExecContext(ctx, query, endedAt, id); err != nil {
+		return fmt.Errorf("failed to close incident %d: %w", id, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package incident_repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"website-monitor/internal/db"
+	"website-monitor/internal/incident_repository"
+	"website-monitor/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetOpenIncidents_HappyPath(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating mock db: %v", err)
+	}
+	defer sqlDB.Close()
+
+	startedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT id, url_id, url, started_at, cause, first_check_id FROM incidents WHERE ended_at IS NULL`).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "url_id", "url", "started_at", "cause", "first_check_id"}).
+				AddRow(7, 1, "https://example.com", startedAt, "connection refused", 42).
+				AddRow(8, 2, "https://google.com", startedAt, "regex did not match", nil),
+		)
+
+	repo := incident_repository.New(db.New(sqlDB))
+
+	incidents, err := repo.GetOpenIncidents(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(incidents) != 2 {
+		t.Fatalf("expected 2 incidents, got %d", len(incidents))
+	}
+
+	if incidents[0].ID != 7 || incidents[0].UrlID != 1 || incidents[0].FirstCheckID == nil || *incidents[0].FirstCheckID != 42 {
+		t.Errorf("unexpected first incident %+v", incidents[0])
+	}
+
+	if incidents[1].FirstCheckID != nil {
+		t.Errorf("expected no first check id, got %d", *incidents[1].FirstCheckID)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet sqlmock expectations: %v", err)
+	}
+}
+
+func TestOpenIncident_ReturnsId(t *testing.T) {
+	sqlDB, mock, _ := sqlmock.New()
+	defer sqlDB.Close()
+
+	firstCheckID := 42
+	incident := models.Incident{
+		UrlID:        1,
+		URL:          "https://example.com",
+		StartedAt:    time.Now(),
+		Cause:        "connection refused",
+		FirstCheckID: &firstCheckID,
+	}
+
+	mock.ExpectQuery(`INSERT INTO incidents`).
+		WithArgs(incident.UrlID, incident.URL, incident.StartedAt, incident.Cause, incident.FirstCheckID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+
+	repo := incident_repository.New(db.New(sqlDB))
+
+	id, err := repo.OpenIncident(context.Background(), incident)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if id != 9 {
+		t.Errorf("expected id 9, got %d", id)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet sqlmock expectations: %v", err)
+	}
+}
+
+func TestCloseIncident_QueryError(t *testing.T) {
+	sqlDB, mock, _ := sqlmock.New()
+	defer sqlDB.Close()
+
+	endedAt := time.Now()
+
+	mock.ExpectExec(`UPDATE incidents SET ended_at = \$1 WHERE id = \$2`).
+		WithArgs(endedAt, 9).
+		WillReturnError(sql.ErrConnDone)
+
+	repo := incident_repository.New(db.New(sqlDB))
+
+	if err := repo.CloseIncident(context.Background(), 9, endedAt); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet sqlmock expectations: %v", err)
+	}
+}
@@ -0,0 +1,18 @@
+package incident_repository
+
+import (
+	"context"
+	"time"
+
+	"website-monitor/internal/models"
+)
+
+// IncidentRepository defines the interface for incident storage
+type IncidentRepository interface {
+	// GetOpenIncidents returns all incidents that have not ended yet
+	GetOpenIncidents(ctx context.Context) ([]models.Incident, error)
+	// OpenIncident stores a new incident and returns its id
+	OpenIncident(ctx context.Context, incident models.Incident) (int, error)
+	// CloseIncident marks the incident as ended at the given time
+	CloseIncident(ctx context.Context, id int, endedAt time.Time) error
+}
@@ -0,0 +1,170 @@
+package instance_repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"website-monitor/internal/db"
+
+	"github.com/lib/pq"
+)
+
+// DbInstanceRepository implements InstanceRepository using database as the storage.
+// Heartbeats are compared with the clock of the database, so the clocks of the instances do not matter
+type DbInstanceRepository struct {
+	db *db.DB
+}
+
+func New(database *db.DB) *DbInstanceRepository {
+	return &DbInstanceRepository{
+		db: database,
+	}
+}
+
+// Heartbeat registers the instance with the address of its API or renews its registration
+func (r *DbInstanceRepository) Heartbeat(ctx context.Context, id, address string) error {
+	query := `
+		INSERT INTO monitor_instances (id, address) VALUES ($1, NULLIF($2, ''))
+		ON CONFLICT (id) DO UPDATE SET heartbeat_at = NOW(), address = EXCLUDED.address`
+
+	if err := r.db.ExecContext(ctx, query, id, address); err != nil {
+		return fmt.Errorf("failed to store heartbeat of instance %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// LiveInstances returns the ids of instances with a heartbeat younger than the ttl, ordered by id
+func (r *DbInstanceRepository) LiveInstances(ctx context.Context, ttl time.Duration) ([]string, error) {
+	query := `
+		SELECT id FROM monitor_instances
+		WHERE heartbeat_at > NOW() - $1 * INTERVAL '1 millisecond'
+		ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, ttl.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query live instances: %w", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan instance: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over instances: %w", err)
+	}
+
+	return ids, nil
+}
+
+// PruneInstances removes instances without a heartbeat younger than the ttl
+func (r *DbInstanceRepository) PruneInstances(ctx context.Context, ttl time.Duration) error {
+	query := `DELETE FROM monitor_instances WHERE heartbeat_at <= NOW() - $1 * INTERVAL '1 millisecond'`
+
+	if err := r.db.ExecContext(ctx, query, ttl.Milliseconds()); err != nil {
+		return fmt.Errorf("failed to prune instances: %w", err)
+	}
+
+	return nil
+}
+
+// Deregister removes the instance, so the others take over its urls without waiting for the ttl
+func (r *DbInstanceRepository) Deregister(ctx context.Context, id string) error {
+	if err := r.db.ExecContext(ctx, `DELETE FROM monitor_instances WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to deregister instance %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// AcquireLeases renews the leases of the buckets held by the instance and takes the buckets whose lease is free or expired.
+// It returns the buckets the instance holds now, leases of other instances are left alone
+func (r *DbInstanceRepository) AcquireLeases(ctx context.Context, id string, buckets []int, ttl time.Duration) ([]int, error) {
+	if len(buckets) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]int64, 0, len(buckets))
+	for _, bucket := range buckets {
+		keys = append(keys, int64(bucket))
+	}
+
+	query := `
+		INSERT INTO cluster_leases (bucket, instance_id, expires_at)
+		SELECT bucket, $1, NOW() + $3 * INTERVAL '1 millisecond' FROM UNNEST($2::INT[]) AS bucket
+		ON CONFLICT (bucket) DO UPDATE SET instance_id = EXCLUDED.instance_id, expires_at = EXCLUDED.expires_at
+		WHERE cluster_leases.instance_id = EXCLUDED.instance_id OR cluster_leases.expires_at <= NOW()
+		RETURNING bucket`
+
+	rows, err := r.db.QueryContext(ctx, query, id, pq.Array(keys), ttl.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire leases of instance %s: %w", id, err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var held []int
+	for rows.Next() {
+		var bucket int
+		if err := rows.Scan(&bucket); err != nil {
+			return nil, fmt.Errorf("failed to scan lease: %w", err)
+		}
+		held = append(held, bucket)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over leases: %w", err)
+	}
+
+	return held, nil
+}
+
+// ReleaseLeases gives up the leases of the instance on the buckets, so other instances can take them
+func (r *DbInstanceRepository) ReleaseLeases(ctx context.Context, id string, buckets []int) error {
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	keys := make([]int64, 0, len(buckets))
+	for _, bucket := range buckets {
+		keys = append(keys, int64(bucket))
+	}
+
+	query := `DELETE FROM cluster_leases WHERE instance_id = $1 AND bucket = ANY($2)`
+	if err := r.db.ExecContext(ctx, query, id, pq.Array(keys)); err != nil {
+		return fmt.Errorf("failed to release leases of instance %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// LeaseHolder returns the address of the instance holding the unexpired lease of the bucket, or "" if nobody holds it
+func (r *DbInstanceRepository) LeaseHolder(ctx context.Context, bucket int) (string, error) {
+	query := `
+		SELECT COALESCE(i.address, '') FROM cluster_leases l
+		JOIN monitor_instances i ON i.id = l.instance_id
+		WHERE l.bucket = $1 AND l.expires_at > NOW()`
+
+	var address string
+	err := r.db.QueryRowContext(ctx, query, bucket).Scan(&address)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query holder of bucket %d: %w", bucket, err)
+	}
+
+	return address, nil
+}
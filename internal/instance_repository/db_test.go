@@ -0,0 +1,186 @@
+package instance_repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"website-monitor/internal/db"
+	"website-monitor/internal/instance_repository"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestHeartbeat_HappyPath(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating mock db: %v", err)
+	}
+	defer sqlDB.Close()
+
+	mock.ExpectExec(`INSERT INTO monitor_instances \(id, address\) VALUES \(\$1, NULLIF\(\$2, ''\)\)\s+ON CONFLICT \(id\) DO UPDATE SET heartbeat_at = NOW\(\), address = EXCLUDED.address`).
+		WithArgs("monitor-1", "http://monitor-1:8080").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := instance_repository.New(db.New(sqlDB))
+
+	if err := repo.Heartbeat(context.Background(), "monitor-1", "http://monitor-1:8080"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet sqlmock expectations: %v", err)
+	}
+}
+
+func TestLiveInstances_HappyPath(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating mock db: %v", err)
+	}
+	defer sqlDB.Close()
+
+	mock.ExpectQuery(`SELECT id FROM monitor_instances\s+WHERE heartbeat_at > NOW\(\) - \$1 \* INTERVAL '1 millisecond'\s+ORDER BY id`).
+		WithArgs(int64(30000)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("monitor-1").AddRow("monitor-2"))
+
+	repo := instance_repository.New(db.New(sqlDB))
+
+	ids, err := repo.LiveInstances(context.Background(), 30*time.Second)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(ids) != 2 || ids[0] != "monitor-1" || ids[1] != "monitor-2" {
+		t.Errorf("unexpected instances %v", ids)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet sqlmock expectations: %v", err)
+	}
+}
+
+func TestLiveInstances_QueryError(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating mock db: %v", err)
+	}
+	defer sqlDB.Close()
+
+	mock.ExpectQuery(`SELECT id FROM monitor_instances`).WillReturnError(sql.ErrConnDone)
+
+	repo := instance_repository.New(db.New(sqlDB))
+
+	if _, err := repo.LiveInstances(context.Background(), 30*time.Second); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestPruneAndDeregister(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating mock db: %v", err)
+	}
+	defer sqlDB.Close()
+
+	mock.ExpectExec(`DELETE FROM monitor_instances WHERE heartbeat_at <= NOW\(\) - \$1 \* INTERVAL '1 millisecond'`).
+		WithArgs(int64(30000)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`DELETE FROM monitor_instances WHERE id = \$1`).
+		WithArgs("monitor-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := instance_repository.New(db.New(sqlDB))
+
+	if err := repo.PruneInstances(context.Background(), 30*time.Second); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := repo.Deregister(context.Background(), "monitor-1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet sqlmock expectations: %v", err)
+	}
+}
+
+func TestAcquireLeases_ReturnsHeldBuckets(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating mock db: %v", err)
+	}
+	defer sqlDB.Close()
+
+	mock.ExpectQuery(`INSERT INTO cluster_leases \(bucket, instance_id, expires_at\)\s+SELECT bucket, \$1, NOW\(\) \+ \$3 \* INTERVAL '1 millisecond' FROM UNNEST\(\$2::INT\[\]\) AS bucket\s+ON CONFLICT \(bucket\) DO UPDATE SET instance_id = EXCLUDED.instance_id, expires_at = EXCLUDED.expires_at\s+WHERE cluster_leases.instance_id = EXCLUDED.instance_id OR cluster_leases.expires_at <= NOW\(\)\s+RETURNING bucket`).
+		WithArgs("monitor-1", "{1,2,3}", int64(30000)).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket"}).AddRow(1).AddRow(3))
+
+	repo := instance_repository.New(db.New(sqlDB))
+
+	held, err := repo.AcquireLeases(context.Background(), "monitor-1", []int{1, 2, 3}, 30*time.Second)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(held) != 2 || held[0] != 1 || held[1] != 3 {
+		t.Errorf("unexpected held buckets %v", held)
+	}
+
+	if held, err := repo.AcquireLeases(context.Background(), "monitor-1", nil, 30*time.Second); err != nil || held != nil {
+		t.Errorf("expected no query without buckets, got %v, %v", held, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet sqlmock expectations: %v", err)
+	}
+}
+
+func TestReleaseLeases_HappyPath(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating mock db: %v", err)
+	}
+	defer sqlDB.Close()
+
+	mock.ExpectExec(`DELETE FROM cluster_leases WHERE instance_id = \$1 AND bucket = ANY\(\$2\)`).
+		WithArgs("monitor-1", "{4,5}").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	repo := instance_repository.New(db.New(sqlDB))
+
+	if err := repo.ReleaseLeases(context.Background(), "monitor-1", []int{4, 5}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet sqlmock expectations: %v", err)
+	}
+}
+
+func TestLeaseHolder(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("unexpected error creating mock db: %v", err)
+	}
+	defer sqlDB.Close()
+
+	query := `SELECT COALESCE\(i.address, ''\) FROM cluster_leases l\s+JOIN monitor_instances i ON i.id = l.instance_id\s+WHERE l.bucket = \$1 AND l.expires_at > NOW\(\)`
+	mock.ExpectQuery(query).WithArgs(7).WillReturnRows(sqlmock.NewRows([]string{"address"}).AddRow("http://monitor-2:8080"))
+	mock.ExpectQuery(query).WithArgs(8).WillReturnRows(sqlmock.NewRows([]string{"address"}))
+
+	repo := instance_repository.New(db.New(sqlDB))
+
+	if address, err := repo.LeaseHolder(context.Background(), 7); err != nil || address != "http://monitor-2:8080" {
+		t.Errorf("expected holder of bucket 7, got %q, %v", address, err)
+	}
+
+	if address, err := repo.LeaseHolder(context.Background(), 8); err != nil || address != "" {
+		t.Errorf("expected no holder of bucket 8, got %q, %v", address, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet sqlmock expectations: %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package instance_repository
+
+import (
+	"context"
+	"time"
+)
+
+// InstanceRepository defines the interface for the registry of running monitor instances
+type InstanceRepository interface {
+	// Heartbeat registers the instance with the address of its API or renews its registration
+	Heartbeat(ctx context.Context, id, address string) error
+	// LiveInstances returns the ids of instances with a heartbeat younger than the ttl, ordered by id
+	LiveInstances(ctx context.Context, ttl time.Duration) ([]string, error)
+	// PruneInstances removes instances without a heartbeat younger than the ttl
+	PruneInstances(ctx context.Context, ttl time.Duration) error
+	// Deregister removes the instance, so the others take over its urls without waiting for the ttl
+	Deregister(ctx context.Context, id string) error
+	// AcquireLeases renews the leases of the buckets held by the instance and takes the buckets whose lease is free or expired.
+	// It returns the buckets the instance holds now, leases of other instances are left alone
+	AcquireLeases(ctx context.Context, id string, buckets []int, ttl time.Duration) ([]int, error)
+	// ReleaseLeases gives up the leases of the instance on the buckets, so other instances can take them
+	ReleaseLeases(ctx context.Context, id string, buckets []int) error
+	// LeaseHolder returns the address of the instance holding the unexpired lease of the bucket, or "" if nobody holds it
+	LeaseHolder(ctx context.Context, bucket int) (string, error)
+}
@@ -0,0 +1,229 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"website-monitor/internal/models"
+)
+
+const namespace = "website_monitor"
+
+// states lists every state exposed by the state gauge
+var states = []models.State{models.StateUnknown, models.StateUp, models.StateDegraded, models.StateDown}
+
+// StateSource provides the current state of monitored urls
+type StateSource interface {
+	State(urlID int) models.State
+}
+
+// Metrics exposes check results and scheduler internals in the Prometheus format. Check results are labeled with
+// the location they were checked from, empty for checks of the monitor itself. The up and state series are not,
+// as the state of a url is combined from all its locations
+type Metrics struct {
+	registry *prometheus.Registry
+	states   StateSource
+
+	lastStatus         *prometheus.GaugeVec
+	responseTime       *prometheus.HistogramVec
+	regexMatch         *prometheus.GaugeVec
+	up                 *prometheus.GaugeVec
+	state              *prometheus.GaugeVec
+	lastCheckTimestamp *prometheus.GaugeVec
+	certExpiry         *prometheus.GaugeVec
+	checks             *prometheus.CounterVec
+	skippedChecks      *prometheus.CounterVec
+
+	checksInFlight  prometheus.Gauge
+	insertFailures  prometheus.Counter
+	monitorsRunning prometheus.Gauge
+}
+
+// New creates metrics registered in their own registry. The state source is optional
+func New(stateSource StateSource) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		states:   stateSource,
+		lastStatus: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_http_status",
+			Help:      "HTTP status code of the last check, 0 if the request failed.",
+		}, []string{"url", "location"}),
+		responseTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "response_time_seconds",
+			Help:      "Response time of checks.",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"url", "location"}),
+		regexMatch: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "regex_match",
+			Help:      "Whether the last check matched the regex pattern of the url (1) or not (0).",
+		}, []string{"url", "location"}),
+		up: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "up",
+			Help:      "Whether the url is down (0) or not (1).",
+		}, []string{"url"}),
+		state: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "state",
+			Help:      "Current state of the url, 1 for the active state and 0 for the others.",
+		}, []string{"url", "state"}),
+		lastCheckTimestamp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_check_timestamp_seconds",
+			Help:      "Unix time of the last check.",
+		}, []string{"url", "location"}),
+		certExpiry: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "tls_cert_expiry_timestamp_seconds",
+			Help:      "Unix time at which the earliest expiring certificate presented by the url expires.",
+		}, []string{"url", "location"}),
+		checks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "checks_total",
+			Help:      "Number of performed checks by result.",
+		}, []string{"url", "location", "result"}),
+		skippedChecks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "checks_skipped_total",
+			Help:      "Number of due checks skipped because previous checks of the url were still running.",
+		}, []string{"url", "location"}),
+		checksInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "checks_in_flight",
+			Help:      "Number of checks currently being performed.",
+		}),
+		insertFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "check_insert_failures_total",
+			Help:      "Number of check results that could not be stored.",
+		}),
+		monitorsRunning: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "monitors_running",
+			Help:      "Number of running url monitors of the scheduler.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.lastStatus,
+		m.responseTime,
+		m.regexMatch,
+		m.up,
+		m.state,
+		m.lastCheckTimestamp,
+		m.certExpiry,
+		m.checks,
+		m.skippedChecks,
+		m.checksInFlight,
+		m.insertFailures,
+		m.monitorsRunning,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Handler returns the HTTP handler serving the metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Observe records a check result. When used together with a state tracker, it must be registered after the tracker
+func (m *Metrics) Observe(ctx context.Context, url models.MonitoredUrl, result models.CheckResult) error {
+	status := 0
+	if result.HttpStatus != nil {
+		status = *result.HttpStatus
+	}
+	m.lastStatus.WithLabelValues(url.Url, result.Location).Set(float64(status))
+
+	if result.ResponseTimeMs != nil {
+		m.responseTime.WithLabelValues(url.Url, result.Location).Observe(float64(*result.ResponseTimeMs) / 1000)
+	}
+
+	if result.RegexMatch != nil {
+		m.regexMatch.WithLabelValues(url.Url, result.Location).Set(boolToFloat(*result.RegexMatch))
+	}
+
+	m.lastCheckTimestamp.WithLabelValues(url.Url, result.Location).Set(float64(result.CheckTimestamp.Unix()))
+
+	if result.CertExpiresAt != nil {
+		m.certExpiry.WithLabelValues(url.Url, result.Location).Set(float64(result.CertExpiresAt.Unix()))
+	}
+
+	outcome := "success"
+	if result.Failed() {
+		outcome = "failure"
+	}
+	m.checks.WithLabelValues(url.Url, result.Location, outcome).Inc()
+
+	if m.states != nil {
+		current := m.states.State(url.ID)
+		m.up.WithLabelValues(url.Url).Set(boolToFloat(current != models.StateDown))
+		for _, s := range states {
+			m.state.WithLabelValues(url.Url, string(s)).Set(boolToFloat(s == current))
+		}
+	}
+
+	return nil
+}
+
+// MonitorStarted counts a started url monitor
+func (m *Metrics) MonitorStarted(url models.MonitoredUrl) {
+	m.monitorsRunning.Inc()
+}
+
+// MonitorStopped counts a stopped url monitor. The series of the url are kept, as a stopped monitor is restarted
+// when the configuration of its url changes
+func (m *Metrics) MonitorStopped(url models.MonitoredUrl) {
+	m.monitorsRunning.Dec()
+}
+
+// UrlRemoved drops the series of the url at all locations, so removed urls do not linger
+func (m *Metrics) UrlRemoved(url models.MonitoredUrl) {
+	labels := prometheus.Labels{"url": url.Url}
+	m.lastStatus.DeletePartialMatch(labels)
+	m.responseTime.DeletePartialMatch(labels)
+	m.regexMatch.DeletePartialMatch(labels)
+	m.up.DeletePartialMatch(labels)
+	m.state.DeletePartialMatch(labels)
+	m.lastCheckTimestamp.DeletePartialMatch(labels)
+	m.certExpiry.DeletePartialMatch(labels)
+	m.checks.DeletePartialMatch(labels)
+	m.skippedChecks.DeletePartialMatch(labels)
+}
+
+// CheckStarted counts a check in flight
+func (m *Metrics) CheckStarted() {
+	m.checksInFlight.Inc()
+}
+
+// CheckFinished counts a finished check
+func (m *Metrics) CheckFinished() {
+	m.checksInFlight.Dec()
+}
+
+// CheckSkipped counts due checks of the url at the location that were skipped
+func (m *Metrics) CheckSkipped(url models.MonitoredUrl, location string, count int) {
+	m.skippedChecks.WithLabelValues(url.Url, location).Add(float64(count))
+}
+
+// InsertFailed counts a check result that could not be stored
+func (m *Metrics) InsertFailed() {
+	m.insertFailures.Inc()
+}
+
+func boolToFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+
+	return 0
+}
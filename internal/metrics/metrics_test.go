@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"website-monitor/internal/models"
+)
+
+type mockStateSource struct {
+	state models.State
+}
+
+func (m *mockStateSource) State(urlID int) models.State {
+	return m.state
+}
+
+var testUrl = models.MonitoredUrl{ID: 1, Url: "https://example.com", CheckIntervalSec: 10, RegexPattern: "Example"}
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatalf("failed to read metrics: %v", err)
+	}
+
+	return string(body)
+}
+
+func TestMetrics_Observe_ExposesCheckResult(t *testing.T) {
+	m := New(&mockStateSource{state: models.StateDown})
+
+	status := 503
+	responseTime := 250
+	match := false
+	certExpiry := time.Unix(1800000000, 0)
+	result := models.CheckResult{
+		URL:            testUrl.Url,
+		CheckTimestamp: time.Unix(1700000000, 0),
+		HttpStatus:     &status,
+		ResponseTimeMs: &responseTime,
+		RegexMatch:     &match,
+		CertExpiresAt:  &certExpiry,
+	}
+
+	if err := m.Observe(context.Background(), testUrl, result); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	body := scrape(t, m)
+
+	expected := []string{
+		`website_monitor_last_http_status{location="",url="https://example.com"} 503`,
+		`website_monitor_response_time_seconds_count{location="",url="https://example.com"} 1`,
+		`website_monitor_regex_match{location="",url="https://example.com"} 0`,
+		`website_monitor_up{url="https://example.com"} 0`,
+		`website_monitor_state{state="DOWN",url="https://example.com"} 1`,
+		`website_monitor_state{state="UP",url="https://example.com"} 0`,
+		`website_monitor_last_check_timestamp_seconds{location="",url="https://example.com"} 1.7e+09`,
+		`website_monitor_tls_cert_expiry_timestamp_seconds{location="",url="https://example.com"} 1.8e+09`,
+		`website_monitor_checks_total{location="",result="failure",url="https://example.com"} 1`,
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected metrics to contain %q", line)
+		}
+	}
+}
+
+func TestMetrics_Observe_LabelsLocations(t *testing.T) {
+	m := New(&mockStateSource{state: models.StateDegraded})
+	url := testUrl
+	url.Locations = []string{"eu-west", "us-east"}
+
+	ok, failed := 200, 500
+	_ = m.Observe(context.Background(), url, models.CheckResult{URL: url.Url, Location: "eu-west", HttpStatus: &ok})
+	_ = m.Observe(context.Background(), url, models.CheckResult{URL: url.Url, Location: "us-east", HttpStatus: &failed, Error: "unexpected status 500"})
+
+	body := scrape(t, m)
+
+	expected := []string{
+		`website_monitor_last_http_status{location="eu-west",url="https://example.com"} 200`,
+		`website_monitor_last_http_status{location="us-east",url="https://example.com"} 500`,
+		`website_monitor_checks_total{location="eu-west",result="success",url="https://example.com"} 1`,
+		`website_monitor_checks_total{location="us-east",result="failure",url="https://example.com"} 1`,
+		`website_monitor_up{url="https://example.com"} 1`,
+		`website_monitor_state{state="DEGRADED",url="https://example.com"} 1`,
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected metrics to contain %q", line)
+		}
+	}
+}
+
+func TestMetrics_SchedulerEvents(t *testing.T) {
+	m := New(nil)
+
+	m.MonitorStarted(testUrl)
+	m.CheckStarted()
+	m.CheckSkipped(testUrl, "eu-west", 3)
+	m.InsertFailed()
+
+	body := scrape(t, m)
+
+	expected := []string{
+		`website_monitor_monitors_running 1`,
+		`website_monitor_checks_in_flight 1`,
+		`website_monitor_check_insert_failures_total 1`,
+		`website_monitor_checks_skipped_total{location="eu-west",url="https://example.com"} 3`,
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected metrics to contain %q", line)
+		}
+	}
+}
+
+func TestMetrics_MonitorStopped_KeepsUrlSeries(t *testing.T) {
+	m := New(nil)
+
+	status := 200
+	m.MonitorStarted(testUrl)
+	_ = m.Observe(context.Background(), testUrl, models.CheckResult{URL: testUrl.Url, HttpStatus: &status})
+	m.CheckSkipped(testUrl, "", 1)
+	m.MonitorStopped(testUrl)
+
+	body := scrape(t, m)
+
+	if !strings.Contains(body, `website_monitor_checks_skipped_total{location="",url="https://example.com"} 1`) {
+		t.Error("Expected series of a restarted url to be kept")
+	}
+
+	if !strings.Contains(body, `website_monitor_monitors_running 0`) {
+		t.Error("Expected no running monitors")
+	}
+}
+
+func TestMetrics_UrlRemoved_DropsSeriesOfAllLocations(t *testing.T) {
+	m := New(nil)
+
+	status := 200
+	_ = m.Observe(context.Background(), testUrl, models.CheckResult{URL: testUrl.Url, HttpStatus: &status})
+	_ = m.Observe(context.Background(), testUrl, models.CheckResult{URL: testUrl.Url, HttpStatus: &status, Location: "eu-west"})
+	m.CheckSkipped(testUrl, "eu-west", 1)
+	m.UrlRemoved(testUrl)
+
+	if body := scrape(t, m); strings.Contains(body, `url="https://example.com"`) {
+		t.Error("Expected series of removed url to be dropped")
+	}
+}
@@ -0,0 +1,5 @@
+ALTER TABLE monitored_urls
+    ADD COLUMN method TEXT NOT NULL DEFAULT 'GET',
+    ADD COLUMN headers JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN body TEXT,
+    ADD COLUMN expected_status_codes TEXT;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE incidents (
+    id SERIAL PRIMARY KEY,
+    url_id INT NOT NULL REFERENCES monitored_urls(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL,
+    ended_at TIMESTAMPTZ,
+    cause TEXT NOT NULL,
+    first_check_id INT REFERENCES checks(id) ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX incidents_open_url_id_idx ON incidents (url_id) WHERE ended_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    endpoint TEXT NOT NULL,
+    url_id INT NOT NULL,
+    incident_id INT REFERENCES incidents(id) ON DELETE SET NULL,
+    payload JSONB NOT NULL,
+    attempt INT NOT NULL,
+    status_code INT,
+    error TEXT,
+    delivered_at TIMESTAMPTZ NOT NULL
+);
\ No newline at end of file
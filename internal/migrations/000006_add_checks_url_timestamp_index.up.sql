@@ -0,0 +1 @@
+CREATE INDEX checks_url_check_timestamp_idx ON checks (url, check_timestamp DESC);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE monitored_urls ADD COLUMN paused BOOLEAN NOT NULL DEFAULT false;
\ No newline at end of file
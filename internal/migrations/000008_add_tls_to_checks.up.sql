@@ -0,0 +1,5 @@
+ALTER TABLE checks
+    ADD COLUMN tls_certificates JSONB,
+    ADD COLUMN tls_expires_at TIMESTAMPTZ,
+    ADD COLUMN tls_days_remaining INT,
+    ADD COLUMN warning TEXT;
\ No newline at end of file
@@ -0,0 +1,6 @@
+ALTER TABLE checks
+    ADD COLUMN dns_ms INT,
+    ADD COLUMN connect_ms INT,
+    ADD COLUMN tls_handshake_ms INT,
+    ADD COLUMN ttfb_ms INT,
+    ADD COLUMN transfer_ms INT;
\ No newline at end of file
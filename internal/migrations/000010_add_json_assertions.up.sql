@@ -0,0 +1,3 @@
+ALTER TABLE monitored_urls ADD COLUMN json_assertions JSONB NOT NULL DEFAULT '[]';
+
+ALTER TABLE checks ADD COLUMN assertion_results JSONB;
\ No newline at end of file
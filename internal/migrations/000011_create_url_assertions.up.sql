@@ -0,0 +1,11 @@
+CREATE TABLE url_assertions (
+    id SERIAL PRIMARY KEY,
+    url_id INT NOT NULL REFERENCES monitored_urls(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('contains', 'not_contains', 'regex', 'body_size', 'header_equals', 'header_matches')),
+    header TEXT,
+    value TEXT,
+    min_bytes INT,
+    max_bytes INT,
+    UNIQUE (url_id, position)
+);
\ No newline at end of file
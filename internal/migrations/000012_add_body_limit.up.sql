@@ -0,0 +1,3 @@
+ALTER TABLE monitored_urls ADD COLUMN max_body_bytes INT;
+
+ALTER TABLE checks ADD COLUMN body_truncated BOOLEAN NOT NULL DEFAULT false;
\ No newline at end of file
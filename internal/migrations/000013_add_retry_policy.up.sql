@@ -0,0 +1,8 @@
+ALTER TABLE monitored_urls
+    ADD COLUMN retry_attempts INT NOT NULL DEFAULT 0 CHECK (retry_attempts BETWEEN 0 AND 5),
+    ADD COLUMN retry_delay_ms INT NOT NULL DEFAULT 0 CHECK (retry_delay_ms BETWEEN 0 AND 30000),
+    ADD COLUMN retry_on TEXT;
+
+ALTER TABLE checks
+    ADD COLUMN attempts INT NOT NULL DEFAULT 1,
+    ADD COLUMN attempt_errors JSONB;
\ No newline at end of file
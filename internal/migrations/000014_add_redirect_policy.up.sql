@@ -0,0 +1,5 @@
+ALTER TABLE monitored_urls
+    ADD COLUMN redirect_policy TEXT CHECK (redirect_policy IN ('follow', 'none', 'same_host')),
+    ADD COLUMN max_redirects INT CHECK (max_redirects BETWEEN 1 AND 20);
+
+ALTER TABLE checks ADD COLUMN redirects JSONB;
\ No newline at end of file
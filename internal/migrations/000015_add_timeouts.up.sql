@@ -0,0 +1,3 @@
+ALTER TABLE monitored_urls
+    ADD COLUMN timeout_ms INT CHECK (timeout_ms > 0),
+    ADD COLUMN slow_threshold_ms INT CHECK (slow_threshold_ms > 0);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE monitored_urls ADD COLUMN auth JSONB;
\ No newline at end of file
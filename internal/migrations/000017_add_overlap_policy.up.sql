@@ -0,0 +1,5 @@
+ALTER TABLE monitored_urls
+    ADD COLUMN overlap_policy TEXT CHECK (overlap_policy IN ('skip', 'queue', 'concurrent')),
+    ADD COLUMN max_concurrent INT CHECK (max_concurrent BETWEEN 1 AND 10);
+
+ALTER TABLE checks ADD COLUMN skipped BOOLEAN NOT NULL DEFAULT FALSE;
\ No newline at end of file
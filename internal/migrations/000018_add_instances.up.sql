@@ -0,0 +1,5 @@
+CREATE TABLE monitor_instances (
+    id TEXT PRIMARY KEY,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
\ No newline at end of file
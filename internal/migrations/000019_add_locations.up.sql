@@ -0,0 +1,5 @@
+ALTER TABLE monitored_urls
+    ADD COLUMN locations TEXT[],
+    ADD COLUMN quorum INT CHECK (quorum >= 1);
+
+ALTER TABLE checks ADD COLUMN location TEXT;
\ No newline at end of file
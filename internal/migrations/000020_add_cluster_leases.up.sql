@@ -0,0 +1,5 @@
+CREATE TABLE cluster_leases (
+    bucket INT PRIMARY KEY,
+    instance_id TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE monitor_instances ADD COLUMN address TEXT;
\ No newline at end of file
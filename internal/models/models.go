@@ -1,12 +1,20 @@
 package models
 
 import (
+	"regexp"
 	"time"
 )
 
 // Config represents the application configuration
 type Config struct {
-	Database DatabaseConfig `json:"database"`
+	Database  DatabaseConfig  `json:"database"`
+	Scheduler SchedulerConfig `json:"scheduler"`
+	State     StateConfig     `json:"state"`
+	Notifier  NotifierConfig  `json:"notifier"`
+	Api       ApiConfig       `json:"api"`
+	Checker   CheckerConfig   `json:"checker"`
+	Cluster   ClusterConfig   `json:"cluster"`
+	Agent     AgentConfig     `json:"agent"`
 }
 
 // DatabaseConfig holds database connection parameters
@@ -19,12 +27,138 @@ type DatabaseConfig struct {
 	SSLMode  string `json:"ssl_mode"`
 }
 
+// SchedulerConfig holds scheduler tuning parameters
+type SchedulerConfig struct {
+	// ReloadIntervalSec is how often monitored urls are re-read from the repository, 0 disables reloading
+	ReloadIntervalSec int `json:"reload_interval_sec"`
+	// Workers is the number of checks that run at the same time
+	Workers int `json:"workers"`
+	// PerHostLimit is the number of checks of the same host that run at the same time, 0 disables the limit
+	PerHostLimit int `json:"per_host_limit"`
+	// JitterPercent delays every check by a random share of up to this percentage of its interval, 0 disables jitter
+	JitterPercent int `json:"jitter_percent"`
+	// Location is where the checks run, urls with locations are only checked if it is one of them
+	Location string `json:"location"`
+}
+
+// ClusterConfig holds settings of the coordination between monitor instances sharing the database
+type ClusterConfig struct {
+	// InstanceID identifies this instance among the others, it has to be unique
+	InstanceID string `json:"instance_id"`
+	// HeartbeatIntervalSec is how often the instance renews its registration and looks for joined or dead instances
+	HeartbeatIntervalSec int `json:"heartbeat_interval_sec"`
+	// InstanceTTLSec is how long an instance without a heartbeat is considered alive, its urls move to the others after that
+	InstanceTTLSec int `json:"instance_ttl_sec"`
+	// AdvertiseURL is the base url the other instances reach the API of this instance at, they forward agent results to it
+	AdvertiseURL string `json:"advertise_url"`
+}
+
+// AgentConfig holds the settings of a monitor running as an agent, which pushes its results to a collector
+type AgentConfig struct {
+	// CollectorURL is the address of the API of the monitor collecting the results
+	CollectorURL string `json:"collector_url"`
+	// Location is where the agent runs, it authenticates as the agent of that location with Token
+	Location string `json:"location"`
+	Token    string `json:"-"`
+}
+
+// CheckerConfig holds settings of the HTTP checks
+type CheckerConfig struct {
+	// TLSExpiryWarningDays and TLSExpiryCriticalDays are the days before certificate expiry from which checks report a warning
+	TLSExpiryWarningDays  int `json:"tls_expiry_warning_days"`
+	TLSExpiryCriticalDays int `json:"tls_expiry_critical_days"`
+}
+
+// StateConfig holds thresholds of the up/down state machine
+type StateConfig struct {
+	// FailureThreshold is the number of consecutive failed checks after which a url is considered down
+	FailureThreshold int `json:"failure_threshold"`
+	// RecoveryThreshold is the number of consecutive successful checks after which a down url is considered up again
+	RecoveryThreshold int `json:"recovery_threshold"`
+}
+
+// NotifierConfig holds webhook notification parameters
+type NotifierConfig struct {
+	WebhookURLs []string `json:"webhook_urls"`
+	// Secret is used to sign webhook payloads with HMAC-SHA256, payloads are not signed if empty
+	Secret        string `json:"-"`
+	TimeoutSec    int    `json:"timeout_sec"`
+	MaxAttempts   int    `json:"max_attempts"`
+	RetryDelaySec int    `json:"retry_delay_sec"`
+}
+
+// ApiConfig holds HTTP API parameters
+type ApiConfig struct {
+	Addr string `json:"addr"`
+	// Token must be sent to change urls through the API, urls cannot be changed through it if empty
+	Token string `json:"-"`
+	// AgentTokens holds the token of the agent at every location, agents are not accepted if empty
+	AgentTokens map[string]string `json:"-"`
+}
+
 // MonitoredUrl represents a url to be monitored
 type MonitoredUrl struct {
-	ID               int    `json:"id"`
+	ID               int    `json:"id,omitempty"`
 	Url              string `json:"url"`
 	CheckIntervalSec int    `json:"check_interval_sec"`
 	RegexPattern     string `json:"regex_pattern,omitempty"`
+	// Method is the HTTP method of the check request, GET if empty
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	// ExpectedStatusCodes lists acceptable status codes and ranges, e.g. "200,201,300-399". Any status is accepted if empty
+	ExpectedStatusCodes string `json:"expected_status_codes,omitempty"`
+	// JSONAssertions are evaluated against the response body parsed as JSON, the check fails if any of them fails
+	JSONAssertions []JSONAssertion `json:"json_assertions,omitempty"`
+	// Assertions check the body and headers of the response, the check fails if any of them fails
+	Assertions []ContentAssertion `json:"assertions,omitempty"`
+	// MaxBodyBytes is how much of the response body is scanned by the regex and content assertions, 0 uses the default of 64KB
+	MaxBodyBytes int `json:"max_body_bytes,omitempty"`
+	// RetryAttempts is how often a failing check is attempted before the failure is recorded, 0 and 1 mean no retries
+	RetryAttempts int `json:"retry_attempts,omitempty"`
+	// RetryDelayMs is the delay between attempts
+	RetryDelayMs int `json:"retry_delay_ms,omitempty"`
+	// RetryOn lists which failures are retried, e.g. "network,timeout,502-504". Network errors and timeouts if empty
+	RetryOn string `json:"retry_on,omitempty"`
+	// RedirectPolicy is follow, none or same_host, redirects are followed to any host if empty
+	RedirectPolicy string `json:"redirect_policy,omitempty"`
+	// MaxRedirects is how many redirects are followed before the check fails, 0 uses the default of 10
+	MaxRedirects int `json:"max_redirects,omitempty"`
+	// TimeoutMs bounds a single attempt including the body, 0 uses 30 seconds or the check interval if that is shorter
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+	// SlowThresholdMs is the response time above which a successful check degrades the url, 0 disables it
+	SlowThresholdMs int `json:"slow_threshold_ms,omitempty"`
+	// Auth authenticates the check requests, they are sent without credentials if nil
+	Auth *Auth `json:"auth,omitempty"`
+	// OverlapPolicy decides what happens when a check is due while the previous one is still running:
+	// skip it, queue one run until the previous one finishes, or run concurrently. Queue if empty
+	OverlapPolicy string `json:"overlap_policy,omitempty"`
+	// MaxConcurrent is how many checks of the url run at the same time under the concurrent policy, 0 uses the default of 2
+	MaxConcurrent int `json:"max_concurrent,omitempty"`
+	// Locations lists where the url is checked from, by the agents there and by the monitor if it runs at one of them.
+	// The monitor checks the url itself if empty
+	Locations []string `json:"locations,omitempty"`
+	// Quorum is how many of the locations have to find the url down before it is considered down, 0 uses a majority
+	Quorum int `json:"quorum,omitempty"`
+	// Paused urls are kept in the database but not checked
+	Paused bool `json:"paused"`
+}
+
+// Auth configures the authentication of check requests. Secrets are never stored,
+// only the names of the environment variables of the monitor holding them
+type Auth struct {
+	// Type is basic, bearer or oauth2
+	Type string `json:"type"`
+	// Username and PasswordEnv are used by basic
+	Username    string `json:"username,omitempty"`
+	PasswordEnv string `json:"password_env,omitempty"`
+	// TokenEnv holds the static token of bearer
+	TokenEnv string `json:"token_env,omitempty"`
+	// TokenURL, ClientID, ClientSecretEnv and Scopes configure the oauth2 client credentials grant
+	TokenURL        string   `json:"token_url,omitempty"`
+	ClientID        string   `json:"client_id,omitempty"`
+	ClientSecretEnv string   `json:"client_secret_env,omitempty"`
+	Scopes          []string `json:"scopes,omitempty"`
 }
 
 // CheckResult represents the result of a website check
@@ -36,4 +170,204 @@ type CheckResult struct {
 	HttpStatus     *int      `json:"http_status,omitempty"`
 	RegexMatch     *bool     `json:"regex_match,omitempty"`
 	Error          string    `json:"error,omitempty"`
+	// DnsMs, ConnectMs, TlsHandshakeMs, TimeToFirstByteMs and TransferMs break the request down into its phases.
+	// Phases that did not happen, e.g. TLS for plain HTTP, are nil
+	DnsMs             *int `json:"dns_ms,omitempty"`
+	ConnectMs         *int `json:"connect_ms,omitempty"`
+	TlsHandshakeMs    *int `json:"tls_handshake_ms,omitempty"`
+	TimeToFirstByteMs *int `json:"ttfb_ms,omitempty"`
+	TransferMs        *int `json:"transfer_ms,omitempty"`
+	// AssertionResults holds the outcome of every assertion configured for the url
+	AssertionResults []AssertionResult `json:"assertion_results,omitempty"`
+	// Attempts is the number of requests the check took, more than one if failures were retried
+	Attempts int `json:"attempts,omitempty"`
+	// AttemptErrors lists the error of every failed attempt in order, it is only set if the check was retried
+	AttemptErrors []string `json:"attempt_errors,omitempty"`
+	// Redirects lists every redirect of the last attempt in order, empty if the url answered directly
+	Redirects []Redirect `json:"redirects,omitempty"`
+	// Location is where the check ran, empty for a monitor without a location
+	Location string `json:"location,omitempty"`
+	// Skipped is set if the check did not run because the previous one was still running, see Warning for details.
+	// Skipped checks are not counted in statistics and do not change the state of the url
+	Skipped bool `json:"skipped,omitempty"`
+	// Missed is set on a skipped result standing for several checks, the number of slots the url fell behind by
+	// because no worker was free. It is not stored, Warning states it
+	Missed int `json:"missed,omitempty"`
+	// BodyTruncated is set if the body was longer than the read limit of the url, so only its beginning was matched
+	BodyTruncated bool `json:"body_truncated,omitempty"`
+	// Certificates is the chain presented by HTTPS sites, leaf first
+	Certificates []Certificate `json:"certificates,omitempty"`
+	// CertExpiresAt is the earliest expiry in the chain and CertDaysRemaining the whole days left until then
+	CertExpiresAt     *time.Time `json:"cert_expires_at,omitempty"`
+	CertDaysRemaining *int       `json:"cert_days_remaining,omitempty"`
+	// Warning describes a problem that does not fail the check but degrades the url, e.g. a certificate about to expire
+	// or a slow response
+	Warning string `json:"warning,omitempty"`
+}
+
+// JSONAssertion checks the value selected by a JSONPath-style path, e.g. $.checks[0].status or $.items.length()
+type JSONAssertion struct {
+	Path string `json:"path"`
+	// Op is one of exists, not_exists, equals, not_equals, gt, gte, lt and lte
+	Op    string      `json:"op"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// ContentAssertion checks the body or a header of the response
+type ContentAssertion struct {
+	// Type is one of contains, not_contains, regex, body_size, header_equals and header_matches
+	Type string `json:"type"`
+	// Header is the response header checked by header_equals and header_matches
+	Header string `json:"header,omitempty"`
+	// Value is the text, regex or header value to look for
+	Value string `json:"value,omitempty"`
+	// MinBytes and MaxBytes bound the body size for body_size, either may be left out
+	MinBytes *int `json:"min_bytes,omitempty"`
+	MaxBytes *int `json:"max_bytes,omitempty"`
+}
+
+// AssertionResult records the outcome of an assertion in a check
+type AssertionResult struct {
+	// Type is json for JSON assertions and the assertion type for content assertions
+	Type     string      `json:"type"`
+	Path     string      `json:"path,omitempty"`
+	Op       string      `json:"op,omitempty"`
+	Header   string      `json:"header,omitempty"`
+	Expected interface{} `json:"expected,omitempty"`
+	// Actual is the selected value, left out for objects and arrays
+	Actual interface{} `json:"actual,omitempty"`
+	Passed bool        `json:"passed"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// Redirect is a single hop of a redirect chain
+type Redirect struct {
+	// URL is the address that answered with a redirect
+	URL string `json:"url"`
+	// Status is the redirect status, e.g. 301
+	Status int `json:"status"`
+	// Location is the address the redirect pointed to
+	Location string `json:"location"`
+}
+
+// Certificate describes a TLS certificate presented by a checked site
+type Certificate struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	DNSNames  []string  `json:"dns_names,omitempty"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+// CheckStats represents aggregated check statistics of a url over a time window
+type CheckStats struct {
+	URL              string    `json:"url"`
+	From             time.Time `json:"from"`
+	To               time.Time `json:"to"`
+	TotalChecks      int       `json:"total_checks"`
+	SuccessfulChecks int       `json:"successful_checks"`
+	ErrorCount       int       `json:"error_count"`
+	// SkippedChecks counts runs skipped because the previous check was still running, they are not part of the other counts
+	SkippedChecks int `json:"skipped_checks"`
+	// UptimePercent is the share of successful checks, nil if there were no checks in the window
+	UptimePercent     *float64       `json:"uptime_percent"`
+	ResponseTimeP50Ms *float64       `json:"response_time_p50_ms"`
+	ResponseTimeP95Ms *float64       `json:"response_time_p95_ms"`
+	ResponseTimeP99Ms *float64       `json:"response_time_p99_ms"`
+	StatusCodes       map[string]int `json:"status_codes"`
+}
+
+// Failed reports whether the check found the website unhealthy
+func (r CheckResult) Failed() bool {
+	return r.Error != "" || (r.RegexMatch != nil && !*r.RegexMatch)
+}
+
+// Overlap policies decide what happens when a check is due while the previous one is still running
+const (
+	// OverlapSkip skips the due check
+	OverlapSkip = "skip"
+	// OverlapQueue runs the due check once the previous one finishes, further due checks are skipped
+	OverlapQueue = "queue"
+	// OverlapConcurrent runs the due check alongside the previous ones up to MaxConcurrent, further due checks are skipped
+	OverlapConcurrent = "concurrent"
+)
+
+// MaxConcurrentLimit is the highest accepted max_concurrent
+const MaxConcurrentLimit = 10
+
+// ValidOverlapPolicy reports whether the policy is known, the empty policy stands for the default policy of the scheduler
+func ValidOverlapPolicy(policy string) bool {
+	switch policy {
+	case "", OverlapSkip, OverlapQueue, OverlapConcurrent:
+		return true
+	}
+
+	return false
+}
+
+// locationPattern matches the names of locations, they are used as user names of agents and in comma separated lists
+var locationPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidLocation reports whether the name can be used as a location
+func ValidLocation(name string) bool {
+	return locationPattern.MatchString(name)
+}
+
+// HasLocation reports whether the location is one of the locations the url is checked from
+func (u MonitoredUrl) HasLocation(location string) bool {
+	for _, l := range u.Locations {
+		if l == location {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LocationQuorum returns how many locations have to find the url down before it is considered down,
+// a majority of its locations if the url does not set a quorum
+func (u MonitoredUrl) LocationQuorum() int {
+	if u.Quorum > 0 {
+		return u.Quorum
+	}
+
+	return len(u.Locations)/2 + 1
+}
+
+// State represents the availability state of a monitored url
+type State string
+
+const (
+	// StateUnknown is the state of a url that has not been checked yet
+	StateUnknown State = "UNKNOWN"
+	// StateUp is the state of a healthy url
+	StateUp State = "UP"
+	// StateDegraded is the state of a url that is failing but has not reached the failure threshold yet
+	StateDegraded State = "DEGRADED"
+	// StateDown is the state of a url with an open incident
+	StateDown State = "DOWN"
+)
+
+// Incident represents a period during which a monitored url was down
+type Incident struct {
+	ID           int        `json:"id"`
+	UrlID        int        `json:"url_id"`
+	URL          string     `json:"url"`
+	StartedAt    time.Time  `json:"started_at"`
+	EndedAt      *time.Time `json:"ended_at,omitempty"`
+	Cause        string     `json:"cause"`
+	FirstCheckID *int       `json:"first_check_id,omitempty"`
+}
+
+// WebhookDelivery represents a single attempt to deliver a webhook notification
+type WebhookDelivery struct {
+	ID          int       `json:"id"`
+	Endpoint    string    `json:"endpoint"`
+	UrlID       int       `json:"url_id"`
+	IncidentID  *int      `json:"incident_id,omitempty"`
+	Payload     []byte    `json:"payload"`
+	Attempt     int       `json:"attempt"`
+	StatusCode  *int      `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DeliveredAt time.Time `json:"delivered_at"`
 }
@@ -0,0 +1,188 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"website-monitor/internal/delivery_repository"
+	"website-monitor/internal/models"
+	"website-monitor/internal/state"
+)
+
+// SignatureHeader carries the HMAC-SHA256 of the request body, formatted as "sha256=<hex>"
+const SignatureHeader = "X-Webhook-Signature"
+
+// Payload is the JSON body posted to webhook endpoints
+type Payload struct {
+	UrlID         int                `json:"url_id"`
+	URL           string             `json:"url"`
+	PreviousState models.State       `json:"previous_state"`
+	NewState      models.State       `json:"new_state"`
+	IncidentID    *int               `json:"incident_id,omitempty"`
+	Check         models.CheckResult `json:"check"`
+}
+
+// Notifier posts up/down state changes to webhook endpoints
+type Notifier struct {
+	endpoints   []string
+	secret      []byte
+	client      *http.Client
+	maxAttempts int
+	retryDelay  time.Duration
+	deliveries  delivery_repository.DeliveryRepository
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func New(deliveries delivery_repository.DeliveryRepository, cfg models.NotifierConfig) *Notifier {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Notifier{
+		endpoints: cfg.WebhookURLs,
+		secret:    []byte(cfg.Secret),
+		client: &http.Client{
+			Timeout: time.Duration(cfg.TimeoutSec) * time.Second,
+		},
+		maxAttempts: cfg.MaxAttempts,
+		retryDelay:  time.Duration(cfg.RetryDelaySec) * time.Second,
+		deliveries:  deliveries,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// HandleTransition sends a notification to every endpoint when a url goes down or comes back up.
+// Deliveries run in the background, so a slow endpoint does not delay checks
+func (n *Notifier) HandleTransition(ctx context.Context, transition state.Transition) {
+	if len(n.endpoints) == 0 {
+		return
+	}
+
+	if transition.To != models.StateDown && transition.From != models.StateDown {
+		return
+	}
+
+	payload := Payload{
+		UrlID:         transition.Url.ID,
+		URL:           transition.Url.Url,
+		PreviousState: transition.From,
+		NewState:      transition.To,
+		Check:         transition.Result,
+	}
+	if transition.Incident != nil {
+		payload.IncidentID = &transition.Incident.ID
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to encode webhook payload for %s: %v", transition.Url.Url, err)
+
+		return
+	}
+
+	for _, endpoint := range n.endpoints {
+		n.wg.Add(1)
+		go n.deliver(endpoint, payload, body)
+	}
+}
+
+// Stop aborts pending retries and waits for in-flight delivery attempts to finish
+func (n *Notifier) Stop() {
+	n.cancel()
+	n.wg.Wait()
+}
+
+// deliver posts the body to the endpoint, retrying failed attempts with a growing delay
+func (n *Notifier) deliver(endpoint string, payload Payload, body []byte) {
+	defer n.wg.Done()
+
+	for attempt := 1; attempt <= n.maxAttempts; attempt++ {
+		statusCode, err := n.send(endpoint, body)
+
+		delivery := models.WebhookDelivery{
+			Endpoint:    endpoint,
+			UrlID:       payload.UrlID,
+			IncidentID:  payload.IncidentID,
+			Payload:     body,
+			Attempt:     attempt,
+			StatusCode:  statusCode,
+			DeliveredAt: time.Now(),
+		}
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+
+		// The delivery log is written even during shutdown, so the last attempts are not lost
+		if logErr := n.deliveries.InsertDelivery(context.Background(), delivery); logErr != nil {
+			log.Printf("Failed to log webhook delivery to %s: %v", endpoint, logErr)
+		}
+
+		if err == nil {
+			return
+		}
+
+		log.Printf("Webhook delivery to %s failed (attempt %d/%d): %v", endpoint, attempt, n.maxAttempts, err)
+
+		if attempt == n.maxAttempts || n.ctx.Err() != nil {
+			return
+		}
+
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-time.After(n.retryDelay * time.Duration(attempt)):
+		}
+	}
+}
+
+// send performs a single delivery attempt and returns the response status, if any
+func (n *Notifier) send(endpoint string, body []byte) (*int, error) {
+	// In-flight attempts are not cancelled on shutdown, they are bounded by the client timeout
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if len(n.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(n.secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	// Drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	statusCode := resp.StatusCode
+	if statusCode < 200 || statusCode > 299 {
+		return &statusCode, fmt.Errorf("unexpected status code %d", statusCode)
+	}
+
+	return &statusCode, nil
+}
+
+// Sign returns the signature of the body in the format of SignatureHeader
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
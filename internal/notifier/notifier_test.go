@@ -0,0 +1,153 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"website-monitor/internal/models"
+	"website-monitor/internal/state"
+)
+
+type mockDeliveryRepository struct {
+	mu         sync.Mutex
+	deliveries []models.WebhookDelivery
+}
+
+func (m *mockDeliveryRepository) InsertDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries = append(m.deliveries, delivery)
+	return nil
+}
+
+func downTransition() state.Transition {
+	return state.Transition{
+		Url:      models.MonitoredUrl{ID: 1, Url: "https://example.com", CheckIntervalSec: 10},
+		From:     models.StateDegraded,
+		To:       models.StateDown,
+		Result:   models.CheckResult{ID: 42, URL: "https://example.com", Error: "connection refused"},
+		Incident: &models.Incident{ID: 7},
+	}
+}
+
+func TestNotifier_HandleTransition_PostsSignedPayload(t *testing.T) {
+	var gotBody []byte
+	var gotSignature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotSignature = r.Header.Get(SignatureHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	deliveries := &mockDeliveryRepository{}
+	notifier := New(deliveries, models.NotifierConfig{
+		WebhookURLs: []string{server.URL},
+		Secret:      "s3cret",
+		TimeoutSec:  5,
+		MaxAttempts: 3,
+	})
+
+	notifier.HandleTransition(context.Background(), downTransition())
+	notifier.Stop()
+
+	var payload Payload
+	if err := json.Unmarshal(gotBody, &payload); err != nil {
+		t.Fatalf("Expected JSON payload, got error: %v", err)
+	}
+
+	if payload.NewState != models.StateDown || payload.PreviousState != models.StateDegraded {
+		t.Errorf("Expected DEGRADED -> DOWN payload, got %s -> %s", payload.PreviousState, payload.NewState)
+	}
+
+	if payload.IncidentID == nil || *payload.IncidentID != 7 {
+		t.Errorf("Expected incident id 7, got %v", payload.IncidentID)
+	}
+
+	if payload.Check.ID != 42 {
+		t.Errorf("Expected check 42 in payload, got %d", payload.Check.ID)
+	}
+
+	if gotSignature != Sign([]byte("s3cret"), gotBody) {
+		t.Errorf("Expected valid signature, got %s", gotSignature)
+	}
+
+	if len(deliveries.deliveries) != 1 || deliveries.deliveries[0].Error != "" {
+		t.Errorf("Expected 1 successful delivery logged, got %+v", deliveries.deliveries)
+	}
+}
+
+func TestNotifier_HandleTransition_RetriesFailedDeliveries(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	deliveries := &mockDeliveryRepository{}
+	notifier := New(deliveries, models.NotifierConfig{
+		WebhookURLs: []string{server.URL},
+		TimeoutSec:  5,
+		MaxAttempts: 3,
+	})
+	notifier.retryDelay = time.Millisecond
+
+	notifier.HandleTransition(context.Background(), downTransition())
+	notifier.wg.Wait()
+
+	if calls != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", calls)
+	}
+
+	if len(deliveries.deliveries) != 3 {
+		t.Fatalf("Expected 3 logged attempts, got %d", len(deliveries.deliveries))
+	}
+
+	first := deliveries.deliveries[0]
+	if first.StatusCode == nil || *first.StatusCode != http.StatusBadGateway || first.Error == "" {
+		t.Errorf("Expected first attempt to be logged as failed with 502, got %+v", first)
+	}
+
+	if last := deliveries.deliveries[2]; last.Attempt != 3 || last.Error != "" {
+		t.Errorf("Expected third attempt to succeed, got %+v", last)
+	}
+}
+
+func TestNotifier_HandleTransition_IgnoresDegraded(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	notifier := New(&mockDeliveryRepository{}, models.NotifierConfig{
+		WebhookURLs: []string{server.URL},
+		TimeoutSec:  5,
+		MaxAttempts: 1,
+	})
+
+	transition := downTransition()
+	transition.From = models.StateUp
+	transition.To = models.StateDegraded
+
+	notifier.HandleTransition(context.Background(), transition)
+	notifier.Stop()
+
+	if called {
+		t.Error("Expected no notification for a transition that does not involve DOWN")
+	}
+}
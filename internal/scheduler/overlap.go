@@ -0,0 +1,44 @@
+package scheduler
+
+import (
+	"fmt"
+
+	"website-monitor/internal/models"
+)
+
+// DefaultOverlapPolicy is used if a url does not set one
+const DefaultOverlapPolicy = models.OverlapQueue
+
+// DefaultMaxConcurrent is the number of concurrent checks of a url under the concurrent policy if the url does not set one
+const DefaultMaxConcurrent = 2
+
+// overlapPolicy returns the overlap policy of the url
+func overlapPolicy(url models.MonitoredUrl) string {
+	if url.OverlapPolicy == "" {
+		return DefaultOverlapPolicy
+	}
+
+	return url.OverlapPolicy
+}
+
+// maxActive returns how many checks of the url may run at the same time
+func maxActive(url models.MonitoredUrl) int {
+	if overlapPolicy(url) != models.OverlapConcurrent {
+		return 1
+	}
+
+	if url.MaxConcurrent <= 0 {
+		return DefaultMaxConcurrent
+	}
+
+	return url.MaxConcurrent
+}
+
+// skipReason describes why a check was skipped while the given number of checks of the url were running
+func skipReason(active int) string {
+	if active == 1 {
+		return "skipped, the previous check was still running"
+	}
+
+	return fmt.Sprintf("skipped, %d checks were still running", active)
+}
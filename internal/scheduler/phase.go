@@ -0,0 +1,86 @@
+package scheduler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"time"
+
+	"website-monitor/internal/models"
+)
+
+// interval returns the check interval of a url. Urls are checked at most once a second,
+// which also keeps urls built by hand without an interval from being due over and over
+func interval(url models.MonitoredUrl) time.Duration {
+	if url.CheckIntervalSec < 1 {
+		return time.Second
+	}
+
+	return time.Duration(url.CheckIntervalSec) * time.Second
+}
+
+// phaseOffset returns where within its interval a url is checked. It is derived from a hash of the url id,
+// so urls with the same interval are spread evenly over it and keep their phase across restarts and reloads
+func phaseOffset(id int, every time.Duration) time.Duration {
+	if every <= 0 {
+		return 0
+	}
+
+	var key [8]byte
+	binary.BigEndian.PutUint64(key[:], uint64(id))
+
+	hash := fnv.New64a()
+	_, _ = hash.Write(key[:])
+
+	return time.Duration(hash.Sum64() % uint64(every))
+}
+
+// nextSlot returns the first time after t at which a url with the given interval and phase offset is due.
+// Slots are counted from the Unix epoch, so they do not depend on when the monitor was started
+func nextSlot(t time.Time, every, offset time.Duration) time.Time {
+	if every <= 0 {
+		return t
+	}
+
+	elapsed := time.Duration(t.UnixNano()) - offset
+	slots := elapsed / every
+	if elapsed >= 0 {
+		slots++
+	}
+
+	return time.Unix(0, int64(slots*every+offset))
+}
+
+// nextRun returns the slot after the given one and how many slots were missed on the way. If the slot after
+// the given one has passed already, e.g. because no worker was free for longer than the interval, the url skips
+// ahead to its first slot after now, missed runs are not made up
+func nextRun(slot time.Time, every, offset time.Duration, now time.Time) (time.Time, int) {
+	next := nextSlot(slot, every, offset)
+	if !next.After(now) {
+		skipped := nextSlot(now, every, offset)
+
+		return skipped, int(skipped.Sub(next) / every)
+	}
+
+	return next, 0
+}
+
+// missedReason describes why the given number of checks of a url were skipped when it fell behind its slots
+func missedReason(missed int) string {
+	if missed == 1 {
+		return "skipped 1 check, no worker was free at its time"
+	}
+
+	return fmt.Sprintf("skipped %d checks, no worker was free at their time", missed)
+}
+
+// jitter returns a random delay of up to the given percentage of the interval, 0 if the percentage is 0
+func jitter(every time.Duration, percent int) time.Duration {
+	limit := every * time.Duration(percent) / 100
+	if limit <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(limit)))
+}
@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPhaseOffset_SpreadsUrlsOverTheInterval(t *testing.T) {
+	every := time.Minute
+	buckets := make([]int, 6)
+
+	for id := 1; id <= 600; id++ {
+		offset := phaseOffset(id, every)
+		if offset < 0 || offset >= every {
+			t.Fatalf("Expected offset within the interval, got %v for url %d", offset, id)
+		}
+
+		if offset != phaseOffset(id, every) {
+			t.Fatalf("Expected offset of url %d to be deterministic", id)
+		}
+
+		buckets[offset*time.Duration(len(buckets))/every]++
+	}
+
+	// Every 10 seconds of the minute should get roughly a sixth of the urls
+	for i, count := range buckets {
+		if count < 60 || count > 140 {
+			t.Errorf("Expected urls to be spread evenly, got %d in bucket %d: %v", count, i, buckets)
+		}
+	}
+}
+
+func TestNextSlot(t *testing.T) {
+	every := time.Minute
+	offset := 15 * time.Second
+	now := time.Date(2024, 1, 1, 12, 0, 40, 0, time.UTC)
+
+	if slot := nextSlot(now, every, offset); !slot.Equal(time.Date(2024, 1, 1, 12, 1, 15, 0, time.UTC)) {
+		t.Errorf("Expected slot at the offset of the next minute, got %v", slot)
+	}
+
+	aligned := time.Date(2024, 1, 1, 12, 0, 15, 0, time.UTC)
+	if slot := nextSlot(aligned, every, offset); !slot.Equal(aligned.Add(every)) {
+		t.Errorf("Expected slot after an aligned time to be one interval later, got %v", slot)
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	slot := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if next, missed := nextRun(slot, time.Minute, 0, slot.Add(time.Second)); !next.Equal(slot.Add(time.Minute)) || missed != 0 {
+		t.Errorf("Expected next run one interval after the slot, got %v with %d missed", next, missed)
+	}
+
+	now := slot.Add(150 * time.Second)
+	if next, missed := nextRun(slot, time.Minute, 0, now); !next.Equal(slot.Add(3*time.Minute)) || missed != 2 {
+		t.Errorf("Expected url that fell behind to skip 2 slots ahead to its next slot, got %v with %d missed", next, missed)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	if delay := jitter(time.Minute, 0); delay != 0 {
+		t.Errorf("Expected no jitter when disabled, got %v", delay)
+	}
+
+	for i := 0; i < 100; i++ {
+		if delay := jitter(time.Minute, 10); delay < 0 || delay >= 6*time.Second {
+			t.Fatalf("Expected jitter below 10%% of the interval, got %v", delay)
+		}
+	}
+}
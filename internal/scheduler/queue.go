@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"website-monitor/internal/models"
+)
+
+// monitor tracks the schedule of a single url
+type monitor struct {
+	url models.MonitoredUrl
+	// slot is the time of the next check aligned to the phase of the url, next is the slot plus jitter
+	slot time.Time
+	next time.Time
+	// index is the position in the due queue, -1 once the monitor is stopped
+	index int
+	// ctx is cancelled when the monitor stops, which aborts its checks in progress
+	ctx    context.Context
+	cancel context.CancelFunc
+	// active counts the checks handed to the workers that have not finished yet
+	active int
+	// held is set while a check waits for a slot of its host, it does not count as active until it runs
+	held bool
+	// queued is set if a due check waits for the previous one to finish under the queue policy
+	queued bool
+	// stopped is set once the url is no longer monitored, so checks waiting for a worker are dropped
+	stopped bool
+}
+
+// job is a unit of work for the workers, either a check or recording that a due check was skipped
+type job struct {
+	m       *monitor
+	skipped bool
+	// active is the number of checks of the url that were running when the check was skipped
+	active int
+	// missed is the number of slots the url fell behind by, the job records them as skipped
+	missed int
+}
+
+// dueQueue orders monitors by the time they are due, the earliest first. It implements heap.Interface
+type dueQueue []*monitor
+
+func (q dueQueue) Len() int { return len(q) }
+
+func (q dueQueue) Less(i, j int) bool { return q[i].next.Before(q[j].next) }
+
+func (q dueQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *dueQueue) Push(x interface{}) {
+	m := x.(*monitor)
+	m.index = len(*q)
+	*q = append(*q, m)
+}
+
+func (q *dueQueue) Pop() interface{} {
+	old := *q
+	m := old[len(old)-1]
+	old[len(old)-1] = nil
+	m.index = -1
+	*q = old[:len(old)-1]
+
+	return m
+}
+
+// schedule queues the monitor to be due at the given time, or moves it there if it is queued already
+func (q *dueQueue) schedule(m *monitor, next time.Time) {
+	m.next = next
+	if m.index >= 0 {
+		heap.Fix(q, m.index)
+
+		return
+	}
+
+	heap.Push(q, m)
+}
+
+// remove takes the monitor out of the queue if it is queued
+func (q *dueQueue) remove(m *monitor) {
+	if m.index >= 0 {
+		heap.Remove(q, m.index)
+	}
+}
+
+// hostOf returns the host the per-host concurrency limit of a url applies to
+func hostOf(url models.MonitoredUrl) string {
+	parsed, err := neturl.Parse(url.Url)
+	if err != nil || parsed.Hostname() == "" {
+		return url.Url
+	}
+
+	return strings.ToLower(parsed.Hostname())
+}
@@ -3,6 +3,7 @@ package scheduler
 import (
 	"context"
 	"log"
+	"reflect"
 	"sync"
 	"time"
 
@@ -17,23 +18,132 @@ type Stoppable interface {
 	Stop()
 }
 
+// ResultObserver defines the interface for components that consume check results
+type ResultObserver interface {
+	Observe(ctx context.Context, url models.MonitoredUrl, result models.CheckResult) error
+}
+
+// Recorder defines the interface for components that track internal scheduler events, e.g. as metrics
+type Recorder interface {
+	MonitorStarted(url models.MonitoredUrl)
+	MonitorStopped(url models.MonitoredUrl)
+	// UrlRemoved is called once the results of the url are no longer recorded by this instance, because it was
+	// deleted, changed its address or moved to another instance. Restarted monitors keep their url
+	UrlRemoved(url models.MonitoredUrl)
+	CheckStarted()
+	CheckFinished()
+	// CheckSkipped is called with the location of the skipped checks, empty for checks of the monitor itself, and
+	// how many were skipped
+	CheckSkipped(url models.MonitoredUrl, location string, count int)
+	InsertFailed()
+}
+
+// noopRecorder is used when no recorder is set
+type noopRecorder struct{}
+
+func (noopRecorder) MonitorStarted(models.MonitoredUrl)            {}
+func (noopRecorder) MonitorStopped(models.MonitoredUrl)            {}
+func (noopRecorder) UrlRemoved(models.MonitoredUrl)                {}
+func (noopRecorder) CheckStarted()                                 {}
+func (noopRecorder) CheckFinished()                                {}
+func (noopRecorder) CheckSkipped(models.MonitoredUrl, string, int) {}
+func (noopRecorder) InsertFailed()                                 {}
+
+// Shard decides which urls are checked by this instance when several instances share the database
+type Shard interface {
+	Owns(urlID int) bool
+}
+
+// defaultWorkers is the number of concurrent checks if the configuration does not set one
+const defaultWorkers = 50
+
 type Scheduler struct {
-	repo    url_repository.UrlRepository
-	db      *db.DB
-	checker checker.IChecker
-	cancel  context.CancelFunc
-	wg      sync.WaitGroup
+	repo           url_repository.UrlRepository
+	db             *db.DB
+	checker        checker.IChecker
+	observers      []ResultObserver
+	recorder       Recorder
+	shard          Shard
+	reloadInterval time.Duration
+	workers        int
+	perHostLimit   int
+	jitterPercent  int
+	location       string
+	cancel         context.CancelFunc
+	wg             sync.WaitGroup
+
+	// jobs hands work from the dispatcher to the workers
+	jobs chan *job
+	// wake tells the dispatcher that the queue changed
+	wake chan struct{}
+	// rebalance tells the reload loop that the urls owned by this instance changed
+	rebalance chan struct{}
+
+	mu       sync.Mutex
+	monitors map[int]*monitor
+	queue    dueQueue
+	// ready holds the jobs waiting for a worker, they are handed out before further monitors become due
+	ready []*job
+	// running counts the checks in progress per host and waiting holds checks held back by the per-host limit
+	running map[string]int
+	waiting map[string][]*monitor
+	// loadErrors holds why urls were not monitored after the last load, by url id
+	loadErrors map[int]string
+	// reported holds the urls whose results this instance recorded after the last load, by address
+	reported map[string]models.MonitoredUrl
 }
 
-func New(repo url_repository.UrlRepository, database *db.DB, chk checker.IChecker) *Scheduler {
+func New(repo url_repository.UrlRepository, database *db.DB, chk checker.IChecker, cfg models.SchedulerConfig) *Scheduler {
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+
 	return &Scheduler{
-		repo:    repo,
-		db:      database,
-		checker: chk,
+		repo:           repo,
+		db:             database,
+		checker:        chk,
+		recorder:       noopRecorder{},
+		reloadInterval: time.Duration(cfg.ReloadIntervalSec) * time.Second,
+		workers:        workers,
+		perHostLimit:   cfg.PerHostLimit,
+		jitterPercent:  cfg.JitterPercent,
+		location:       cfg.Location,
+		jobs:           make(chan *job),
+		wake:           make(chan struct{}, 1),
+		rebalance:      make(chan struct{}, 1),
+		monitors:       make(map[int]*monitor),
+		running:        make(map[string]int),
+		waiting:        make(map[string][]*monitor),
+		loadErrors:     make(map[int]string),
+	}
+}
+
+// AddObserver registers an observer that receives every stored check result. It must be called before Start
+func (s *Scheduler) AddObserver(observer ResultObserver) {
+	s.observers = append(s.observers, observer)
+}
+
+// SetRecorder sets the recorder of internal scheduler events. It must be called before Start
+func (s *Scheduler) SetRecorder(recorder Recorder) {
+	s.recorder = recorder
+}
+
+// SetShard limits the scheduler to the urls owned by this instance. It must be called before Start
+func (s *Scheduler) SetShard(shard Shard) {
+	s.shard = shard
+}
+
+// Rebalance makes the scheduler pick up urls that moved to or away from this instance right away,
+// instead of at the next reload
+func (s *Scheduler) Rebalance() {
+	select {
+	case s.rebalance <- struct{}{}:
+	default:
 	}
 }
 
-// Start begins monitoring of all URLs from the repository
+// Start begins monitoring of all URLs from the repository and keeps them in sync with it
 func (s *Scheduler) Start(ctx context.Context) error {
 	urls, err := s.repo.GetMonitoredUrls()
 	if err != nil {
@@ -42,60 +152,463 @@ func (s *Scheduler) Start(ctx context.Context) error {
 
 	if len(urls) == 0 {
 		log.Println("No URLs to monitor")
-
-		return nil
 	}
 
 	// Wrap context with cancel to ensure Stop() can immediately signal all goroutines and wait for them to exit
 	ctx, s.cancel = context.WithCancel(ctx)
 
-	for _, url := range urls {
+	s.reconcile(ctx, urls)
+
+	s.wg.Add(s.workers + 1)
+	go s.dispatchLoop(ctx)
+	for i := 0; i < s.workers; i++ {
+		go s.worker(ctx)
+	}
+
+	if s.reloadInterval > 0 || s.shard != nil {
 		s.wg.Add(1)
-		go s.startMonitorUrl(ctx, url)
+		go s.reloadLoop(ctx)
 	}
 
 	return nil
 }
 
-// Stop gracefully stops all monitoring goroutines
+// Stop gracefully stops the dispatcher and the workers, checks in progress are cancelled
 func (s *Scheduler) Stop() {
 	if s.cancel != nil {
 		log.Println("Stopping scheduler...")
 		s.cancel()
 		s.wg.Wait()
+
+		s.mu.Lock()
+		for id, m := range s.monitors {
+			s.stopMonitor(m)
+			delete(s.monitors, id)
+		}
+		s.mu.Unlock()
+
 		log.Println("Scheduler stopped")
 	}
 }
 
-// startMonitorUrl runs in a goroutine to monitor a single url
-func (s *Scheduler) startMonitorUrl(ctx context.Context, url models.MonitoredUrl) {
+// reloadLoop periodically, and whenever urls move between instances, re-reads monitored urls
+// and reconciles running monitors against them
+func (s *Scheduler) reloadLoop(ctx context.Context) {
+	defer s.wg.Done()
+
+	var reload <-chan time.Time
+	if s.reloadInterval > 0 {
+		ticker := time.NewTicker(s.reloadInterval)
+		defer ticker.Stop()
+		reload = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reload:
+		case <-s.rebalance:
+		}
+
+		urls, err := s.repo.GetMonitoredUrls()
+		if err != nil {
+			// Keep the current monitors running, the next reload will try again
+			log.Printf("Failed to reload monitored urls: %v", err)
+
+			continue
+		}
+
+		s.reconcile(ctx, urls)
+	}
+}
+
+// LoadErrors returns why urls could not be monitored after the last load, e.g. an invalid regex, by url id
+func (s *Scheduler) LoadErrors() map[int]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	loadErrors := make(map[int]string, len(s.loadErrors))
+	for id, message := range s.loadErrors {
+		loadErrors[id] = message
+	}
+
+	return loadErrors
+}
+
+// reconcile schedules new urls at their phase, stops monitoring removed urls and reschedules changed urls with their new settings.
+// Urls the checker rejects are not monitored until they are fixed, urls owned by other instances or checked
+// from other locations are left to them
+func (s *Scheduler) reconcile(ctx context.Context, urls []models.MonitoredUrl) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invalid := s.checker.Prepare(urls)
+	loadErrors := make(map[int]string, len(invalid))
+
+	// Results of urls owned by this instance are recorded here, whichever location checks them
+	reported := make(map[string]models.MonitoredUrl, len(urls))
+	for _, url := range urls {
+		if s.shard == nil || s.shard.Owns(url.ID) {
+			reported[url.Url] = url
+		}
+	}
+	for address, url := range s.reported {
+		if _, ok := reported[address]; !ok {
+			s.recorder.UrlRemoved(url)
+		}
+	}
+	s.reported = reported
+
+	wanted := make(map[int]models.MonitoredUrl, len(urls))
+	for _, url := range urls {
+		if err, ok := invalid[url.ID]; ok {
+			loadErrors[url.ID] = err.Error()
+			// Only log once per error, not on every reload
+			if s.loadErrors[url.ID] != err.Error() {
+				log.Printf("Not monitoring %s: %v", url.Url, err)
+			}
+
+			continue
+		}
+
+		// Other instances check the urls they own, errors are still reported for all urls
+		if s.shard != nil && !s.shard.Owns(url.ID) {
+			continue
+		}
+
+		if len(url.Locations) > 0 && !url.HasLocation(s.location) {
+			continue
+		}
+
+		wanted[url.ID] = url
+	}
+	s.loadErrors = loadErrors
+
+	for id, m := range s.monitors {
+		url, ok := wanted[id]
+		if ok && reflect.DeepEqual(url, m.url) {
+			continue
+		}
+
+		if ok {
+			log.Printf("Configuration of %s changed, restarting monitoring", url.Url)
+		}
+
+		s.stopMonitor(m)
+		delete(s.monitors, id)
+	}
+
+	now := time.Now()
+	for id, url := range wanted {
+		if _, ok := s.monitors[id]; ok {
+			continue
+		}
+
+		log.Printf("Starting monitoring for %s (interval: %d seconds)", url.Url, url.CheckIntervalSec)
+		s.recorder.MonitorStarted(url)
+
+		monitorCtx, cancel := context.WithCancel(ctx)
+		m := &monitor{url: url, index: -1, ctx: monitorCtx, cancel: cancel}
+		s.monitors[id] = m
+		s.schedule(m, nextSlot(now, interval(url), phaseOffset(url.ID, interval(url))))
+	}
+
+	s.wakeDispatcher()
+}
+
+// schedule queues the monitor for the check of the given slot, delayed by the configured jitter.
+// The caller must hold s.mu
+func (s *Scheduler) schedule(m *monitor, slot time.Time) {
+	m.slot = slot
+	s.queue.schedule(m, slot.Add(jitter(interval(m.url), s.jitterPercent)))
+}
+
+// stopMonitor takes the monitor out of the schedule and cancels its checks in progress. The caller must hold s.mu
+func (s *Scheduler) stopMonitor(m *monitor) {
+	log.Printf("Stopping monitoring for %s", m.url.Url)
+
+	m.stopped = true
+	s.queue.remove(m)
+	if m.cancel != nil {
+		m.cancel()
+	}
+
+	s.recorder.MonitorStopped(m.url)
+}
+
+// wakeDispatcher makes the dispatcher look at the queue again, e.g. after urls were added
+func (s *Scheduler) wakeDispatcher() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// dispatchLoop hands jobs to the workers as monitors become due, the most overdue first.
+// While all workers are busy due monitors wait in the queue, so the number of concurrent checks stays bounded
+func (s *Scheduler) dispatchLoop(ctx context.Context) {
 	defer s.wg.Done()
 
-	log.Printf("Starting monitoring for %s (interval: %d seconds)", url.Url, url.CheckIntervalSec)
+	for {
+		j, wait := s.nextJob(time.Now())
+		if j != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case s.jobs <- j:
+			}
 
-	ticker := time.NewTicker(time.Duration(url.CheckIntervalSec) * time.Second)
-	defer ticker.Stop()
+			continue
+		}
+
+		// Without queued monitors there is nothing to wait for until the queue changes
+		var timer *time.Timer
+		var due <-chan time.Time
+		if wait >= 0 {
+			timer = time.NewTimer(wait)
+			due = timer.C
+		}
 
-	for ; true; <-ticker.C {
 		select {
 		case <-ctx.Done():
-			log.Printf("Stopping monitoring for %s", url.Url)
+		case <-s.wake:
+		case <-due:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// nextJob returns the next job that is ready for a worker, moving due monitors to their next slot on the way.
+// If no job is ready, it returns how long until the next monitor is due, or a negative duration if the queue is empty
+func (s *Scheduler) nextJob(now time.Time) (*job, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		if len(s.ready) > 0 {
+			j := s.ready[0]
+			s.ready[0] = nil
+			s.ready = s.ready[1:]
+
+			return j, 0
+		}
+
+		if s.queue.Len() == 0 {
+			return nil, -1
+		}
+
+		m := s.queue[0]
+		if wait := m.next.Sub(now); wait > 0 {
+			return nil, wait
+		}
+
+		s.due(m, now)
+	}
+}
+
+// due moves a due monitor to its next slot and starts its check unless the overlap policy of the url
+// holds it back. The caller must hold s.mu
+func (s *Scheduler) due(m *monitor, now time.Time) {
+	every := interval(m.url)
+	next, missed := nextRun(m.slot, every, phaseOffset(m.url.ID, every), now)
+	s.schedule(m, next)
+
+	// Slots that passed while no worker was free are recorded, so a saturated pool does not go unnoticed
+	if missed > 0 {
+		s.ready = append(s.ready, &job{m: m, skipped: true, missed: missed})
+	}
+
+	// A check held back by the per-host limit has not started yet, it runs in place of this one
+	if m.held {
+		return
+	}
+
+	if m.active < maxActive(m.url) {
+		s.startCheck(m)
+
+		return
+	}
+
+	if overlapPolicy(m.url) == models.OverlapQueue && !m.queued {
+		m.queued = true
+
+		return
+	}
+
+	s.ready = append(s.ready, &job{m: m, skipped: true, active: m.active})
+}
+
+// startCheck makes a check of the monitor ready for a worker, or holds it back while its host is at the
+// per-host limit. The caller must hold s.mu
+func (s *Scheduler) startCheck(m *monitor) {
+	host := hostOf(m.url)
+	if s.perHostLimit > 0 && s.running[host] >= s.perHostLimit {
+		m.held = true
+		s.waiting[host] = append(s.waiting[host], m)
+
+		return
+	}
+
+	m.active++
+	s.running[host]++
+	s.ready = append(s.ready, &job{m: m})
+}
+
+// worker runs the jobs handed over by the dispatcher until the scheduler stops
+func (s *Scheduler) worker(ctx context.Context) {
+	defer s.wg.Done()
 
+	for {
+		select {
+		case <-ctx.Done():
 			return
-		default:
+		case j := <-s.jobs:
+			if j.skipped {
+				s.recordSkipped(ctx, j)
+			} else {
+				s.run(j.m)
+			}
+		}
+	}
+}
+
+// run checks the url of the monitor and starts the check queued in the meantime, if any
+func (s *Scheduler) run(m *monitor) {
+	s.mu.Lock()
+	// The url may have been removed or changed while the check was waiting for a worker
+	stopped := m.stopped
+	s.mu.Unlock()
+
+	// A url that moved to another instance is no longer checked here, even before the rebalance stops its monitor
+	if !stopped && (s.shard == nil || s.shard.Owns(m.url.ID)) {
+		s.performCheck(m.ctx, m.url)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m.active--
+	s.release(hostOf(m.url))
+	if m.queued && !m.stopped {
+		m.queued = false
+		s.startCheck(m)
+	}
+
+	s.wakeDispatcher()
+}
+
+// release frees a check slot of the host and hands it to the first check held back by the per-host limit.
+// The caller must hold s.mu
+func (s *Scheduler) release(host string) {
+	s.running[host]--
+	if s.running[host] <= 0 {
+		delete(s.running, host)
+	}
+
+	for len(s.waiting[host]) > 0 {
+		m := s.waiting[host][0]
+		s.waiting[host] = s.waiting[host][1:]
+		m.held = false
+
+		if m.stopped {
+			continue
 		}
 
-		s.performCheck(url)
+		// A held back check goes ahead of checks that became due later
+		m.active++
+		s.running[host]++
+		s.ready = append(s.ready, &job{m: m})
+
+		break
+	}
+
+	if len(s.waiting[host]) == 0 {
+		delete(s.waiting, host)
+	}
+}
+
+// recordSkipped stores a skipped check, so gaps in the checks of a url can be explained
+func (s *Scheduler) recordSkipped(ctx context.Context, j *job) {
+	s.mu.Lock()
+	// The url may have been removed or changed while the job was waiting for a worker
+	stopped := j.m.stopped
+	s.mu.Unlock()
+
+	if stopped {
+		return
 	}
+
+	url := j.m.url
+	result := models.CheckResult{
+		URL:            url.Url,
+		CheckTimestamp: time.Now(),
+		Location:       s.location,
+		Skipped:        true,
+	}
+
+	if j.missed > 0 {
+		log.Printf("Skipped %d checks of %s, no worker was free", j.missed, url.Url)
+		result.Missed = j.missed
+		result.Warning = missedReason(j.missed)
+	} else {
+		log.Printf("Skipping check of %s, %d checks still running", url.Url, j.active)
+		result.Warning = skipReason(j.active)
+	}
+
+	_ = s.Record(ctx, url, result)
 }
 
 // performCheck executes a single check for a url and stores the result
-func (s *Scheduler) performCheck(url models.MonitoredUrl) {
+func (s *Scheduler) performCheck(ctx context.Context, url models.MonitoredUrl) {
 	log.Printf("Checking %s", url.Url)
 
-	result := s.checker.Check(url)
+	s.recorder.CheckStarted()
+	result := s.checker.Check(ctx, url)
+	s.recorder.CheckFinished()
+
+	// A check interrupted by shutdown or reload says nothing about the site, so it is not stored
+	if ctx.Err() != nil {
+		log.Printf("Check of %s cancelled", url.Url)
+
+		return
+	}
+
+	result.Location = s.location
+	_ = s.Record(ctx, url, result)
+}
+
+// Record stores the result of a check of the url and passes it to the observers. Skipped checks are only stored,
+// they say nothing about the state of the url. Besides the checks run here, it records the results pushed by agents.
+// The result reaches the observers even if it could not be stored
+func (s *Scheduler) Record(ctx context.Context, url models.MonitoredUrl, result models.CheckResult) error {
+	if result.Skipped {
+		s.recorder.CheckSkipped(url, result.Location, max(result.Missed, 1))
+	}
 
-	if err := s.checker.InsertCheckResult(result); err != nil {
+	id, err := s.checker.InsertCheckResult(ctx, result)
+	if err != nil && ctx.Err() == nil {
+		s.recorder.InsertFailed()
 		log.Printf("Failed to store check result for %s: %v", url.Url, err)
 	}
+	result.ID = id
+
+	if result.Skipped {
+		return err
+	}
+
+	for _, observer := range s.observers {
+		if err := observer.Observe(ctx, url, result); err != nil {
+			log.Printf("Failed to process check result for %s: %v", url.Url, err)
+		}
+	}
+
+	return err
 }
@@ -1,8 +1,14 @@
 package scheduler
 
 import (
+	"container/heap"
 	"context"
 	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -10,16 +16,27 @@ import (
 )
 
 type mockRepository struct {
+	mu   sync.Mutex
 	urls []models.MonitoredUrl
 	err  error
 }
 
 func (m *mockRepository) GetMonitoredUrls() ([]models.MonitoredUrl, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	return m.urls, m.err
 }
 
+func (m *mockRepository) setUrls(urls []models.MonitoredUrl) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.urls = urls
+}
+
 type mockChecker struct {
+	mu              sync.Mutex
 	checkResult     models.CheckResult
+	prepareErrors   map[int]error
 	insertError     error
 	checkCalls      []models.MonitoredUrl
 	insertCalls     []models.CheckResult
@@ -27,16 +44,37 @@ type mockChecker struct {
 	insertCallCount int
 }
 
-func (m *mockChecker) Check(url models.MonitoredUrl) models.CheckResult {
+func (m *mockChecker) Prepare(urls []models.MonitoredUrl) map[int]error {
+	return m.prepareErrors
+}
+
+func (m *mockChecker) Check(ctx context.Context, url models.MonitoredUrl) models.CheckResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.checkCalls = append(m.checkCalls, url)
 	m.checkCallCount++
 	return m.checkResult
 }
 
-func (m *mockChecker) InsertCheckResult(result models.CheckResult) error {
+func (m *mockChecker) InsertCheckResult(ctx context.Context, result models.CheckResult) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.insertCalls = append(m.insertCalls, result)
 	m.insertCallCount++
-	return m.insertError
+	if m.insertError != nil {
+		return 0, m.insertError
+	}
+	return m.insertCallCount, nil
+}
+
+type mockObserver struct {
+	results []models.CheckResult
+	err     error
+}
+
+func (m *mockObserver) Observe(ctx context.Context, url models.MonitoredUrl, result models.CheckResult) error {
+	m.results = append(m.results, result)
+	return m.err
 }
 
 func TestScheduler_Start_RepositoryError(t *testing.T) {
@@ -45,7 +83,7 @@ func TestScheduler_Start_RepositoryError(t *testing.T) {
 	}
 	checker := &mockChecker{}
 
-	scheduler := New(repo, nil, checker)
+	scheduler := New(repo, nil, checker, models.SchedulerConfig{})
 	ctx := context.Background()
 
 	err := scheduler.Start(ctx)
@@ -80,7 +118,7 @@ func TestScheduler_Start_HappyPath(t *testing.T) {
 	}
 	checker := &mockChecker{}
 
-	scheduler := New(repo, nil, checker)
+	scheduler := New(repo, nil, checker, models.SchedulerConfig{})
 	ctx := context.Background()
 
 	err := scheduler.Start(ctx)
@@ -99,7 +137,7 @@ func TestScheduler_Start_EmptyUrls(t *testing.T) {
 	}
 	checker := &mockChecker{}
 
-	scheduler := New(repo, nil, checker)
+	scheduler := New(repo, nil, checker, models.SchedulerConfig{})
 	ctx := context.Background()
 
 	err := scheduler.Start(ctx)
@@ -135,9 +173,9 @@ func TestScheduler_PerformCheck_HappyPath(t *testing.T) {
 	}
 
 	repo := &mockRepository{}
-	scheduler := New(repo, nil, checker)
+	scheduler := New(repo, nil, checker, models.SchedulerConfig{})
 
-	scheduler.performCheck(url)
+	scheduler.performCheck(context.Background(), url)
 
 	if checker.checkCallCount != 1 {
 		t.Errorf("Expected Check to be called once, got %d calls", checker.checkCallCount)
@@ -190,9 +228,9 @@ func TestScheduler_PerformCheck_CheckerInsertError(t *testing.T) {
 	}
 
 	repo := &mockRepository{}
-	scheduler := New(repo, nil, checker)
+	scheduler := New(repo, nil, checker, models.SchedulerConfig{})
 
-	scheduler.performCheck(url)
+	scheduler.performCheck(context.Background(), url)
 
 	if checker.checkCallCount != 1 {
 		t.Errorf("Expected Check to be called once, got %d calls", checker.checkCallCount)
@@ -236,10 +274,10 @@ func TestScheduler_PerformCheck_CheckerMultipleInteractions(t *testing.T) {
 	}
 
 	repo := &mockRepository{}
-	scheduler := New(repo, nil, checker)
+	scheduler := New(repo, nil, checker, models.SchedulerConfig{})
 
 	for _, url := range urls {
-		scheduler.performCheck(url)
+		scheduler.performCheck(context.Background(), url)
 	}
 
 	if checker.checkCallCount != 2 {
@@ -263,3 +301,699 @@ func TestScheduler_PerformCheck_CheckerMultipleInteractions(t *testing.T) {
 		}
 	}
 }
+
+func TestScheduler_PerformCheck_NotifiesObservers(t *testing.T) {
+	url := models.MonitoredUrl{
+		ID:               1,
+		Url:              "https://example.com",
+		CheckIntervalSec: 30,
+	}
+
+	checker := &mockChecker{
+		checkResult: models.CheckResult{URL: url.Url, Error: "connection refused"},
+	}
+
+	scheduler := New(&mockRepository{}, nil, checker, models.SchedulerConfig{})
+	failing := &mockObserver{err: errors.New("observer error")}
+	observer := &mockObserver{}
+	scheduler.AddObserver(failing)
+	scheduler.AddObserver(observer)
+
+	scheduler.performCheck(context.Background(), url)
+
+	if len(observer.results) != 1 {
+		t.Fatalf("Expected observer to receive 1 result, got %d", len(observer.results))
+	}
+
+	if observer.results[0].ID != 1 {
+		t.Errorf("Expected observed result to carry stored check id 1, got %d", observer.results[0].ID)
+	}
+
+	if len(failing.results) != 1 {
+		t.Errorf("Expected failing observer to receive 1 result, got %d", len(failing.results))
+	}
+}
+
+type mockRecorder struct {
+	noopRecorder
+	checksStarted  int
+	checksFinished int
+	checksSkipped  int
+	insertFailures int
+	removed        []string
+}
+
+func (m *mockRecorder) CheckStarted()  { m.checksStarted++ }
+func (m *mockRecorder) CheckFinished() { m.checksFinished++ }
+func (m *mockRecorder) InsertFailed()  { m.insertFailures++ }
+
+func (m *mockRecorder) UrlRemoved(url models.MonitoredUrl) { m.removed = append(m.removed, url.Url) }
+
+func (m *mockRecorder) CheckSkipped(_ models.MonitoredUrl, _ string, count int) {
+	m.checksSkipped += count
+}
+
+func TestScheduler_PerformCheck_RecordsEvents(t *testing.T) {
+	url := models.MonitoredUrl{
+		ID:               1,
+		Url:              "https://example.com",
+		CheckIntervalSec: 30,
+	}
+
+	checker := &mockChecker{
+		checkResult: models.CheckResult{URL: url.Url},
+		insertError: errors.New("database error"),
+	}
+
+	scheduler := New(&mockRepository{}, nil, checker, models.SchedulerConfig{})
+	recorder := &mockRecorder{}
+	scheduler.SetRecorder(recorder)
+
+	scheduler.performCheck(context.Background(), url)
+
+	if recorder.checksStarted != 1 || recorder.checksFinished != 1 {
+		t.Errorf("Expected check to be recorded as started and finished, got %+v", recorder)
+	}
+
+	if recorder.insertFailures != 1 {
+		t.Errorf("Expected 1 insert failure to be recorded, got %d", recorder.insertFailures)
+	}
+}
+
+func TestScheduler_PerformCheck_CancelledContext(t *testing.T) {
+	url := models.MonitoredUrl{
+		ID:               1,
+		Url:              "https://example.com",
+		CheckIntervalSec: 30,
+	}
+
+	checker := &mockChecker{
+		checkResult: models.CheckResult{URL: url.Url, Error: "context canceled"},
+	}
+
+	scheduler := New(&mockRepository{}, nil, checker, models.SchedulerConfig{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	scheduler.performCheck(ctx, url)
+
+	if checker.checkCallCount != 1 {
+		t.Errorf("Expected Check to be called once, got %d calls", checker.checkCallCount)
+	}
+
+	if checker.insertCallCount != 0 {
+		t.Errorf("Expected cancelled check not to be stored, got %d insert calls", checker.insertCallCount)
+	}
+}
+
+func TestScheduler_Reconcile_AddsRemovesAndRestartsMonitors(t *testing.T) {
+	checker := &mockChecker{}
+	scheduler := New(&mockRepository{}, nil, checker, models.SchedulerConfig{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	scheduler.cancel = cancel
+	defer scheduler.Stop()
+
+	scheduler.reconcile(ctx, []models.MonitoredUrl{
+		{ID: 1, Url: "https://example.com", CheckIntervalSec: 30},
+		{ID: 2, Url: "https://google.com", CheckIntervalSec: 30},
+	})
+
+	if len(scheduler.monitors) != 2 {
+		t.Fatalf("Expected 2 monitors, got %d", len(scheduler.monitors))
+	}
+
+	unchanged := scheduler.monitors[1]
+	changed := scheduler.monitors[2]
+
+	scheduler.reconcile(ctx, []models.MonitoredUrl{
+		{ID: 1, Url: "https://example.com", CheckIntervalSec: 30},
+		{ID: 2, Url: "https://google.com", CheckIntervalSec: 60, RegexPattern: "Google"},
+		{ID: 3, Url: "https://github.com", CheckIntervalSec: 30},
+	})
+
+	if len(scheduler.monitors) != 3 {
+		t.Fatalf("Expected 3 monitors, got %d", len(scheduler.monitors))
+	}
+
+	if scheduler.monitors[1] != unchanged {
+		t.Error("Expected unchanged url to keep its monitor")
+	}
+
+	if scheduler.monitors[2] == changed {
+		t.Error("Expected changed url to get a new monitor")
+	}
+
+	if !changed.stopped || changed.index != -1 {
+		t.Error("Expected old monitor of changed url to be stopped and dequeued")
+	}
+
+	if scheduler.monitors[2].url.CheckIntervalSec != 60 {
+		t.Errorf("Expected restarted monitor to use new interval, got %d", scheduler.monitors[2].url.CheckIntervalSec)
+	}
+
+	scheduler.reconcile(ctx, []models.MonitoredUrl{
+		{ID: 3, Url: "https://github.com", CheckIntervalSec: 30},
+	})
+
+	if len(scheduler.monitors) != 1 {
+		t.Fatalf("Expected 1 monitor, got %d", len(scheduler.monitors))
+	}
+
+	if !unchanged.stopped || scheduler.queue.Len() != 1 {
+		t.Errorf("Expected monitor of removed url to be stopped, got %d queued monitors", scheduler.queue.Len())
+	}
+}
+
+func TestScheduler_Reconcile_RemovesUrlsNotRecordedAnymore(t *testing.T) {
+	scheduler := New(&mockRepository{}, nil, &mockChecker{}, models.SchedulerConfig{Location: "eu-west"})
+	recorder := &mockRecorder{}
+	scheduler.SetRecorder(recorder)
+	shard := &mockShard{owned: map[int]bool{1: true, 2: true, 3: true, 4: true}}
+	scheduler.SetShard(shard)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	scheduler.cancel = cancel
+	defer scheduler.Stop()
+
+	scheduler.reconcile(ctx, []models.MonitoredUrl{
+		{ID: 1, Url: "https://example.com", CheckIntervalSec: 30},
+		{ID: 2, Url: "https://google.com", CheckIntervalSec: 30},
+		// Only checked by agents, so there is no monitor here
+		{ID: 3, Url: "https://agents.example.com", CheckIntervalSec: 30, Locations: []string{"us-east"}},
+		{ID: 4, Url: "https://github.com", CheckIntervalSec: 30},
+	})
+
+	// A changed configuration restarts the monitor of url 1, url 2 moves to another instance and url 3 is deleted
+	shard.set(map[int]bool{1: true, 3: true, 4: true})
+	scheduler.reconcile(ctx, []models.MonitoredUrl{
+		{ID: 1, Url: "https://example.com", CheckIntervalSec: 60},
+		{ID: 2, Url: "https://google.com", CheckIntervalSec: 30},
+		{ID: 4, Url: "https://github.com", CheckIntervalSec: 30},
+	})
+
+	sort.Strings(recorder.removed)
+	if !reflect.DeepEqual(recorder.removed, []string{"https://agents.example.com", "https://google.com"}) {
+		t.Errorf("Expected the deleted and moved urls to be removed, got %v", recorder.removed)
+	}
+}
+
+func TestScheduler_Reconcile_SkipsInvalidUrls(t *testing.T) {
+	checker := &mockChecker{prepareErrors: map[int]error{2: errors.New("invalid regex: regex_pattern is invalid")}}
+	scheduler := New(&mockRepository{}, nil, checker, models.SchedulerConfig{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	scheduler.cancel = cancel
+	defer scheduler.Stop()
+
+	scheduler.reconcile(ctx, []models.MonitoredUrl{
+		{ID: 1, Url: "https://example.com", CheckIntervalSec: 30},
+		{ID: 2, Url: "https://google.com", CheckIntervalSec: 30, RegexPattern: "[invalid"},
+	})
+
+	if _, ok := scheduler.monitors[2]; ok || len(scheduler.monitors) != 1 {
+		t.Errorf("Expected only the valid url to be monitored, got %d monitors", len(scheduler.monitors))
+	}
+
+	if loadErrors := scheduler.LoadErrors(); loadErrors[2] != "invalid regex: regex_pattern is invalid" || len(loadErrors) != 1 {
+		t.Errorf("Unexpected load errors %v", loadErrors)
+	}
+
+	checker.prepareErrors = nil
+	scheduler.reconcile(ctx, []models.MonitoredUrl{
+		{ID: 1, Url: "https://example.com", CheckIntervalSec: 30},
+		{ID: 2, Url: "https://google.com", CheckIntervalSec: 30, RegexPattern: "Google"},
+	})
+
+	if len(scheduler.monitors) != 2 || len(scheduler.LoadErrors()) != 0 {
+		t.Errorf("Expected fixed url to be monitored, got %d monitors and load errors %v", len(scheduler.monitors), scheduler.LoadErrors())
+	}
+}
+
+func TestScheduler_Start_ReloadsUrls(t *testing.T) {
+	repo := &mockRepository{}
+	checker := &mockChecker{}
+
+	scheduler := New(repo, nil, checker, models.SchedulerConfig{})
+	scheduler.reloadInterval = 10 * time.Millisecond
+
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer scheduler.Stop()
+
+	repo.setUrls([]models.MonitoredUrl{
+		{ID: 1, Url: "https://example.com", CheckIntervalSec: 30},
+	})
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		dueNow(scheduler)
+
+		checker.mu.Lock()
+		calls := checker.checkCallCount
+		checker.mu.Unlock()
+
+		if calls > 0 {
+			return
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Error("Expected url added after start to be checked")
+}
+
+// dueNow makes all queued monitors due right away, so tests do not wait for the phase of the urls
+func dueNow(s *Scheduler) {
+	s.mu.Lock()
+	for _, m := range s.queue {
+		m.next = time.Time{}
+	}
+	heap.Init(&s.queue)
+	s.mu.Unlock()
+
+	s.wakeDispatcher()
+}
+
+// blockingChecker holds every check until it is released and tracks how many run at the same time
+type blockingChecker struct {
+	mockChecker
+	release chan struct{}
+	active  int32
+	peak    int32
+}
+
+func (b *blockingChecker) Check(ctx context.Context, url models.MonitoredUrl) models.CheckResult {
+	active := atomic.AddInt32(&b.active, 1)
+	for {
+		peak := atomic.LoadInt32(&b.peak)
+		if active <= peak || atomic.CompareAndSwapInt32(&b.peak, peak, active) {
+			break
+		}
+	}
+
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+	}
+	atomic.AddInt32(&b.active, -1)
+
+	return b.mockChecker.Check(ctx, url)
+}
+
+func TestScheduler_Start_BoundsConcurrentChecks(t *testing.T) {
+	var urls []models.MonitoredUrl
+	for i := 1; i <= 6; i++ {
+		urls = append(urls, models.MonitoredUrl{ID: i, Url: fmt.Sprintf("https://site%d.example.com", i), CheckIntervalSec: 60})
+	}
+
+	checker := &blockingChecker{release: make(chan struct{})}
+	scheduler := New(&mockRepository{urls: urls}, nil, checker, models.SchedulerConfig{Workers: 2})
+
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer scheduler.Stop()
+	dueNow(scheduler)
+
+	for i := 0; i < len(urls); i++ {
+		select {
+		case checker.release <- struct{}{}:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected all urls to be checked, only %d were", i)
+		}
+	}
+
+	if peak := atomic.LoadInt32(&checker.peak); peak > 2 {
+		t.Errorf("Expected at most 2 checks at the same time, got %d", peak)
+	}
+}
+
+func TestScheduler_NextJob_OrdersByDueTimeAndLimitsHosts(t *testing.T) {
+	scheduler := New(&mockRepository{}, nil, &mockChecker{}, models.SchedulerConfig{PerHostLimit: 1})
+
+	now := time.Now()
+	first := &monitor{url: models.MonitoredUrl{ID: 1, Url: "https://example.com/a", CheckIntervalSec: 60}, index: -1}
+	second := &monitor{url: models.MonitoredUrl{ID: 2, Url: "https://EXAMPLE.com/b", CheckIntervalSec: 60}, index: -1}
+	other := &monitor{url: models.MonitoredUrl{ID: 3, Url: "https://google.com", CheckIntervalSec: 60}, index: -1}
+	later := &monitor{url: models.MonitoredUrl{ID: 4, Url: "https://github.com", CheckIntervalSec: 60}, index: -1}
+	scheduler.schedule(other, now.Add(-time.Second))
+	scheduler.schedule(first, now.Add(-3*time.Second))
+	scheduler.schedule(later, now.Add(time.Minute))
+	scheduler.schedule(second, now.Add(-2*time.Second))
+
+	if j, _ := scheduler.nextJob(now); j == nil || j.m != first {
+		t.Fatalf("Expected most overdue monitor first, got %+v", j)
+	}
+
+	// The second url shares the host of the first one, so it is held back
+	if j, _ := scheduler.nextJob(now); j == nil || j.m != other {
+		t.Fatalf("Expected monitor of another host, got %+v", j)
+	}
+
+	j, wait := scheduler.nextJob(now)
+	if j != nil || wait <= 0 || wait > time.Minute {
+		t.Fatalf("Expected to wait for the next due monitor, got %+v and %v", j, wait)
+	}
+
+	if first.next.Before(now) || first.index < 0 {
+		t.Errorf("Expected dispatched monitor to be queued for its next slot, got %v", first.next)
+	}
+
+	scheduler.release(hostOf(first.url))
+
+	if j, _ := scheduler.nextJob(now); j == nil || j.m != second {
+		t.Errorf("Expected held back monitor once its host is free, got %+v", j)
+	}
+}
+
+func TestScheduler_NextJob_HeldBackCheckIsNotRunning(t *testing.T) {
+	scheduler := New(&mockRepository{}, nil, &mockChecker{}, models.SchedulerConfig{PerHostLimit: 1})
+
+	now := time.Now()
+	url := models.MonitoredUrl{ID: 1, Url: "https://example.com/a", CheckIntervalSec: 60, OverlapPolicy: models.OverlapSkip}
+	m := &monitor{url: url, index: -1}
+	scheduler.running[hostOf(url)] = 1
+
+	scheduler.schedule(m, now.Add(-time.Second))
+	if j, _ := scheduler.nextJob(now); j != nil {
+		t.Fatalf("Expected check to be held back by the per-host limit, got %+v", j)
+	}
+
+	if m.active != 0 || !m.held {
+		t.Fatalf("Expected held back check not to count as running, got active %d and held %v", m.active, m.held)
+	}
+
+	// The url is due again while its check is still held back
+	scheduler.schedule(m, now.Add(-time.Second))
+	if j, _ := scheduler.nextJob(now); j != nil {
+		t.Fatalf("Expected no skipped check while the check has not started, got %+v", j)
+	}
+
+	scheduler.release(hostOf(url))
+
+	if len(scheduler.ready) != 1 || scheduler.ready[0].skipped || m.active != 1 || m.held {
+		t.Errorf("Expected held back check to run once its host is free, got %d ready jobs and active %d", len(scheduler.ready), m.active)
+	}
+}
+
+func TestScheduler_NextJob_OverlapPolicies(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        string
+		maxConcurrent int
+		active        int
+		queued        bool
+		expectCheck   bool
+		expectSkip    bool
+		expectQueued  bool
+	}{
+		{name: "idle url is checked", policy: models.OverlapSkip, expectCheck: true},
+		{name: "skip", policy: models.OverlapSkip, active: 1, expectSkip: true},
+		{name: "queue one", policy: models.OverlapQueue, active: 1, expectQueued: true},
+		{name: "queue is full", policy: models.OverlapQueue, active: 1, queued: true, expectSkip: true, expectQueued: true},
+		{name: "default is queue", active: 1, expectQueued: true},
+		{name: "concurrent below limit", policy: models.OverlapConcurrent, maxConcurrent: 3, active: 2, expectCheck: true},
+		{name: "concurrent at limit", policy: models.OverlapConcurrent, maxConcurrent: 3, active: 3, expectSkip: true},
+		{name: "concurrent default limit", policy: models.OverlapConcurrent, active: 2, expectSkip: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler := New(&mockRepository{}, nil, &mockChecker{}, models.SchedulerConfig{})
+
+			now := time.Now()
+			url := models.MonitoredUrl{ID: 1, Url: "https://example.com", CheckIntervalSec: 60, OverlapPolicy: tt.policy, MaxConcurrent: tt.maxConcurrent}
+			m := &monitor{url: url, index: -1, active: tt.active, queued: tt.queued}
+			scheduler.schedule(m, now.Add(-time.Second))
+
+			j, _ := scheduler.nextJob(now)
+
+			if tt.expectCheck != (j != nil && !j.skipped) {
+				t.Errorf("Expected check %v, got %+v", tt.expectCheck, j)
+			}
+
+			if tt.expectSkip != (j != nil && j.skipped) {
+				t.Errorf("Expected skip %v, got %+v", tt.expectSkip, j)
+			}
+
+			if j != nil && j.skipped && j.active != tt.active {
+				t.Errorf("Expected skip to record %d running checks, got %d", tt.active, j.active)
+			}
+
+			if m.queued != tt.expectQueued {
+				t.Errorf("Expected queued %v, got %v", tt.expectQueued, m.queued)
+			}
+
+			if !m.next.After(now) {
+				t.Errorf("Expected monitor to move to its next slot, got %v", m.next)
+			}
+		})
+	}
+}
+
+func TestScheduler_NextJob_RecordsSlotsMissedWhileWorkersWereBusy(t *testing.T) {
+	checker := &mockChecker{}
+	scheduler := New(&mockRepository{}, nil, checker, models.SchedulerConfig{Workers: 1})
+	recorder := &mockRecorder{}
+	scheduler.SetRecorder(recorder)
+
+	// The only worker was busy for almost three intervals, so the dispatcher gets to the url that late
+	now := time.Now()
+	url := models.MonitoredUrl{ID: 1, Url: "https://example.com", CheckIntervalSec: 60}
+	every := interval(url)
+	m := &monitor{url: url, index: -1, ctx: context.Background()}
+	scheduler.schedule(m, nextSlot(now.Add(-3*every), every, phaseOffset(url.ID, every)))
+
+	missed, _ := scheduler.nextJob(now)
+	if missed == nil || !missed.skipped || missed.missed != 2 {
+		t.Fatalf("Expected a skipped job for the 2 missed slots, got %+v", missed)
+	}
+
+	if j, _ := scheduler.nextJob(now); j == nil || j.skipped || j.m != m {
+		t.Fatalf("Expected the due check to run, got %+v", j)
+	}
+
+	if !m.next.After(now) {
+		t.Errorf("Expected monitor to skip ahead to its next slot, got %v", m.next)
+	}
+
+	scheduler.recordSkipped(context.Background(), missed)
+
+	if len(checker.insertCalls) != 1 {
+		t.Fatalf("Expected one row for the missed checks, got %d inserts", len(checker.insertCalls))
+	}
+
+	result := checker.insertCalls[0]
+	if !result.Skipped || result.Missed != 2 || result.Warning != "skipped 2 checks, no worker was free at their time" {
+		t.Errorf("Unexpected skipped check %+v", result)
+	}
+
+	if recorder.checksSkipped != 2 {
+		t.Errorf("Expected 2 skipped checks to be counted, got %d", recorder.checksSkipped)
+	}
+}
+
+func TestScheduler_Run_StartsQueuedCheck(t *testing.T) {
+	checker := &mockChecker{}
+	scheduler := New(&mockRepository{}, nil, checker, models.SchedulerConfig{})
+
+	url := models.MonitoredUrl{ID: 1, Url: "https://example.com", CheckIntervalSec: 60}
+	m := &monitor{url: url, index: -1, ctx: context.Background(), active: 1, queued: true}
+	scheduler.running[hostOf(url)] = 1
+
+	scheduler.run(m)
+
+	if checker.checkCallCount != 1 {
+		t.Errorf("Expected url to be checked once, got %d", checker.checkCallCount)
+	}
+
+	if m.queued || m.active != 1 || len(scheduler.ready) != 1 || scheduler.ready[0].m != m {
+		t.Errorf("Expected queued check to be ready, got queued %v, active %d and %d ready jobs", m.queued, m.active, len(scheduler.ready))
+	}
+}
+
+func TestScheduler_RecordSkipped_StoppedMonitor(t *testing.T) {
+	checker := &mockChecker{}
+	scheduler := New(&mockRepository{}, nil, checker, models.SchedulerConfig{})
+	recorder := &mockRecorder{}
+	scheduler.SetRecorder(recorder)
+
+	url := models.MonitoredUrl{ID: 1, Url: "https://example.com", CheckIntervalSec: 60}
+	scheduler.recordSkipped(context.Background(), &job{m: &monitor{url: url, stopped: true}, skipped: true, active: 1})
+
+	if len(checker.insertCalls) != 0 || recorder.checksSkipped != 0 {
+		t.Errorf("Expected no skipped check of a stopped monitor, got %d inserts", len(checker.insertCalls))
+	}
+}
+
+func TestScheduler_Run_SkipsUrlsNoLongerOwned(t *testing.T) {
+	checker := &mockChecker{}
+	scheduler := New(&mockRepository{}, nil, checker, models.SchedulerConfig{})
+	scheduler.SetShard(&mockShard{owned: map[int]bool{}})
+
+	url := models.MonitoredUrl{ID: 1, Url: "https://example.com", CheckIntervalSec: 60}
+	m := &monitor{url: url, index: -1, ctx: context.Background(), active: 1}
+	scheduler.running[hostOf(url)] = 1
+
+	scheduler.run(m)
+
+	if checker.checkCallCount != 0 || len(checker.insertCalls) != 0 {
+		t.Errorf("Expected url owned by another instance not to be checked, got %d checks", checker.checkCallCount)
+	}
+
+	if m.active != 0 || len(scheduler.running) != 0 {
+		t.Errorf("Expected check slot to be freed, got active %d and running %v", m.active, scheduler.running)
+	}
+}
+
+func TestScheduler_RecordSkipped(t *testing.T) {
+	checker := &mockChecker{}
+	scheduler := New(&mockRepository{}, nil, checker, models.SchedulerConfig{})
+	recorder := &mockRecorder{}
+	scheduler.SetRecorder(recorder)
+	observer := &mockObserver{}
+	scheduler.AddObserver(observer)
+
+	url := models.MonitoredUrl{ID: 1, Url: "https://example.com", CheckIntervalSec: 60}
+	scheduler.recordSkipped(context.Background(), &job{m: &monitor{url: url}, skipped: true, active: 1})
+
+	if len(checker.insertCalls) != 1 {
+		t.Fatalf("Expected skipped check to be stored, got %d inserts", len(checker.insertCalls))
+	}
+
+	result := checker.insertCalls[0]
+	if !result.Skipped || result.URL != url.Url || result.Warning != "skipped, the previous check was still running" || result.Failed() {
+		t.Errorf("Unexpected skipped check %+v", result)
+	}
+
+	if checker.checkCallCount != 0 || len(observer.results) != 0 {
+		t.Errorf("Expected skipped check neither to run nor to reach observers, got %d checks and %d observed", checker.checkCallCount, len(observer.results))
+	}
+
+	if recorder.checksSkipped != 1 {
+		t.Errorf("Expected skipped check to be recorded, got %d", recorder.checksSkipped)
+	}
+}
+
+type mockShard struct {
+	mu    sync.Mutex
+	owned map[int]bool
+}
+
+func (m *mockShard) Owns(urlID int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.owned[urlID]
+}
+
+func (m *mockShard) set(owned map[int]bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.owned = owned
+}
+
+func TestScheduler_Rebalance_MonitorsOwnedUrlsOnly(t *testing.T) {
+	repo := &mockRepository{urls: []models.MonitoredUrl{
+		{ID: 1, Url: "https://example.com", CheckIntervalSec: 30},
+		{ID: 2, Url: "https://google.com", CheckIntervalSec: 30},
+	}}
+	shard := &mockShard{owned: map[int]bool{1: true}}
+
+	scheduler := New(repo, nil, &mockChecker{}, models.SchedulerConfig{})
+	scheduler.SetShard(shard)
+
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer scheduler.Stop()
+
+	monitored := func() map[int]bool {
+		scheduler.mu.Lock()
+		defer scheduler.mu.Unlock()
+
+		ids := map[int]bool{}
+		for id := range scheduler.monitors {
+			ids[id] = true
+		}
+
+		return ids
+	}
+
+	if ids := monitored(); !ids[1] || ids[2] {
+		t.Fatalf("Expected only the owned url to be monitored, got %v", ids)
+	}
+
+	// Without a reload interval, only the rebalance makes the scheduler notice the move
+	shard.set(map[int]bool{2: true})
+	scheduler.Rebalance()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if ids := monitored(); !ids[1] && ids[2] {
+			return
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Errorf("Expected monitoring to follow the moved urls, got %v", monitored())
+}
+
+func TestScheduler_Reconcile_MonitorsUrlsOfItsLocation(t *testing.T) {
+	scheduler := New(&mockRepository{}, nil, &mockChecker{}, models.SchedulerConfig{Location: "eu-west"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	scheduler.cancel = cancel
+	defer scheduler.Stop()
+
+	scheduler.reconcile(ctx, []models.MonitoredUrl{
+		{ID: 1, Url: "https://example.com", CheckIntervalSec: 30},
+		{ID: 2, Url: "https://google.com", CheckIntervalSec: 30, Locations: []string{"eu-west", "us-east"}},
+		{ID: 3, Url: "https://github.com", CheckIntervalSec: 30, Locations: []string{"us-east"}},
+	})
+
+	if len(scheduler.monitors) != 2 || scheduler.monitors[1] == nil || scheduler.monitors[2] == nil {
+		t.Errorf("Expected urls without locations and urls of eu-west to be monitored, got %v", scheduler.monitors)
+	}
+}
+
+func TestScheduler_PerformCheck_RecordsLocation(t *testing.T) {
+	url := models.MonitoredUrl{ID: 1, Url: "https://example.com", CheckIntervalSec: 30}
+	checker := &mockChecker{checkResult: models.CheckResult{URL: url.Url}}
+	scheduler := New(&mockRepository{}, nil, checker, models.SchedulerConfig{Location: "eu-west"})
+	observer := &mockObserver{}
+	scheduler.AddObserver(observer)
+
+	scheduler.performCheck(context.Background(), url)
+
+	if len(checker.insertCalls) != 1 || checker.insertCalls[0].Location != "eu-west" {
+		t.Fatalf("Expected stored result of eu-west, got %+v", checker.insertCalls)
+	}
+
+	if len(observer.results) != 1 || observer.results[0].Location != "eu-west" {
+		t.Errorf("Expected observed result of eu-west, got %+v", observer.results)
+	}
+}
+
+func TestScheduler_Record_StoresSkippedChecksOnly(t *testing.T) {
+	url := models.MonitoredUrl{ID: 1, Url: "https://example.com", CheckIntervalSec: 30}
+	checker := &mockChecker{insertError: errors.New("insert error")}
+	scheduler := New(&mockRepository{}, nil, checker, models.SchedulerConfig{})
+	observer := &mockObserver{}
+	scheduler.AddObserver(observer)
+
+	if err := scheduler.Record(context.Background(), url, models.CheckResult{URL: url.Url, Skipped: true}); err == nil {
+		t.Error("Expected the insert error to be returned")
+	}
+
+	if len(checker.insertCalls) != 1 {
+		t.Errorf("Expected skipped check to be stored, got %d inserts", len(checker.insertCalls))
+	}
+
+	if len(observer.results) != 0 {
+		t.Errorf("Expected skipped check not to be observed, got %d results", len(observer.results))
+	}
+}
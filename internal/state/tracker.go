@@ -0,0 +1,386 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"website-monitor/internal/incident_repository"
+	"website-monitor/internal/models"
+)
+
+// TransitionHandler defines the interface for components that react to state changes of monitored urls
+type TransitionHandler interface {
+	HandleTransition(ctx context.Context, transition Transition)
+}
+
+// Shard decides which urls are tracked by this instance when several instances share the database
+type Shard interface {
+	Owns(urlID int) bool
+}
+
+// Tracker computes the up/down state of monitored urls from their check results and records incidents
+type Tracker struct {
+	repo              incident_repository.IncidentRepository
+	failureThreshold  int
+	recoveryThreshold int
+	handlers          []TransitionHandler
+	shard             Shard
+
+	mu   sync.Mutex
+	urls map[int]*urlState
+}
+
+// staleIntervals is after how many check intervals without a result a location no longer counts towards the state of the url,
+// e.g. because its agent stopped
+const staleIntervals = 3
+
+// urlState holds the state of a single url, combined from the states of the locations it is checked from
+type urlState struct {
+	mu        sync.Mutex
+	state     models.State
+	incident  *models.Incident
+	locations map[string]*locationState
+	// restored is set for urls restored from an open incident until their first check result
+	restored bool
+}
+
+// locationState holds the state machine of a url at a single location
+type locationState struct {
+	state                models.State
+	consecutiveFailures  int
+	consecutiveSuccesses int
+	firstFailure         models.CheckResult
+	// lastSeen is the timestamp of the latest check at the location
+	lastSeen time.Time
+}
+
+// Transition describes a change of state of a monitored url
+type Transition struct {
+	Url    models.MonitoredUrl
+	From   models.State
+	To     models.State
+	Result models.CheckResult
+	// Incident is the incident opened or closed by the transition, if any
+	Incident *models.Incident
+}
+
+func New(repo incident_repository.IncidentRepository, cfg models.StateConfig) *Tracker {
+	return &Tracker{
+		repo:              repo,
+		failureThreshold:  cfg.FailureThreshold,
+		recoveryThreshold: cfg.RecoveryThreshold,
+		urls:              make(map[int]*urlState),
+	}
+}
+
+// OnTransition registers a handler that is called on every state change. It must be called before checks are observed
+func (t *Tracker) OnTransition(handler TransitionHandler) {
+	t.handlers = append(t.handlers, handler)
+}
+
+// SetShard limits the tracker to the urls owned by this instance. It must be called before Load
+func (t *Tracker) SetShard(shard Shard) {
+	t.shard = shard
+}
+
+// Load restores the down state of urls with open incidents, so a restart does not lose ongoing incidents.
+// With a shard it has to be called again whenever urls move between instances: it forgets urls that moved away,
+// as their state is kept by their new owner, and restores open incidents of urls that moved here
+func (t *Tracker) Load(ctx context.Context) error {
+	incidents, err := t.repo.GetOpenIncidents(ctx)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for urlID := range t.urls {
+		if !t.owns(urlID) {
+			delete(t.urls, urlID)
+		}
+	}
+
+	for i := range incidents {
+		urlID := incidents[i].UrlID
+		// Urls tracked already are up to date, only their owner opens and closes their incidents
+		if _, ok := t.urls[urlID]; ok || !t.owns(urlID) {
+			continue
+		}
+
+		t.urls[urlID] = &urlState{
+			state:    models.StateDown,
+			incident: &incidents[i],
+			restored: true,
+		}
+	}
+
+	return nil
+}
+
+// owns reports whether the url is tracked by this instance
+func (t *Tracker) owns(urlID int) bool {
+	return t.shard == nil || t.shard.Owns(urlID)
+}
+
+// State returns the current state of the url
+func (t *Tracker) State(urlID int) models.State {
+	s := t.get(urlID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state
+}
+
+// Observe feeds a check result into the state machine of the url, opening or closing incidents on transitions
+func (t *Tracker) Observe(ctx context.Context, url models.MonitoredUrl, result models.CheckResult) error {
+	// A check that was running while its url moved to another instance is left to the new owner
+	if !t.owns(url.ID) {
+		return nil
+	}
+
+	s := t.get(url.ID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transition := t.next(s, url, result)
+	if transition == nil {
+		return nil
+	}
+
+	log.Printf("State of %s changed from %s to %s", url.Url, transition.From, transition.To)
+
+	err := t.persist(ctx, s, transition)
+
+	// Handlers are called even if the incident could not be stored, the state change itself did happen
+	for _, handler := range t.handlers {
+		handler.HandleTransition(ctx, *transition)
+	}
+
+	return err
+}
+
+// get returns the state of the url, creating it on first use
+func (t *Tracker) get(urlID int) *urlState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.urls[urlID]
+	if !ok {
+		s = &urlState{state: models.StateUnknown}
+		t.urls[urlID] = s
+	}
+
+	return s
+}
+
+// next advances the state machine of the location of the result, combines the states of all locations
+// and returns the resulting transition, or nil if the state of the url did not change
+func (t *Tracker) next(s *urlState, url models.MonitoredUrl, result models.CheckResult) *Transition {
+	// An agent may still report a location the url was just taken away from
+	if len(url.Locations) > 0 && !url.HasLocation(result.Location) {
+		return nil
+	}
+
+	if s.restored {
+		s.restore(url, result)
+	}
+
+	from := s.state
+	t.advance(s.location(result.Location), result)
+	s.state = combine(s, url, result)
+
+	if s.state == from {
+		return nil
+	}
+
+	return &Transition{
+		Url:    url,
+		From:   from,
+		To:     s.state,
+		Result: result,
+	}
+}
+
+// location returns the state machine of the url at the location, creating it on first use
+func (s *urlState) location(name string) *locationState {
+	if s.locations == nil {
+		s.locations = make(map[string]*locationState)
+	}
+
+	l, ok := s.locations[name]
+	if !ok {
+		l = &locationState{state: models.StateUnknown}
+		s.locations[name] = l
+	}
+
+	return l
+}
+
+// restore marks every location of a url restored from an open incident as down, so the url stays down
+// until as many locations recover as would have closed the incident before the restart
+func (s *urlState) restore(url models.MonitoredUrl, result models.CheckResult) {
+	names := url.Locations
+	if len(names) == 0 {
+		names = []string{result.Location}
+	}
+
+	s.locations = make(map[string]*locationState, len(names))
+	for _, name := range names {
+		// Locations that never report again go stale like any other
+		s.locations[name] = &locationState{state: models.StateDown, lastSeen: result.CheckTimestamp}
+	}
+	s.restored = false
+}
+
+// advance feeds the result into the state machine of a location
+func (t *Tracker) advance(l *locationState, result models.CheckResult) {
+	l.lastSeen = result.CheckTimestamp
+
+	if result.Failed() {
+		l.consecutiveSuccesses = 0
+		l.consecutiveFailures++
+		if l.consecutiveFailures == 1 {
+			l.firstFailure = result
+		}
+
+		switch {
+		case l.state == models.StateDown:
+		case l.consecutiveFailures >= t.failureThreshold:
+			l.state = models.StateDown
+		default:
+			l.state = models.StateDegraded
+		}
+
+		return
+	}
+
+	l.consecutiveFailures = 0
+	l.consecutiveSuccesses++
+
+	if l.state != models.StateDown || l.consecutiveSuccesses >= t.recoveryThreshold {
+		// A passing check with a warning, e.g. an expiring certificate, keeps the url degraded
+		if result.Warning != "" {
+			l.state = models.StateDegraded
+		} else {
+			l.state = models.StateUp
+		}
+	}
+}
+
+// combine derives the state of the url from its locations: down once the quorum of locations is down,
+// degraded while any other location fails or is degraded, and up otherwise. Locations that stopped reporting
+// are left out, and locations the url is no longer checked from are forgotten
+func combine(s *urlState, url models.MonitoredUrl, result models.CheckResult) models.State {
+	staleAfter := staleIntervals * time.Duration(url.CheckIntervalSec) * time.Second
+
+	down, failing, up := 0, 0, 0
+	for name, l := range s.locations {
+		// Without locations the url is checked by the monitor alone, so only its latest location counts
+		assigned := url.HasLocation(name) || (len(url.Locations) == 0 && name == result.Location)
+		if !assigned {
+			delete(s.locations, name)
+
+			continue
+		}
+
+		if result.CheckTimestamp.Sub(l.lastSeen) > staleAfter {
+			continue
+		}
+
+		switch l.state {
+		case models.StateDown:
+			down++
+		case models.StateDegraded:
+			failing++
+		case models.StateUp:
+			up++
+		}
+	}
+
+	switch {
+	case down >= url.LocationQuorum():
+		return models.StateDown
+	case down > 0 || failing > 0:
+		return models.StateDegraded
+	case up > 0:
+		return models.StateUp
+	}
+
+	return s.state
+}
+
+// firstFailure returns the earliest first failure of the locations that are down, it is the start of the incident
+func (s *urlState) firstFailure() models.CheckResult {
+	var first models.CheckResult
+	for _, l := range s.locations {
+		if l.state != models.StateDown || l.firstFailure.CheckTimestamp.IsZero() {
+			continue
+		}
+
+		if first.CheckTimestamp.IsZero() || l.firstFailure.CheckTimestamp.Before(first.CheckTimestamp) {
+			first = l.firstFailure
+		}
+	}
+
+	return first
+}
+
+// persist opens an incident when the url goes down and closes it when the url recovers
+func (t *Tracker) persist(ctx context.Context, s *urlState, transition *Transition) error {
+	switch {
+	case transition.To == models.StateDown:
+		firstFailure := s.firstFailure()
+		incident := models.Incident{
+			UrlID:     transition.Url.ID,
+			URL:       transition.Url.Url,
+			StartedAt: firstFailure.CheckTimestamp,
+			Cause:     failureCause(firstFailure),
+		}
+		if firstFailure.ID != 0 {
+			firstCheckID := firstFailure.ID
+			incident.FirstCheckID = &firstCheckID
+		}
+
+		id, err := t.repo.OpenIncident(ctx, incident)
+		if err != nil {
+			return fmt.Errorf("failed to open incident for %s: %w", transition.Url.Url, err)
+		}
+
+		incident.ID = id
+		s.incident = &incident
+		transition.Incident = &incident
+	case transition.From == models.StateDown && s.incident != nil:
+		incident := s.incident
+		s.incident = nil
+
+		endedAt := transition.Result.CheckTimestamp
+		if err := t.repo.CloseIncident(ctx, incident.ID, endedAt); err != nil {
+			return fmt.Errorf("failed to close incident for %s: %w", transition.Url.Url, err)
+		}
+
+		incident.EndedAt = &endedAt
+		transition.Incident = incident
+	}
+
+	return nil
+}
+
+// failureCause describes why a check failed and where, if the check ran at a location
+func failureCause(result models.CheckResult) string {
+	cause := "regex did not match"
+	if result.Error != "" {
+		cause = result.Error
+	}
+
+	if result.Location != "" {
+		return fmt.Sprintf("%s: %s", result.Location, cause)
+	}
+
+	return cause
+}
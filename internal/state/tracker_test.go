@@ -0,0 +1,405 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"website-monitor/internal/models"
+)
+
+type mockIncidentRepository struct {
+	open      []models.Incident
+	openErr   error
+	opened    []models.Incident
+	closedIDs []int
+	nextID    int
+}
+
+func (m *mockIncidentRepository) GetOpenIncidents(ctx context.Context) ([]models.Incident, error) {
+	return m.open, m.openErr
+}
+
+func (m *mockIncidentRepository) OpenIncident(ctx context.Context, incident models.Incident) (int, error) {
+	m.nextID++
+	incident.ID = m.nextID
+	m.opened = append(m.opened, incident)
+	return m.nextID, nil
+}
+
+func (m *mockIncidentRepository) CloseIncident(ctx context.Context, id int, endedAt time.Time) error {
+	m.closedIDs = append(m.closedIDs, id)
+	return nil
+}
+
+type mockHandler struct {
+	transitions []Transition
+}
+
+func (m *mockHandler) HandleTransition(ctx context.Context, transition Transition) {
+	m.transitions = append(m.transitions, transition)
+}
+
+var testUrl = models.MonitoredUrl{ID: 1, Url: "https://example.com", CheckIntervalSec: 10}
+
+func successResult() models.CheckResult {
+	status := 200
+	return models.CheckResult{URL: testUrl.Url, CheckTimestamp: time.Now(), HttpStatus: &status}
+}
+
+func failureResult(id int) models.CheckResult {
+	return models.CheckResult{ID: id, URL: testUrl.Url, CheckTimestamp: time.Now(), Error: "connection refused"}
+}
+
+func TestTracker_Observe_GoesDownAfterThreshold(t *testing.T) {
+	repo := &mockIncidentRepository{}
+	tracker := New(repo, models.StateConfig{FailureThreshold: 3, RecoveryThreshold: 2})
+	ctx := context.Background()
+
+	_ = tracker.Observe(ctx, testUrl, successResult())
+	if state := tracker.State(testUrl.ID); state != models.StateUp {
+		t.Fatalf("Expected state UP, got %s", state)
+	}
+
+	_ = tracker.Observe(ctx, testUrl, failureResult(10))
+	_ = tracker.Observe(ctx, testUrl, failureResult(11))
+	if state := tracker.State(testUrl.ID); state != models.StateDegraded {
+		t.Fatalf("Expected state DEGRADED below threshold, got %s", state)
+	}
+
+	if len(repo.opened) != 0 {
+		t.Fatalf("Expected no incident below threshold, got %d", len(repo.opened))
+	}
+
+	_ = tracker.Observe(ctx, testUrl, failureResult(12))
+	if state := tracker.State(testUrl.ID); state != models.StateDown {
+		t.Fatalf("Expected state DOWN, got %s", state)
+	}
+
+	if len(repo.opened) != 1 {
+		t.Fatalf("Expected 1 incident, got %d", len(repo.opened))
+	}
+
+	incident := repo.opened[0]
+	if incident.FirstCheckID == nil || *incident.FirstCheckID != 10 {
+		t.Errorf("Expected incident to reference first failing check 10, got %v", incident.FirstCheckID)
+	}
+
+	if incident.Cause != "connection refused" {
+		t.Errorf("Expected cause 'connection refused', got %s", incident.Cause)
+	}
+
+	_ = tracker.Observe(ctx, testUrl, failureResult(13))
+	if len(repo.opened) != 1 {
+		t.Errorf("Expected no new incident while down, got %d", len(repo.opened))
+	}
+}
+
+func TestTracker_Observe_RecoversAfterThreshold(t *testing.T) {
+	repo := &mockIncidentRepository{}
+	tracker := New(repo, models.StateConfig{FailureThreshold: 1, RecoveryThreshold: 2})
+	ctx := context.Background()
+
+	_ = tracker.Observe(ctx, testUrl, failureResult(1))
+	if state := tracker.State(testUrl.ID); state != models.StateDown {
+		t.Fatalf("Expected state DOWN, got %s", state)
+	}
+
+	_ = tracker.Observe(ctx, testUrl, successResult())
+	if state := tracker.State(testUrl.ID); state != models.StateDown {
+		t.Fatalf("Expected state to stay DOWN below recovery threshold, got %s", state)
+	}
+
+	_ = tracker.Observe(ctx, testUrl, successResult())
+	if state := tracker.State(testUrl.ID); state != models.StateUp {
+		t.Fatalf("Expected state UP, got %s", state)
+	}
+
+	if len(repo.closedIDs) != 1 || repo.closedIDs[0] != 1 {
+		t.Errorf("Expected incident 1 to be closed, got %v", repo.closedIDs)
+	}
+}
+
+func TestTracker_Observe_RegexMismatchIsFailure(t *testing.T) {
+	repo := &mockIncidentRepository{}
+	tracker := New(repo, models.StateConfig{FailureThreshold: 1, RecoveryThreshold: 1})
+
+	result := successResult()
+	match := false
+	result.RegexMatch = &match
+
+	_ = tracker.Observe(context.Background(), testUrl, result)
+
+	if state := tracker.State(testUrl.ID); state != models.StateDown {
+		t.Fatalf("Expected state DOWN, got %s", state)
+	}
+
+	if repo.opened[0].Cause != "regex did not match" {
+		t.Errorf("Expected regex cause, got %s", repo.opened[0].Cause)
+	}
+}
+
+func TestTracker_Load_RestoresOpenIncidents(t *testing.T) {
+	repo := &mockIncidentRepository{
+		open: []models.Incident{{ID: 5, UrlID: testUrl.ID, URL: testUrl.Url, Cause: "timeout"}},
+	}
+	tracker := New(repo, models.StateConfig{FailureThreshold: 1, RecoveryThreshold: 1})
+
+	if err := tracker.Load(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if state := tracker.State(testUrl.ID); state != models.StateDown {
+		t.Fatalf("Expected restored state DOWN, got %s", state)
+	}
+
+	_ = tracker.Observe(context.Background(), testUrl, successResult())
+
+	if len(repo.closedIDs) != 1 || repo.closedIDs[0] != 5 {
+		t.Errorf("Expected restored incident 5 to be closed, got %v", repo.closedIDs)
+	}
+}
+
+func TestTracker_Load_KeepsRestoredIncidentUntilQuorumRecovers(t *testing.T) {
+	repo := &mockIncidentRepository{
+		open: []models.Incident{{ID: 42, UrlID: testUrl.ID, URL: testUrl.Url, Cause: "eu-west: timeout"}},
+	}
+	tracker := New(repo, models.StateConfig{FailureThreshold: 1, RecoveryThreshold: 1})
+	ctx := context.Background()
+	url := testUrl
+	url.Locations = []string{"eu-west", "us-east", "ap-south"}
+	now := time.Now()
+
+	if err := tracker.Load(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	_ = tracker.Observe(ctx, url, locationResult("eu-west", true, now))
+	if state := tracker.State(url.ID); state != models.StateDown {
+		t.Fatalf("Expected restored state DOWN after a failure at one location, got %s", state)
+	}
+
+	_ = tracker.Observe(ctx, url, locationResult("eu-west", false, now.Add(time.Second)))
+	if state := tracker.State(url.ID); state != models.StateDown {
+		t.Fatalf("Expected state DOWN while 2 of 3 locations are still down, got %s", state)
+	}
+
+	if len(repo.closedIDs) != 0 {
+		t.Fatalf("Expected restored incident to stay open, got closed %v", repo.closedIDs)
+	}
+
+	_ = tracker.Observe(ctx, url, locationResult("us-east", false, now.Add(2*time.Second)))
+	if state := tracker.State(url.ID); state != models.StateDegraded {
+		t.Fatalf("Expected state DEGRADED below quorum, got %s", state)
+	}
+
+	if len(repo.closedIDs) != 1 || repo.closedIDs[0] != 42 {
+		t.Errorf("Expected restored incident 42 to be closed, got %v", repo.closedIDs)
+	}
+}
+
+func TestTracker_Load_RepositoryError(t *testing.T) {
+	repo := &mockIncidentRepository{openErr: errors.New("repository error")}
+	tracker := New(repo, models.StateConfig{FailureThreshold: 1, RecoveryThreshold: 1})
+
+	if err := tracker.Load(context.Background()); err == nil {
+		t.Fatal("Expected error from repository")
+	}
+}
+
+func TestTracker_Observe_CallsTransitionHandlers(t *testing.T) {
+	repo := &mockIncidentRepository{}
+	tracker := New(repo, models.StateConfig{FailureThreshold: 2, RecoveryThreshold: 1})
+	handler := &mockHandler{}
+	tracker.OnTransition(handler)
+	ctx := context.Background()
+
+	_ = tracker.Observe(ctx, testUrl, successResult())
+	_ = tracker.Observe(ctx, testUrl, successResult())
+	_ = tracker.Observe(ctx, testUrl, failureResult(1))
+	_ = tracker.Observe(ctx, testUrl, failureResult(2))
+	_ = tracker.Observe(ctx, testUrl, successResult())
+
+	expected := []struct{ from, to models.State }{
+		{models.StateUnknown, models.StateUp},
+		{models.StateUp, models.StateDegraded},
+		{models.StateDegraded, models.StateDown},
+		{models.StateDown, models.StateUp},
+	}
+
+	if len(handler.transitions) != len(expected) {
+		t.Fatalf("Expected %d transitions, got %d", len(expected), len(handler.transitions))
+	}
+
+	for i, exp := range expected {
+		if handler.transitions[i].From != exp.from || handler.transitions[i].To != exp.to {
+			t.Errorf("Expected transition %d from %s to %s, got %s to %s", i, exp.from, exp.to, handler.transitions[i].From, handler.transitions[i].To)
+		}
+	}
+
+	down := handler.transitions[2]
+	if down.Incident == nil || down.Incident.ID != 1 {
+		t.Errorf("Expected DOWN transition to carry incident 1, got %+v", down.Incident)
+	}
+
+	up := handler.transitions[3]
+	if up.Incident == nil || up.Incident.EndedAt == nil {
+		t.Errorf("Expected recovery transition to carry the closed incident, got %+v", up.Incident)
+	}
+}
+
+func TestTracker_Observe_WarningDegradesWithoutIncident(t *testing.T) {
+	repo := &mockIncidentRepository{}
+	tracker := New(repo, models.StateConfig{FailureThreshold: 1, RecoveryThreshold: 1})
+	ctx := context.Background()
+
+	warning := successResult()
+	warning.Warning = "TLS certificate expires in 5 days, critical threshold is 7 days"
+
+	_ = tracker.Observe(ctx, testUrl, successResult())
+	_ = tracker.Observe(ctx, testUrl, warning)
+	if state := tracker.State(testUrl.ID); state != models.StateDegraded {
+		t.Fatalf("Expected state DEGRADED for a warning, got %s", state)
+	}
+
+	if len(repo.opened) != 0 {
+		t.Errorf("Expected no incident for a warning, got %d", len(repo.opened))
+	}
+
+	_ = tracker.Observe(ctx, testUrl, failureResult(10))
+	_ = tracker.Observe(ctx, testUrl, warning)
+	if state := tracker.State(testUrl.ID); state != models.StateDegraded {
+		t.Fatalf("Expected recovered url with a warning to be DEGRADED, got %s", state)
+	}
+
+	if len(repo.closedIDs) != 1 {
+		t.Errorf("Expected incident to be closed on recovery, got %v", repo.closedIDs)
+	}
+
+	_ = tracker.Observe(ctx, testUrl, successResult())
+	if state := tracker.State(testUrl.ID); state != models.StateUp {
+		t.Errorf("Expected state UP once the warning is gone, got %s", state)
+	}
+}
+
+type mockShard struct {
+	owned map[int]bool
+}
+
+func (m *mockShard) Owns(urlID int) bool {
+	return m.owned[urlID]
+}
+
+func TestTracker_Load_FollowsShard(t *testing.T) {
+	other := models.MonitoredUrl{ID: 2, Url: "https://google.com", CheckIntervalSec: 10}
+	repo := &mockIncidentRepository{
+		open: []models.Incident{
+			{ID: 5, UrlID: testUrl.ID, URL: testUrl.Url, Cause: "timeout"},
+			{ID: 6, UrlID: other.ID, URL: other.Url, Cause: "timeout"},
+		},
+	}
+	shard := &mockShard{owned: map[int]bool{testUrl.ID: true}}
+	tracker := New(repo, models.StateConfig{FailureThreshold: 1, RecoveryThreshold: 1})
+	tracker.SetShard(shard)
+
+	if err := tracker.Load(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if _, ok := tracker.urls[other.ID]; ok {
+		t.Error("Expected incident of url owned by another instance not to be restored")
+	}
+
+	// Results of urls owned by another instance are ignored
+	_ = tracker.Observe(context.Background(), other, successResult())
+	if len(repo.closedIDs) != 0 {
+		t.Errorf("Expected no incident to be closed, got %v", repo.closedIDs)
+	}
+
+	// The urls swap owners, the incident of the url that moved away was closed by its new owner meanwhile
+	shard.owned = map[int]bool{other.ID: true}
+	repo.open = repo.open[1:]
+
+	if err := tracker.Load(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if _, ok := tracker.urls[testUrl.ID]; ok {
+		t.Error("Expected state of url that moved away to be dropped")
+	}
+
+	if state := tracker.State(other.ID); state != models.StateDown {
+		t.Errorf("Expected open incident of url that moved here to be restored, got %s", state)
+	}
+}
+
+func locationResult(location string, failed bool, timestamp time.Time) models.CheckResult {
+	result := models.CheckResult{URL: testUrl.Url, CheckTimestamp: timestamp, Location: location}
+	if failed {
+		result.Error = "connection refused"
+	}
+	return result
+}
+
+func TestTracker_Observe_GoesDownOnQuorumOfLocations(t *testing.T) {
+	repo := &mockIncidentRepository{}
+	tracker := New(repo, models.StateConfig{FailureThreshold: 1, RecoveryThreshold: 1})
+	ctx := context.Background()
+	url := testUrl
+	url.Locations = []string{"eu-west", "us-east", "ap-south"}
+	now := time.Now()
+
+	for _, location := range url.Locations {
+		_ = tracker.Observe(ctx, url, locationResult(location, false, now))
+	}
+
+	_ = tracker.Observe(ctx, url, locationResult("eu-west", true, now.Add(time.Second)))
+	if state := tracker.State(url.ID); state != models.StateDegraded {
+		t.Fatalf("Expected state DEGRADED while down at a single location, got %s", state)
+	}
+
+	_ = tracker.Observe(ctx, url, locationResult("us-east", true, now.Add(2*time.Second)))
+	if state := tracker.State(url.ID); state != models.StateDown {
+		t.Fatalf("Expected state DOWN at 2 of 3 locations, got %s", state)
+	}
+
+	if len(repo.opened) != 1 || repo.opened[0].Cause != "eu-west: connection refused" {
+		t.Fatalf("Expected incident caused at eu-west, got %+v", repo.opened)
+	}
+
+	_ = tracker.Observe(ctx, url, locationResult("us-east", false, now.Add(3*time.Second)))
+	if state := tracker.State(url.ID); state != models.StateDegraded {
+		t.Fatalf("Expected state DEGRADED below quorum, got %s", state)
+	}
+
+	if len(repo.closedIDs) != 1 {
+		t.Errorf("Expected incident to be closed below quorum, got %v", repo.closedIDs)
+	}
+}
+
+func TestTracker_Observe_IgnoresStaleAndUnassignedLocations(t *testing.T) {
+	repo := &mockIncidentRepository{}
+	tracker := New(repo, models.StateConfig{FailureThreshold: 1, RecoveryThreshold: 1})
+	ctx := context.Background()
+	url := testUrl
+	url.Locations = []string{"eu-west", "us-east", "ap-south"}
+	url.Quorum = 1
+	now := time.Now()
+
+	_ = tracker.Observe(ctx, url, locationResult("moon", true, now))
+	if state := tracker.State(url.ID); state != models.StateUnknown {
+		t.Fatalf("Expected results of unassigned locations to be ignored, got %s", state)
+	}
+
+	_ = tracker.Observe(ctx, url, locationResult("eu-west", true, now))
+	if state := tracker.State(url.ID); state != models.StateDown {
+		t.Fatalf("Expected state DOWN with a quorum of 1, got %s", state)
+	}
+
+	// eu-west stopped reporting for more than 3 intervals
+	_ = tracker.Observe(ctx, url, locationResult("us-east", false, now.Add(time.Minute)))
+	if state := tracker.State(url.ID); state != models.StateUp {
+		t.Fatalf("Expected stale location to be left out, got %s", state)
+	}
+}
@@ -1,12 +1,25 @@
 package url_repository
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"website-monitor/internal/db"
 	"website-monitor/internal/models"
+
+	"github.com/lib/pq"
 )
 
+// monitoredUrlColumns lists the monitored_urls columns in the order expected by scanMonitoredUrl
+const monitoredUrlColumns = `id, url, check_interval_sec, COALESCE(regex_pattern, ''), method, headers, COALESCE(body, ''), COALESCE(expected_status_codes, ''), paused, json_assertions, COALESCE(max_body_bytes, 0),
+	retry_attempts, retry_delay_ms, COALESCE(retry_on, ''), COALESCE(redirect_policy, ''), COALESCE(max_redirects, 0),
+	COALESCE(timeout_ms, 0), COALESCE(slow_threshold_ms, 0), auth, COALESCE(overlap_policy, ''), COALESCE(max_concurrent, 0), locations, COALESCE(quorum, 0)`
+
+// uniqueViolation is the Postgres error code of a unique constraint violation
+const uniqueViolation = "23505"
+
 // DbUrlRepository implements UrlRepository using database as the data source
 type DbUrlRepository struct {
 	db *db.DB
@@ -18,11 +31,320 @@ func New(database *db.DB) *DbUrlRepository {
 	}
 }
 
-// GetMonitoredUrls returns all URLs that should be monitored from the database
+// GetMonitoredUrls returns all URLs that should be monitored from the database, paused urls are skipped
 func (r *DbUrlRepository) GetMonitoredUrls() ([]models.MonitoredUrl, error) {
-	query := `SELECT id, url, check_interval_sec, COALESCE(regex_pattern, '') FROM monitored_urls`
+	return r.queryMonitoredUrls(context.Background(), `SELECT `+monitoredUrlColumns+` FROM monitored_urls WHERE NOT paused`)
+}
+
+// assertionColumns lists the url_assertions columns in the order expected by scanAssertion
+const assertionColumns = `url_id, type, COALESCE(header, ''), COALESCE(value, ''), min_bytes, max_bytes`
+
+// ListMonitoredUrls returns all monitored urls ordered by id
+func (r *DbUrlRepository) ListMonitoredUrls(ctx context.Context) ([]models.MonitoredUrl, error) {
+	return r.queryMonitoredUrls(ctx, `SELECT `+monitoredUrlColumns+` FROM monitored_urls ORDER BY id`)
+}
+
+// GetMonitoredUrl returns a single monitored url or ErrNotFound
+func (r *DbUrlRepository) GetMonitoredUrl(ctx context.Context, id int) (models.MonitoredUrl, error) {
+	query := `SELECT ` + monitoredUrlColumns + ` FROM monitored_urls WHERE id = $1`
+
+	url, err := scanMonitoredUrl(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return url, ErrNotFound
+	}
+	if err != nil {
+		return url, fmt.Errorf("failed to get monitored url %d: %w", id, err)
+	}
+
+	urls := []models.MonitoredUrl{url}
+	if err := r.loadAssertions(ctx, urls); err != nil {
+		return url, err
+	}
+
+	return urls[0], nil
+}
+
+// CreateMonitoredUrl stores a new monitored url with its assertions and returns it with its id
+func (r *DbUrlRepository) CreateMonitoredUrl(ctx context.Context, url models.MonitoredUrl) (models.MonitoredUrl, error) {
+	err := r.db.WithTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		url, err = createMonitoredUrl(ctx, tx, url)
+
+		return err
+	})
+
+	return url, err
+}
+
+// UpdateMonitoredUrl replaces the monitored url with the same id and its assertions or returns ErrNotFound
+func (r *DbUrlRepository) UpdateMonitoredUrl(ctx context.Context, url models.MonitoredUrl) (models.MonitoredUrl, error) {
+	err := r.db.WithTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		url, err = updateMonitoredUrl(ctx, tx, url)
+
+		return err
+	})
+
+	return url, err
+}
+
+// DeleteMonitoredUrl removes the monitored url or returns ErrNotFound
+func (r *DbUrlRepository) DeleteMonitoredUrl(ctx context.Context, id int) error {
+	return deleteMonitoredUrl(ctx, r.db, id)
+}
+
+// ApplyChanges creates, updates and deletes monitored urls in a single transaction
+func (r *DbUrlRepository) ApplyChanges(ctx context.Context, changes Changes) error {
+	return r.db.WithTx(ctx, func(tx *sql.Tx) error {
+		// Deletes go first, so a url can be removed and added back under a new configuration without a conflict
+		for _, id := range changes.Delete {
+			if err := deleteMonitoredUrl(ctx, tx, id); err != nil {
+				return err
+			}
+		}
+
+		for _, url := range changes.Update {
+			if _, err := updateMonitoredUrl(ctx, tx, url); err != nil {
+				return err
+			}
+		}
+
+		for _, url := range changes.Create {
+			if _, err := createMonitoredUrl(ctx, tx, url); err != nil {
+				return fmt.Errorf("%s: %w", url.Url, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// queryRower is implemented by both *db.DB and *sql.Tx, so deletes can run inside or outside a transaction
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// createMonitoredUrl stores a new monitored url with its assertions and returns it with its id
+func createMonitoredUrl(ctx context.Context, tx *sql.Tx, url models.MonitoredUrl) (models.MonitoredUrl, error) {
+	query := `
+		INSERT INTO monitored_urls (url, check_interval_sec, regex_pattern, method, headers, body, expected_status_codes, paused,
+			json_assertions, max_body_bytes, retry_attempts, retry_delay_ms, retry_on, redirect_policy, max_redirects,
+			timeout_ms, slow_threshold_ms, auth, overlap_policy, max_concurrent, locations, quorum)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8, $9, NULLIF($10, 0), $11, $12, NULLIF($13, ''),
+			NULLIF($14, ''), NULLIF($15, 0), NULLIF($16, 0), NULLIF($17, 0), $18, NULLIF($19, ''), NULLIF($20, 0), $21, NULLIF($22, 0))
+		RETURNING id`
+
+	headers, err := encodeHeaders(url.Headers)
+	if err != nil {
+		return url, err
+	}
+
+	jsonAssertions, err := encodeJSONAssertions(url.JSONAssertions)
+	if err != nil {
+		return url, err
+	}
+
+	auth, err := encodeAuth(url.Auth)
+	if err != nil {
+		return url, err
+	}
+
+	err = tx.QueryRowContext(ctx, query,
+		url.Url,
+		url.CheckIntervalSec,
+		url.RegexPattern,
+		url.Method,
+		headers,
+		url.Body,
+		url.ExpectedStatusCodes,
+		url.Paused,
+		jsonAssertions,
+		url.MaxBodyBytes,
+		url.RetryAttempts,
+		url.RetryDelayMs,
+		url.RetryOn,
+		url.RedirectPolicy,
+		url.MaxRedirects,
+		url.TimeoutMs,
+		url.SlowThresholdMs,
+		auth,
+		url.OverlapPolicy,
+		url.MaxConcurrent,
+		encodeLocations(url.Locations),
+		url.Quorum).Scan(&url.ID)
+
+	if isUniqueViolation(err) {
+		return url, ErrDuplicateUrl
+	}
+	if err != nil {
+		return url, fmt.Errorf("failed to create monitored url: %w", err)
+	}
+
+	return url, insertAssertions(ctx, tx, url)
+}
+
+// updateMonitoredUrl replaces the monitored url with the same id and its assertions or returns ErrNotFound
+func updateMonitoredUrl(ctx context.Context, tx *sql.Tx, url models.MonitoredUrl) (models.MonitoredUrl, error) {
+	query := `
+		UPDATE monitored_urls
+		SET url = $2, check_interval_sec = $3, regex_pattern = NULLIF($4, ''), method = $5, headers = $6,
+			body = NULLIF($7, ''), expected_status_codes = NULLIF($8, ''), paused = $9, json_assertions = $10,
+			max_body_bytes = NULLIF($11, 0), retry_attempts = $12, retry_delay_ms = $13, retry_on = NULLIF($14, ''),
+			redirect_policy = NULLIF($15, ''), max_redirects = NULLIF($16, 0),
+			timeout_ms = NULLIF($17, 0), slow_threshold_ms = NULLIF($18, 0),
+			auth = $19, overlap_policy = NULLIF($20, ''), max_concurrent = NULLIF($21, 0),
+			locations = $22, quorum = NULLIF($23, 0)
+		WHERE id = $1
+		RETURNING id`
+
+	headers, err := encodeHeaders(url.Headers)
+	if err != nil {
+		return url, err
+	}
+
+	jsonAssertions, err := encodeJSONAssertions(url.JSONAssertions)
+	if err != nil {
+		return url, err
+	}
+
+	auth, err := encodeAuth(url.Auth)
+	if err != nil {
+		return url, err
+	}
+
+	err = tx.QueryRowContext(ctx, query,
+		url.ID,
+		url.Url,
+		url.CheckIntervalSec,
+		url.RegexPattern,
+		url.Method,
+		headers,
+		url.Body,
+		url.ExpectedStatusCodes,
+		url.Paused,
+		jsonAssertions,
+		url.MaxBodyBytes,
+		url.RetryAttempts,
+		url.RetryDelayMs,
+		url.RetryOn,
+		url.RedirectPolicy,
+		url.MaxRedirects,
+		url.TimeoutMs,
+		url.SlowThresholdMs,
+		auth,
+		url.OverlapPolicy,
+		url.MaxConcurrent,
+		encodeLocations(url.Locations),
+		url.Quorum).Scan(&url.ID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return url, ErrNotFound
+	}
+	if isUniqueViolation(err) {
+		return url, ErrDuplicateUrl
+	}
+	if err != nil {
+		return url, fmt.Errorf("failed to update monitored url %d: %w", url.ID, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM url_assertions WHERE url_id = $1`, url.ID); err != nil {
+		return url, fmt.Errorf("failed to delete assertions of monitored url %d: %w", url.ID, err)
+	}
+
+	return url, insertAssertions(ctx, tx, url)
+}
+
+// insertAssertions stores the assertions of the url in their order
+func insertAssertions(ctx context.Context, tx *sql.Tx, url models.MonitoredUrl) error {
+	query := `
+		INSERT INTO url_assertions (url_id, position, type, header, value, min_bytes, max_bytes)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7)`
+
+	for i, a := range url.Assertions {
+		_, err := tx.ExecContext(ctx, query, url.ID, i, a.Type, a.Header, a.Value, a.MinBytes, a.MaxBytes)
+		if err != nil {
+			return fmt.Errorf("failed to store assertion %d of monitored url %d: %w", i, url.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// loadAssertions reads the assertions of the urls and attaches them in their order
+func (r *DbUrlRepository) loadAssertions(ctx context.Context, urls []models.MonitoredUrl) error {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(urls))
+	byID := make(map[int]*models.MonitoredUrl, len(urls))
+	for i := range urls {
+		ids = append(ids, int64(urls[i].ID))
+		byID[urls[i].ID] = &urls[i]
+	}
 
-	rows, err := r.db.Query(query)
+	query := `SELECT ` + assertionColumns + ` FROM url_assertions WHERE url_id = ANY($1) ORDER BY url_id, position`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to query url assertions: %w", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	for rows.Next() {
+		var urlID int
+		var a models.ContentAssertion
+		if err := rows.Scan(&urlID, &a.Type, &a.Header, &a.Value, &a.MinBytes, &a.MaxBytes); err != nil {
+			return fmt.Errorf("failed to scan url assertion: %w", err)
+		}
+
+		if url, ok := byID[urlID]; ok {
+			url.Assertions = append(url.Assertions, a)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over url assertions: %w", err)
+	}
+
+	return nil
+}
+
+// deleteMonitoredUrl removes the monitored url or returns ErrNotFound
+func deleteMonitoredUrl(ctx context.Context, q queryRower, id int) error {
+	query := `DELETE FROM monitored_urls WHERE id = $1 RETURNING id`
+
+	err := q.QueryRowContext(ctx, query, id).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete monitored url %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// SetPaused pauses or resumes checks of the monitored url or returns ErrNotFound
+func (r *DbUrlRepository) SetPaused(ctx context.Context, id int, paused bool) error {
+	query := `UPDATE monitored_urls SET paused = $2 WHERE id = $1 RETURNING id`
+
+	err := r.db.QueryRowContext(ctx, query, id, paused).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set paused of monitored url %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// queryMonitoredUrls runs a query selecting monitoredUrlColumns and scans all rows
+func (r *DbUrlRepository) queryMonitoredUrls(ctx context.Context, query string, args ...interface{}) ([]models.MonitoredUrl, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query monitored URLs: %w", err)
 	}
@@ -32,8 +354,8 @@ func (r *DbUrlRepository) GetMonitoredUrls() ([]models.MonitoredUrl, error) {
 
 	var urls []models.MonitoredUrl
 	for rows.Next() {
-		var url models.MonitoredUrl
-		if err := rows.Scan(&url.ID, &url.Url, &url.CheckIntervalSec, &url.RegexPattern); err != nil {
+		url, err := scanMonitoredUrl(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan monitored url: %w", err)
 		}
 		urls = append(urls, url)
@@ -43,5 +365,138 @@ func (r *DbUrlRepository) GetMonitoredUrls() ([]models.MonitoredUrl, error) {
 		return nil, fmt.Errorf("error iterating over monitored urls: %w", err)
 	}
 
+	if err := r.loadAssertions(ctx, urls); err != nil {
+		return nil, err
+	}
+
 	return urls, nil
 }
+
+// scanner is implemented by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanMonitoredUrl scans a row selected with monitoredUrlColumns
+func scanMonitoredUrl(row scanner) (models.MonitoredUrl, error) {
+	var url models.MonitoredUrl
+	var headers, jsonAssertions, auth []byte
+
+	err := row.Scan(
+		&url.ID,
+		&url.Url,
+		&url.CheckIntervalSec,
+		&url.RegexPattern,
+		&url.Method,
+		&headers,
+		&url.Body,
+		&url.ExpectedStatusCodes,
+		&url.Paused,
+		&jsonAssertions,
+		&url.MaxBodyBytes,
+		&url.RetryAttempts,
+		&url.RetryDelayMs,
+		&url.RetryOn,
+		&url.RedirectPolicy,
+		&url.MaxRedirects,
+		&url.TimeoutMs,
+		&url.SlowThresholdMs,
+		&auth,
+		&url.OverlapPolicy,
+		&url.MaxConcurrent,
+		(*pq.StringArray)(&url.Locations),
+		&url.Quorum,
+	)
+	if err != nil {
+		return url, err
+	}
+
+	if len(headers) > 0 {
+		if err := json.Unmarshal(headers, &url.Headers); err != nil {
+			return url, fmt.Errorf("invalid headers of url %d: %w", url.ID, err)
+		}
+	}
+
+	if len(jsonAssertions) > 0 {
+		if err := json.Unmarshal(jsonAssertions, &url.JSONAssertions); err != nil {
+			return url, fmt.Errorf("invalid json assertions of url %d: %w", url.ID, err)
+		}
+	}
+
+	if auth != nil {
+		url.Auth = &models.Auth{}
+		if err := json.Unmarshal(auth, url.Auth); err != nil {
+			return url, fmt.Errorf("invalid auth of url %d: %w", url.ID, err)
+		}
+	}
+
+	// Urls checked by the monitor itself compare equal regardless of whether they were read from the database or not
+	if len(url.Locations) == 0 {
+		url.Locations = nil
+	}
+
+	// Urls without assertions compare equal regardless of whether they were read from the database or not
+	if len(url.JSONAssertions) == 0 {
+		url.JSONAssertions = nil
+	}
+
+	return url, nil
+}
+
+// encodeHeaders encodes request headers for the JSONB headers column
+func encodeHeaders(headers map[string]string) ([]byte, error) {
+	if headers == nil {
+		headers = map[string]string{}
+	}
+
+	encoded, err := json.Marshal(headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode headers: %w", err)
+	}
+
+	return encoded, nil
+}
+
+// encodeAuth encodes the auth for the nullable JSONB auth column, it only holds names of environment variables
+func encodeAuth(auth *models.Auth) (interface{}, error) {
+	if auth == nil {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode auth: %w", err)
+	}
+
+	return encoded, nil
+}
+
+// encodeLocations encodes the locations for the nullable locations column, NULL if the monitor checks the url itself
+func encodeLocations(locations []string) interface{} {
+	if len(locations) == 0 {
+		return nil
+	}
+
+	return pq.StringArray(locations)
+}
+
+// encodeJSONAssertions encodes assertions for the JSONB json_assertions column
+func encodeJSONAssertions(assertions []models.JSONAssertion) ([]byte, error) {
+	if assertions == nil {
+		assertions = []models.JSONAssertion{}
+	}
+
+	encoded, err := json.Marshal(assertions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode json assertions: %w", err)
+	}
+
+	return encoded, nil
+}
+
+// isUniqueViolation reports whether the error was caused by a unique constraint
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
@@ -1,7 +1,10 @@
 package url_repository_test
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"reflect"
 	"testing"
 
 	"website-monitor/internal/db"
@@ -9,8 +12,16 @@ import (
 	"website-monitor/internal/url_repository"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 )
 
+const monitoredUrlsQuery = `SELECT id, url, check_interval_sec, COALESCE\(regex_pattern, ''\), method, headers, COALESCE\(body, ''\), COALESCE\(expected_status_codes, ''\), paused, json_assertions, COALESCE\(max_body_bytes, 0\),\s+retry_attempts, retry_delay_ms, COALESCE\(retry_on, ''\), COALESCE\(redirect_policy, ''\), COALESCE\(max_redirects, 0\),\s+COALESCE\(timeout_ms, 0\), COALESCE\(slow_threshold_ms, 0\), auth, COALESCE\(overlap_policy, ''\), COALESCE\(max_concurrent, 0\), locations, COALESCE\(quorum, 0\) FROM monitored_urls WHERE NOT paused`
+
+var assertionColumns = []string{"url_id", "type", "header", "value", "min_bytes", "max_bytes"}
+
+var monitoredUrlsColumns = []string{"id", "url", "check_interval_sec", "regex_pattern", "method", "headers", "body", "expected_status_codes", "paused", "json_assertions", "max_body_bytes",
+	"retry_attempts", "retry_delay_ms", "retry_on", "redirect_policy", "max_redirects", "timeout_ms", "slow_threshold_ms", "auth", "overlap_policy", "max_concurrent", "locations", "quorum"}
+
 func TestGetMonitoredUrls_HappyPath(t *testing.T) {
 	sqlDB, mock, err := sqlmock.New()
 	if err != nil {
@@ -18,12 +29,19 @@ func TestGetMonitoredUrls_HappyPath(t *testing.T) {
 	}
 	defer sqlDB.Close()
 
-	mock.ExpectQuery(`SELECT id, url, check_interval_sec, COALESCE\(regex_pattern, ''\) FROM monitored_urls`).
+	mock.ExpectQuery(monitoredUrlsQuery).
+		WillReturnRows(
+			sqlmock.NewRows(monitoredUrlsColumns).
+				AddRow(1, "https://stackoverflow.com", 10, "Example", "GET", []byte(`{}`), "", "", false, []byte(`[]`), 0, 0, 0, "", "", 0, 0, 0, nil, "", 0, nil, 0).
+				AddRow(2, "https://google.com", 120, "Google", "HEAD", []byte(`{"Accept":"text/html"}`), "", "200-299", false, []byte(`[{"path":"$.status","op":"equals","value":"ok"}]`), 1048576, 3, 500, "network,502-504", "same_host", 3, 5000, 1000,
+					[]byte(`{"type":"bearer","token_env":"GOOGLE_TOKEN"}`), "concurrent", 3, "{eu-west,us-east,ap-south}", 2).
+				AddRow(3, "https://github.com", 30, "", "POST", []byte(`{}`), `{"ping":true}`, "201", false, []byte(`[]`), 0, 0, 0, "", "", 0, 0, 0, nil, "", 0, nil, 0),
+		)
+	mock.ExpectQuery(`SELECT (.+) FROM url_assertions WHERE url_id = ANY\(\$1\) ORDER BY url_id, position`).
 		WillReturnRows(
-			sqlmock.NewRows([]string{"id", "url", "check_interval_sec", "regex_pattern"}).
-				AddRow(1, "https://stackoverflow.com", 10, "Example").
-				AddRow(2, "https://google.com", 120, "Google").
-				AddRow(3, "https://github.com", 30, ""),
+			sqlmock.NewRows(assertionColumns).
+				AddRow(3, "not_contains", "", "Maintenance", nil, nil).
+				AddRow(3, "body_size", "", "", 10, nil),
 		)
 
 	repo := url_repository.New(db.New(sqlDB))
@@ -37,14 +55,21 @@ func TestGetMonitoredUrls_HappyPath(t *testing.T) {
 		t.Fatalf("expected 3 URLs, got %d", len(urls))
 	}
 
+	minBytes := 10
 	expected := []models.MonitoredUrl{
-		{ID: 1, Url: "https://stackoverflow.com", CheckIntervalSec: 10, RegexPattern: "Example"},
-		{ID: 2, Url: "https://google.com", CheckIntervalSec: 120, RegexPattern: "Google"},
-		{ID: 3, Url: "https://github.com", CheckIntervalSec: 30, RegexPattern: ""},
+		{ID: 1, Url: "https://stackoverflow.com", CheckIntervalSec: 10, RegexPattern: "Example", Method: "GET", Headers: map[string]string{}},
+		{ID: 2, Url: "https://google.com", CheckIntervalSec: 120, RegexPattern: "Google", Method: "HEAD", Headers: map[string]string{"Accept": "text/html"}, ExpectedStatusCodes: "200-299",
+			JSONAssertions: []models.JSONAssertion{{Path: "$.status", Op: "equals", Value: "ok"}}, MaxBodyBytes: 1048576,
+			RetryAttempts: 3, RetryDelayMs: 500, RetryOn: "network,502-504", RedirectPolicy: "same_host", MaxRedirects: 3,
+			TimeoutMs: 5000, SlowThresholdMs: 1000,
+			Auth: &models.Auth{Type: "bearer", TokenEnv: "GOOGLE_TOKEN"}, OverlapPolicy: "concurrent", MaxConcurrent: 3,
+			Locations: []string{"eu-west", "us-east", "ap-south"}, Quorum: 2},
+		{ID: 3, Url: "https://github.com", CheckIntervalSec: 30, RegexPattern: "", Method: "POST", Headers: map[string]string{}, Body: `{"ping":true}`, ExpectedStatusCodes: "201",
+			Assertions: []models.ContentAssertion{{Type: "not_contains", Value: "Maintenance"}, {Type: "body_size", MinBytes: &minBytes}}},
 	}
 
 	for i, exp := range expected {
-		if urls[i] != exp {
+		if !reflect.DeepEqual(urls[i], exp) {
 			t.Errorf("expected %+v, got %+v", exp, urls[i])
 		}
 	}
@@ -58,8 +83,8 @@ func TestGetMonitoredUrls_EmptyTable(t *testing.T) {
 	sqlDB, mock, _ := sqlmock.New()
 	defer sqlDB.Close()
 
-	mock.ExpectQuery(`SELECT id, url, check_interval_sec, COALESCE\(regex_pattern, ''\) FROM monitored_urls`).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "check_interval_sec", "regex_pattern"}))
+	mock.ExpectQuery(monitoredUrlsQuery).
+		WillReturnRows(sqlmock.NewRows(monitoredUrlsColumns))
 
 	repo := url_repository.New(db.New(sqlDB))
 
@@ -81,7 +106,7 @@ func TestGetMonitoredUrls_QueryError(t *testing.T) {
 	sqlDB, mock, _ := sqlmock.New()
 	defer sqlDB.Close()
 
-	mock.ExpectQuery(`SELECT id, url, check_interval_sec, COALESCE\(regex_pattern, ''\) FROM monitored_urls`).
+	mock.ExpectQuery(monitoredUrlsQuery).
 		WillReturnError(sql.ErrConnDone)
 
 	repo := url_repository.New(db.New(sqlDB))
@@ -99,3 +124,210 @@ func TestGetMonitoredUrls_QueryError(t *testing.T) {
 		t.Errorf("unmet sqlmock expectations: %v", err)
 	}
 }
+
+func TestGetMonitoredUrls_InvalidHeaders(t *testing.T) {
+	sqlDB, mock, _ := sqlmock.New()
+	defer sqlDB.Close()
+
+	mock.ExpectQuery(monitoredUrlsQuery).
+		WillReturnRows(
+			sqlmock.NewRows(monitoredUrlsColumns).
+				AddRow(1, "https://example.com", 10, "", "GET", []byte(`not json`), "", "", false, []byte(`[]`), 0, 0, 0, "", "", 0, 0, 0, nil, "", 0, nil, 0),
+		)
+
+	repo := url_repository.New(db.New(sqlDB))
+
+	if _, err := repo.GetMonitoredUrls(); err == nil {
+		t.Fatal("expected error for invalid headers, got nil")
+	}
+}
+
+func TestGetMonitoredUrl_NotFound(t *testing.T) {
+	sqlDB, mock, _ := sqlmock.New()
+	defer sqlDB.Close()
+
+	mock.ExpectQuery(`SELECT (.+) FROM monitored_urls WHERE id = \$1`).
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows(monitoredUrlsColumns))
+
+	repo := url_repository.New(db.New(sqlDB))
+
+	_, err := repo.GetMonitoredUrl(context.Background(), 42)
+	if !errors.Is(err, url_repository.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet sqlmock expectations: %v", err)
+	}
+}
+
+func TestCreateMonitoredUrl_HappyPath(t *testing.T) {
+	sqlDB, mock, _ := sqlmock.New()
+	defer sqlDB.Close()
+
+	url := models.MonitoredUrl{
+		Url:              "https://example.com",
+		CheckIntervalSec: 30,
+		Method:           "GET",
+		Assertions:       []models.ContentAssertion{{Type: "contains", Value: "Welcome"}},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO monitored_urls`).
+		WithArgs(url.Url, url.CheckIntervalSec, "", "GET", []byte(`{}`), "", "", false, []byte(`[]`), 0, 0, 0, "", "", 0, 0, 0, nil, "", 0, nil, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectExec(`INSERT INTO url_assertions`).
+		WithArgs(5, 0, "contains", "", "Welcome", nil, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	repo := url_repository.New(db.New(sqlDB))
+
+	created, err := repo.CreateMonitoredUrl(context.Background(), url)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if created.ID != 5 {
+		t.Errorf("expected id 5, got %d", created.ID)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet sqlmock expectations: %v", err)
+	}
+}
+
+func TestCreateMonitoredUrl_Duplicate(t *testing.T) {
+	sqlDB, mock, _ := sqlmock.New()
+	defer sqlDB.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO monitored_urls`).
+		WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectRollback()
+
+	repo := url_repository.New(db.New(sqlDB))
+
+	_, err := repo.CreateMonitoredUrl(context.Background(), models.MonitoredUrl{Url: "https://example.com"})
+	if !errors.Is(err, url_repository.ErrDuplicateUrl) {
+		t.Fatalf("expected ErrDuplicateUrl, got %v", err)
+	}
+}
+
+func TestUpdateMonitoredUrl_NotFound(t *testing.T) {
+	sqlDB, mock, _ := sqlmock.New()
+	defer sqlDB.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE monitored_urls`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	repo := url_repository.New(db.New(sqlDB))
+
+	_, err := repo.UpdateMonitoredUrl(context.Background(), models.MonitoredUrl{ID: 42, Url: "https://example.com"})
+	if !errors.Is(err, url_repository.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestDeleteMonitoredUrl_HappyPath(t *testing.T) {
+	sqlDB, mock, _ := sqlmock.New()
+	defer sqlDB.Close()
+
+	mock.ExpectQuery(`DELETE FROM monitored_urls WHERE id = \$1 RETURNING id`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	repo := url_repository.New(db.New(sqlDB))
+
+	if err := repo.DeleteMonitoredUrl(context.Background(), 3); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet sqlmock expectations: %v", err)
+	}
+}
+
+func TestSetPaused_HappyPath(t *testing.T) {
+	sqlDB, mock, _ := sqlmock.New()
+	defer sqlDB.Close()
+
+	mock.ExpectQuery(`UPDATE monitored_urls SET paused = \$2 WHERE id = \$1 RETURNING id`).
+		WithArgs(3, true).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	repo := url_repository.New(db.New(sqlDB))
+
+	if err := repo.SetPaused(context.Background(), 3, true); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet sqlmock expectations: %v", err)
+	}
+}
+
+func TestApplyChanges_CommitsAllWrites(t *testing.T) {
+	sqlDB, mock, _ := sqlmock.New()
+	defer sqlDB.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM monitored_urls WHERE id = \$1 RETURNING id`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery(`UPDATE monitored_urls`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectExec(`DELETE FROM url_assertions WHERE url_id = \$1`).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`INSERT INTO monitored_urls`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectCommit()
+
+	repo := url_repository.New(db.New(sqlDB))
+
+	err := repo.ApplyChanges(context.Background(), url_repository.Changes{
+		Create: []models.MonitoredUrl{{Url: "https://new.example.com", CheckIntervalSec: 60, Method: "GET"}},
+		Update: []models.MonitoredUrl{{ID: 2, Url: "https://google.com", CheckIntervalSec: 120, Method: "GET"}},
+		Delete: []int{3},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet sqlmock expectations: %v", err)
+	}
+}
+
+func TestApplyChanges_RollsBackOnError(t *testing.T) {
+	sqlDB, mock, _ := sqlmock.New()
+	defer sqlDB.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE monitored_urls`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectExec(`DELETE FROM url_assertions WHERE url_id = \$1`).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`INSERT INTO monitored_urls`).
+		WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectRollback()
+
+	repo := url_repository.New(db.New(sqlDB))
+
+	err := repo.ApplyChanges(context.Background(), url_repository.Changes{
+		Create: []models.MonitoredUrl{{Url: "https://example.com", CheckIntervalSec: 60, Method: "GET"}},
+		Update: []models.MonitoredUrl{{ID: 2, Url: "https://google.com", CheckIntervalSec: 120, Method: "GET"}},
+	})
+	if !errors.Is(err, url_repository.ErrDuplicateUrl) {
+		t.Fatalf("expected ErrDuplicateUrl, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet sqlmock expectations: %v", err)
+	}
+}
@@ -1,9 +1,46 @@
 package url_repository
 
-import "website-monitor/internal/models"
+import (
+	"context"
+	"errors"
+
+	"website-monitor/internal/models"
+)
+
+var (
+	// ErrNotFound is returned when a monitored url does not exist
+	ErrNotFound = errors.New("monitored url not found")
+	// ErrDuplicateUrl is returned when a monitored url with the same address already exists
+	ErrDuplicateUrl = errors.New("monitored url already exists")
+)
 
 // UrlRepository defines the interface for url data sources
 type UrlRepository interface {
-	// GetMonitoredUrls returns all urls that should be monitored
+	// GetMonitoredUrls returns all urls that should be monitored, paused urls are skipped
 	GetMonitoredUrls() ([]models.MonitoredUrl, error)
 }
+
+// UrlManager defines the interface for administration of monitored urls
+type UrlManager interface {
+	// ListMonitoredUrls returns all monitored urls ordered by id
+	ListMonitoredUrls(ctx context.Context) ([]models.MonitoredUrl, error)
+	// GetMonitoredUrl returns a single monitored url or ErrNotFound
+	GetMonitoredUrl(ctx context.Context, id int) (models.MonitoredUrl, error)
+	// CreateMonitoredUrl stores a new monitored url and returns it with its id
+	CreateMonitoredUrl(ctx context.Context, url models.MonitoredUrl) (models.MonitoredUrl, error)
+	// UpdateMonitoredUrl replaces the monitored url with the same id or returns ErrNotFound
+	UpdateMonitoredUrl(ctx context.Context, url models.MonitoredUrl) (models.MonitoredUrl, error)
+	// DeleteMonitoredUrl removes the monitored url or returns ErrNotFound
+	DeleteMonitoredUrl(ctx context.Context, id int) error
+	// SetPaused pauses or resumes checks of the monitored url or returns ErrNotFound
+	SetPaused(ctx context.Context, id int, paused bool) error
+	// ApplyChanges creates, updates and deletes monitored urls in a single transaction
+	ApplyChanges(ctx context.Context, changes Changes) error
+}
+
+// Changes is a set of writes applied together by ApplyChanges
+type Changes struct {
+	Create []models.MonitoredUrl
+	Update []models.MonitoredUrl
+	Delete []int
+}
@@ -0,0 +1,271 @@
+package url_sync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"website-monitor/internal/models"
+	"website-monitor/internal/url_repository"
+	"website-monitor/internal/validator"
+)
+
+// Supported file formats
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// File is the document monitored urls are imported from and exported to.
+// Urls are identified by their address, database ids are not part of the file
+type File struct {
+	Urls []models.MonitoredUrl `json:"urls"`
+}
+
+// Update pairs a stored url with the configuration replacing it
+type Update struct {
+	Old models.MonitoredUrl
+	New models.MonitoredUrl
+}
+
+// Plan lists the changes needed to bring the stored urls in line with a file
+type Plan struct {
+	Create []models.MonitoredUrl
+	Update []Update
+	Delete []models.MonitoredUrl
+}
+
+// FormatFromPath returns the format matching the extension of the path, YAML by default
+func FormatFromPath(path string) string {
+	if strings.HasSuffix(strings.ToLower(path), ".json") {
+		return FormatJSON
+	}
+
+	return FormatYAML
+}
+
+// Decode parses a YAML or JSON document, then normalizes and validates every url in it.
+// Unknown fields and addresses listed more than once are rejected
+func Decode(data []byte) ([]models.MonitoredUrl, error) {
+	var file File
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse monitored urls: %w", err)
+	}
+
+	var errs []string
+	seen := make(map[string]int, len(file.Urls))
+	urls := make([]models.MonitoredUrl, 0, len(file.Urls))
+
+	for i, url := range file.Urls {
+		url.ID = 0
+		url = validator.Normalize(url)
+
+		var invalid validator.Errors
+		if err := validator.ValidateMonitoredUrl(url); errors.As(err, &invalid) {
+			errs = append(errs, fmt.Sprintf("urls[%d] (%s): %s", i, url.Url, strings.Join(invalid, "; ")))
+		} else if err != nil {
+			return nil, err
+		}
+
+		if first, ok := seen[url.Url]; ok {
+			errs = append(errs, fmt.Sprintf("urls[%d] (%s): duplicate of urls[%d]", i, url.Url, first))
+		} else {
+			seen[url.Url] = i
+		}
+
+		urls = append(urls, url)
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid monitored urls:\n  %s", strings.Join(errs, "\n  "))
+	}
+
+	return urls, nil
+}
+
+// Encode writes the urls as a YAML or JSON document, leaving out their database ids
+func Encode(urls []models.MonitoredUrl, format string) ([]byte, error) {
+	file := File{Urls: make([]models.MonitoredUrl, 0, len(urls))}
+	for _, url := range urls {
+		url.ID = 0
+		file.Urls = append(file.Urls, url)
+	}
+
+	switch format {
+	case FormatYAML:
+		return yaml.Marshal(file)
+	case FormatJSON:
+		data, err := json.MarshalIndent(file, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		return append(data, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q, expected %s or %s", format, FormatYAML, FormatJSON)
+	}
+}
+
+// Diff compares the stored urls with the desired ones by address.
+// Stored urls missing from the desired set are only deleted if prune is set
+func Diff(current, desired []models.MonitoredUrl, prune bool) Plan {
+	var plan Plan
+
+	stored := make(map[string]models.MonitoredUrl, len(current))
+	for _, url := range current {
+		stored[url.Url] = url
+	}
+
+	wanted := make(map[string]bool, len(desired))
+	for _, url := range desired {
+		wanted[url.Url] = true
+
+		old, ok := stored[url.Url]
+		if !ok {
+			plan.Create = append(plan.Create, url)
+			continue
+		}
+
+		url.ID = old.ID
+		if !equal(old, url) {
+			plan.Update = append(plan.Update, Update{Old: old, New: url})
+		}
+	}
+
+	if prune {
+		for _, url := range current {
+			if !wanted[url.Url] {
+				plan.Delete = append(plan.Delete, url)
+			}
+		}
+	}
+
+	return plan
+}
+
+// Empty reports whether the plan has nothing to change
+func (p Plan) Empty() bool {
+	return len(p.Create) == 0 && len(p.Update) == 0 && len(p.Delete) == 0
+}
+
+// Changes converts the plan to the writes applied by the url repository
+func (p Plan) Changes() url_repository.Changes {
+	changes := url_repository.Changes{Create: p.Create}
+	for _, update := range p.Update {
+		changes.Update = append(changes.Update, update.New)
+	}
+	for _, url := range p.Delete {
+		changes.Delete = append(changes.Delete, url.ID)
+	}
+
+	return changes
+}
+
+// Print writes a human readable summary of the plan, listing the changed fields of every update
+func (p Plan) Print(out io.Writer) {
+	if p.Empty() {
+		fmt.Fprintln(out, "No changes, monitored urls are up to date")
+		return
+	}
+
+	for _, url := range p.Create {
+		fmt.Fprintf(out, "+ %s\n", url.Url)
+	}
+	for _, update := range p.Update {
+		fmt.Fprintf(out, "~ %s (id %d)\n", update.New.Url, update.Old.ID)
+		for _, field := range changedFields(update.Old, update.New) {
+			fmt.Fprintf(out, "    %s\n", field)
+		}
+	}
+	for _, url := range p.Delete {
+		fmt.Fprintf(out, "- %s (id %d)\n", url.Url, url.ID)
+	}
+
+	fmt.Fprintf(out, "Plan: %d to create, %d to update, %d to delete\n", len(p.Create), len(p.Update), len(p.Delete))
+}
+
+// equal compares two urls, treating missing and empty headers, assertions and locations the same way
+func equal(a, b models.MonitoredUrl) bool {
+	if len(a.Headers) == 0 && len(b.Headers) == 0 {
+		a.Headers, b.Headers = nil, nil
+	}
+	if len(a.JSONAssertions) == 0 && len(b.JSONAssertions) == 0 {
+		a.JSONAssertions, b.JSONAssertions = nil, nil
+	}
+	if len(a.Assertions) == 0 && len(b.Assertions) == 0 {
+		a.Assertions, b.Assertions = nil, nil
+	}
+	if len(a.Locations) == 0 && len(b.Locations) == 0 {
+		a.Locations, b.Locations = nil, nil
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// changedFields describes the fields that differ between the old and new url
+func changedFields(old, new models.MonitoredUrl) []string {
+	var fields []string
+
+	add := func(name string, from, to interface{}) {
+		if !reflect.DeepEqual(from, to) {
+			fields = append(fields, fmt.Sprintf("%s: %v -> %v", name, from, to))
+		}
+	}
+
+	add("check_interval_sec", old.CheckIntervalSec, new.CheckIntervalSec)
+	add("regex_pattern", quote(old.RegexPattern), quote(new.RegexPattern))
+	add("method", old.Method, new.Method)
+	add("headers", formatHeaders(old.Headers), formatHeaders(new.Headers))
+	add("body", quote(old.Body), quote(new.Body))
+	add("expected_status_codes", quote(old.ExpectedStatusCodes), quote(new.ExpectedStatusCodes))
+	add("paused", old.Paused, new.Paused)
+	add("max_body_bytes", old.MaxBodyBytes, new.MaxBodyBytes)
+	add("retry_attempts", old.RetryAttempts, new.RetryAttempts)
+	add("retry_delay_ms", old.RetryDelayMs, new.RetryDelayMs)
+	add("retry_on", quote(old.RetryOn), quote(new.RetryOn))
+	add("redirect_policy", quote(old.RedirectPolicy), quote(new.RedirectPolicy))
+	add("max_redirects", old.MaxRedirects, new.MaxRedirects)
+	add("timeout_ms", old.TimeoutMs, new.TimeoutMs)
+	add("slow_threshold_ms", old.SlowThresholdMs, new.SlowThresholdMs)
+	add("auth", formatJSON(old.Auth), formatJSON(new.Auth))
+	add("overlap_policy", quote(old.OverlapPolicy), quote(new.OverlapPolicy))
+	add("max_concurrent", old.MaxConcurrent, new.MaxConcurrent)
+	add("locations", quote(strings.Join(old.Locations, ",")), quote(strings.Join(new.Locations, ",")))
+	add("quorum", old.Quorum, new.Quorum)
+	add("json_assertions", formatJSON(old.JSONAssertions), formatJSON(new.JSONAssertions))
+	add("assertions", formatJSON(old.Assertions), formatJSON(new.Assertions))
+
+	return fields
+}
+
+// quote quotes a string so empty values stay visible in the plan
+func quote(s string) string {
+	return fmt.Sprintf("%q", s)
+}
+
+// formatHeaders prints headers sorted by name
+func formatHeaders(headers map[string]string) string {
+	pairs := make([]string, 0, len(headers))
+	for name, value := range headers {
+		pairs = append(pairs, name+": "+value)
+	}
+	sort.Strings(pairs)
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// formatJSON prints a value as compact JSON
+func formatJSON(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+
+	return string(encoded)
+}
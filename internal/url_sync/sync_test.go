@@ -0,0 +1,174 @@
+package url_sync
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"website-monitor/internal/models"
+)
+
+func TestDecode_YAML(t *testing.T) {
+	data := []byte(`
+urls:
+  - url: " https://example.com "
+    check_interval_sec: 30
+    method: head
+    headers:
+      Accept: text/html
+  - url: https://google.com
+    check_interval_sec: 60
+    paused: true
+`)
+
+	urls, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []models.MonitoredUrl{
+		{Url: "https://example.com", CheckIntervalSec: 30, Method: "HEAD", Headers: map[string]string{"Accept": "text/html"}},
+		{Url: "https://google.com", CheckIntervalSec: 60, Method: "GET", Paused: true},
+	}
+	if !reflect.DeepEqual(urls, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, urls)
+	}
+}
+
+func TestDecode_JSON(t *testing.T) {
+	urls, err := Decode([]byte(`{"urls": [{"url": "https://example.com", "check_interval_sec": 30}]}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(urls) != 1 || urls[0].Url != "https://example.com" {
+		t.Errorf("Unexpected urls %+v", urls)
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := map[string]string{
+		"unknown field": `
+urls:
+  - url: https://example.com
+    check_interval_sec: 30
+    interval: 30
+`,
+		"invalid url": `
+urls:
+  - url: example.com
+    check_interval_sec: 1
+`,
+		"duplicate": `
+urls:
+  - url: https://example.com
+    check_interval_sec: 30
+  - url: https://example.com
+    check_interval_sec: 60
+`,
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Decode([]byte(data)); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestEncode_RoundTrip(t *testing.T) {
+	urls := []models.MonitoredUrl{
+		{ID: 1, Url: "https://example.com", CheckIntervalSec: 30, Method: "GET", Headers: map[string]string{}},
+		{ID: 2, Url: "https://google.com", CheckIntervalSec: 60, Method: "POST", Body: "{}", ExpectedStatusCodes: "200-299"},
+	}
+
+	for _, format := range []string{FormatYAML, FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			data, err := Encode(urls, format)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if bytes.Contains(data, []byte(`"id"`)) || bytes.Contains(data, []byte("id:")) {
+				t.Errorf("Expected ids to be left out, got:\n%s", data)
+			}
+
+			decoded, err := Decode(data)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if plan := Diff(urls, decoded, true); !plan.Empty() {
+				t.Errorf("Expected no changes after round trip, got %+v", plan)
+			}
+		})
+	}
+}
+
+func TestEncode_UnknownFormat(t *testing.T) {
+	if _, err := Encode(nil, "xml"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	current := []models.MonitoredUrl{
+		{ID: 1, Url: "https://example.com", CheckIntervalSec: 30, Method: "GET", Headers: map[string]string{}},
+		{ID: 2, Url: "https://google.com", CheckIntervalSec: 60, Method: "GET", Headers: map[string]string{}},
+		{ID: 3, Url: "https://old.example.com", CheckIntervalSec: 60, Method: "GET", Headers: map[string]string{}},
+	}
+	desired := []models.MonitoredUrl{
+		{Url: "https://example.com", CheckIntervalSec: 30, Method: "GET"},
+		{Url: "https://google.com", CheckIntervalSec: 120, Method: "GET"},
+		{Url: "https://new.example.com", CheckIntervalSec: 60, Method: "GET"},
+	}
+
+	plan := Diff(current, desired, false)
+	if len(plan.Create) != 1 || plan.Create[0].Url != "https://new.example.com" {
+		t.Errorf("Unexpected creates %+v", plan.Create)
+	}
+	if len(plan.Update) != 1 || plan.Update[0].New.ID != 2 || plan.Update[0].New.CheckIntervalSec != 120 {
+		t.Errorf("Unexpected updates %+v", plan.Update)
+	}
+	if len(plan.Delete) != 0 {
+		t.Errorf("Expected no deletes without prune, got %+v", plan.Delete)
+	}
+
+	plan = Diff(current, desired, true)
+	if len(plan.Delete) != 1 || plan.Delete[0].ID != 3 {
+		t.Errorf("Unexpected deletes %+v", plan.Delete)
+	}
+
+	changes := plan.Changes()
+	if !reflect.DeepEqual(changes.Delete, []int{3}) || len(changes.Update) != 1 || len(changes.Create) != 1 {
+		t.Errorf("Unexpected changes %+v", changes)
+	}
+}
+
+func TestPlan_Print(t *testing.T) {
+	plan := Plan{
+		Create: []models.MonitoredUrl{{Url: "https://new.example.com"}},
+		Update: []Update{{
+			Old: models.MonitoredUrl{ID: 2, Url: "https://google.com", CheckIntervalSec: 60},
+			New: models.MonitoredUrl{ID: 2, Url: "https://google.com", CheckIntervalSec: 120},
+		}},
+		Delete: []models.MonitoredUrl{{ID: 3, Url: "https://old.example.com"}},
+	}
+
+	var out bytes.Buffer
+	plan.Print(&out)
+
+	for _, expected := range []string{
+		"+ https://new.example.com",
+		"~ https://google.com (id 2)",
+		"check_interval_sec: 60 -> 120",
+		"- https://old.example.com (id 3)",
+		"Plan: 1 to create, 1 to update, 1 to delete",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package validator
+
+import (
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"website-monitor/internal/assertion"
+	"website-monitor/internal/checker"
+	"website-monitor/internal/models"
+)
+
+const (
+	// MinCheckIntervalSec and MaxCheckIntervalSec mirror the check_interval_sec constraint of monitored_urls
+	MinCheckIntervalSec = 5
+	MaxCheckIntervalSec = 300
+)
+
+// allowedMethods lists the HTTP methods a check can be performed with
+var allowedMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// Errors collects all problems found in a monitored url
+type Errors []string
+
+func (e Errors) Error() string {
+	return "invalid monitored url: " + strings.Join(e, "; ")
+}
+
+// Normalize fills in defaults of optional fields, so stored urls look the same regardless of how they were created
+func Normalize(url models.MonitoredUrl) models.MonitoredUrl {
+	url.Url = strings.TrimSpace(url.Url)
+	url.Method = strings.ToUpper(strings.TrimSpace(url.Method))
+	if url.Method == "" {
+		url.Method = http.MethodGet
+	}
+
+	return url
+}
+
+// ValidateMonitoredUrl checks a monitored url against the constraints of the monitored_urls table and the checker.
+// It returns Errors listing every problem found, or nil if the url is valid
+func ValidateMonitoredUrl(url models.MonitoredUrl) error {
+	var errs Errors
+
+	if err := validateAddress(url.Url); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if url.CheckIntervalSec < MinCheckIntervalSec || url.CheckIntervalSec > MaxCheckIntervalSec {
+		errs = append(errs, fmt.Sprintf("check_interval_sec must be between %d and %d", MinCheckIntervalSec, MaxCheckIntervalSec))
+	}
+
+	if url.RegexPattern != "" {
+		if _, err := regexp.Compile(url.RegexPattern); err != nil {
+			errs = append(errs, fmt.Sprintf("regex_pattern is invalid: %s", err.Error()))
+		}
+	}
+
+	if url.MaxBodyBytes < 0 || url.MaxBodyBytes > checker.MaxBodyBytesLimit {
+		errs = append(errs, fmt.Sprintf("max_body_bytes must be between 0 and %d", checker.MaxBodyBytesLimit))
+	}
+
+	if url.RetryAttempts < 0 || url.RetryAttempts > checker.MaxRetryAttempts {
+		errs = append(errs, fmt.Sprintf("retry_attempts must be between 0 and %d", checker.MaxRetryAttempts))
+	}
+
+	if url.RetryDelayMs < 0 || url.RetryDelayMs > checker.MaxRetryDelayMs {
+		errs = append(errs, fmt.Sprintf("retry_delay_ms must be between 0 and %d", checker.MaxRetryDelayMs))
+	}
+
+	if _, err := checker.ParseRetryOn(url.RetryOn); err != nil {
+		errs = append(errs, fmt.Sprintf("retry_on is invalid: %s", err.Error()))
+	}
+
+	if !checker.ValidRedirectPolicy(url.RedirectPolicy) {
+		errs = append(errs, fmt.Sprintf("redirect_policy must be %s, %s or %s", checker.RedirectFollow, checker.RedirectNone, checker.RedirectSameHost))
+	}
+
+	if url.MaxRedirects < 0 || url.MaxRedirects > checker.MaxRedirectsLimit {
+		errs = append(errs, fmt.Sprintf("max_redirects must be between 0 and %d", checker.MaxRedirectsLimit))
+	}
+
+	if !models.ValidOverlapPolicy(url.OverlapPolicy) {
+		errs = append(errs, fmt.Sprintf("overlap_policy must be %s, %s or %s", models.OverlapSkip, models.OverlapQueue, models.OverlapConcurrent))
+	}
+
+	if url.MaxConcurrent < 0 || url.MaxConcurrent > models.MaxConcurrentLimit {
+		errs = append(errs, fmt.Sprintf("max_concurrent must be between 0 and %d", models.MaxConcurrentLimit))
+	} else if url.MaxConcurrent > 0 && url.OverlapPolicy != models.OverlapConcurrent {
+		errs = append(errs, fmt.Sprintf("max_concurrent requires overlap_policy %s", models.OverlapConcurrent))
+	}
+
+	seen := make(map[string]bool, len(url.Locations))
+	for i, location := range url.Locations {
+		switch {
+		case !models.ValidLocation(location):
+			errs = append(errs, fmt.Sprintf("locations[%d] must consist of letters, digits, '.', '_' and '-'", i))
+		case seen[location]:
+			errs = append(errs, fmt.Sprintf("locations[%d] %q is listed twice", i, location))
+		}
+		seen[location] = true
+	}
+
+	if url.Quorum < 0 || url.Quorum > len(url.Locations) {
+		errs = append(errs, fmt.Sprintf("quorum must be between 0 and the number of locations (%d)", len(url.Locations)))
+	}
+
+	// The timeout has to fit into the interval, which is only known once the interval itself is valid
+	intervalValid := url.CheckIntervalSec >= MinCheckIntervalSec && url.CheckIntervalSec <= MaxCheckIntervalSec
+	if url.TimeoutMs < 0 || (url.TimeoutMs > 0 && url.TimeoutMs < checker.MinTimeoutMs) {
+		errs = append(errs, fmt.Sprintf("timeout_ms must be at least %d", checker.MinTimeoutMs))
+	} else if intervalValid && url.TimeoutMs > url.CheckIntervalSec*1000 {
+		errs = append(errs, fmt.Sprintf("timeout_ms must not exceed the check interval of %d ms", url.CheckIntervalSec*1000))
+	}
+
+	if timeout := checker.Timeout(url); url.SlowThresholdMs < 0 || time.Duration(url.SlowThresholdMs)*time.Millisecond >= timeout {
+		errs = append(errs, fmt.Sprintf("slow_threshold_ms must be between 0 and the timeout of %d ms", timeout.Milliseconds()))
+	}
+
+	if url.Auth != nil {
+		if err := checker.ValidateAuth(*url.Auth, url.Url); err != nil {
+			errs = append(errs, fmt.Sprintf("auth is invalid: %s", err.Error()))
+		}
+	}
+
+	if url.Method != "" && !allowedMethods[strings.ToUpper(url.Method)] {
+		errs = append(errs, fmt.Sprintf("method %q is not supported", url.Method))
+	}
+
+	if _, err := checker.ParseStatusCodes(url.ExpectedStatusCodes); err != nil {
+		errs = append(errs, fmt.Sprintf("expected_status_codes is invalid: %s", err.Error()))
+	}
+
+	for i, a := range url.JSONAssertions {
+		if err := assertion.ValidateJSON(a); err != nil {
+			errs = append(errs, fmt.Sprintf("json_assertions[%d] is invalid: %s", i, err.Error()))
+		}
+	}
+
+	for i, a := range url.Assertions {
+		if err := assertion.ValidateContent(a); err != nil {
+			errs = append(errs, fmt.Sprintf("assertions[%d] is invalid: %s", i, err.Error()))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// validateAddress checks that the address is an absolute http or https url
+func validateAddress(address string) error {
+	if address == "" {
+		return fmt.Errorf("url is required")
+	}
+
+	parsed, err := neturl.Parse(address)
+	if err != nil {
+		return fmt.Errorf("url is invalid: %s", err.Error())
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("url must be an absolute http or https url")
+	}
+
+	if parsed.Host == "" {
+		return fmt.Errorf("url must have a host")
+	}
+
+	return nil
+}
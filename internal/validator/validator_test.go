@@ -0,0 +1,125 @@
+package validator
+
+import (
+	"errors"
+	"testing"
+
+	"website-monitor/internal/models"
+)
+
+func validUrl() models.MonitoredUrl {
+	return models.MonitoredUrl{
+		Url:                 "https://example.com/health",
+		CheckIntervalSec:    30,
+		RegexPattern:        "ok|healthy",
+		Method:              "GET",
+		ExpectedStatusCodes: "200-299",
+		JSONAssertions:      []models.JSONAssertion{{Path: "$.status", Op: "equals", Value: "ok"}},
+		Assertions:          []models.ContentAssertion{{Type: "not_contains", Value: "Maintenance"}},
+		MaxBodyBytes:        1024 * 1024,
+		RetryAttempts:       3,
+		RetryDelayMs:        500,
+		RetryOn:             "network,timeout,502-504",
+		RedirectPolicy:      "same_host",
+		MaxRedirects:        5,
+		TimeoutMs:           10000,
+		SlowThresholdMs:     2000,
+		Auth:                &models.Auth{Type: "basic", Username: "monitor", PasswordEnv: "MONITOR_SECRET_HEALTH_PASSWORD"},
+		OverlapPolicy:       "concurrent",
+		MaxConcurrent:       3,
+		Locations:           []string{"eu-west", "us-east", "ap-south"},
+		Quorum:              2,
+	}
+}
+
+func TestValidateMonitoredUrl_Valid(t *testing.T) {
+	t.Setenv("MONITOR_SECRET_HEALTH_PASSWORD_HOSTS", "example.com")
+
+	if err := ValidateMonitoredUrl(validUrl()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+}
+
+func TestValidateMonitoredUrl_Invalid(t *testing.T) {
+	t.Setenv("MONITOR_SECRET_HEALTH_PASSWORD_HOSTS", "example.com")
+
+	tests := map[string]func(url *models.MonitoredUrl){
+		"missing url":                     func(url *models.MonitoredUrl) { url.Url = "" },
+		"relative url":                    func(url *models.MonitoredUrl) { url.Url = "/health" },
+		"unsupported scheme":              func(url *models.MonitoredUrl) { url.Url = "ftp://example.com" },
+		"missing host":                    func(url *models.MonitoredUrl) { url.Url = "https://" },
+		"interval too short":              func(url *models.MonitoredUrl) { url.CheckIntervalSec = 4 },
+		"interval too long":               func(url *models.MonitoredUrl) { url.CheckIntervalSec = 301 },
+		"invalid regex":                   func(url *models.MonitoredUrl) { url.RegexPattern = "[invalid" },
+		"unsupported method":              func(url *models.MonitoredUrl) { url.Method = "FETCH" },
+		"invalid status code":             func(url *models.MonitoredUrl) { url.ExpectedStatusCodes = "2xx" },
+		"invalid json path":               func(url *models.MonitoredUrl) { url.JSONAssertions[0].Path = "status" },
+		"invalid assertion":               func(url *models.MonitoredUrl) { url.Assertions[0].Type = "excludes" },
+		"negative body limit":             func(url *models.MonitoredUrl) { url.MaxBodyBytes = -1 },
+		"body limit too big":              func(url *models.MonitoredUrl) { url.MaxBodyBytes = 11 * 1024 * 1024 },
+		"too many attempts":               func(url *models.MonitoredUrl) { url.RetryAttempts = 6 },
+		"negative delay":                  func(url *models.MonitoredUrl) { url.RetryDelayMs = -1 },
+		"invalid retry_on":                func(url *models.MonitoredUrl) { url.RetryOn = "network,dns" },
+		"unknown redirects":               func(url *models.MonitoredUrl) { url.RedirectPolicy = "never" },
+		"too many redirects":              func(url *models.MonitoredUrl) { url.MaxRedirects = 21 },
+		"timeout too short":               func(url *models.MonitoredUrl) { url.TimeoutMs = 50 },
+		"timeout > interval":              func(url *models.MonitoredUrl) { url.TimeoutMs = 31000 },
+		"slow above timeout":              func(url *models.MonitoredUrl) { url.SlowThresholdMs = 10000 },
+		"negative slow":                   func(url *models.MonitoredUrl) { url.SlowThresholdMs = -1 },
+		"unknown auth":                    func(url *models.MonitoredUrl) { url.Auth.Type = "digest" },
+		"plain text secret":               func(url *models.MonitoredUrl) { url.Auth.PasswordEnv = "hunter2!" },
+		"unknown overlap":                 func(url *models.MonitoredUrl) { url.OverlapPolicy = "wait" },
+		"too many concurrent":             func(url *models.MonitoredUrl) { url.MaxConcurrent = 11 },
+		"concurrent limit without policy": func(url *models.MonitoredUrl) { url.OverlapPolicy = "skip" },
+		"invalid location":                func(url *models.MonitoredUrl) { url.Locations[1] = "us:east" },
+		"duplicate location":              func(url *models.MonitoredUrl) { url.Locations[2] = "eu-west" },
+		"quorum above locations":          func(url *models.MonitoredUrl) { url.Quorum = 4 },
+		"quorum without locations":        func(url *models.MonitoredUrl) { url.Locations = nil },
+	}
+
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			url := validUrl()
+			mutate(&url)
+
+			if err := ValidateMonitoredUrl(url); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestValidateMonitoredUrl_CollectsAllErrors(t *testing.T) {
+	t.Setenv("MONITOR_SECRET_HEALTH_PASSWORD_HOSTS", "example.com")
+
+	url := validUrl()
+	url.Url = ""
+	url.CheckIntervalSec = 0
+
+	err := ValidateMonitoredUrl(url)
+
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected Errors, got %T", err)
+	}
+
+	if len(errs) != 2 {
+		t.Errorf("Expected 2 errors, got %v", errs)
+	}
+}
+
+func TestNormalize_DefaultsMethod(t *testing.T) {
+	url := Normalize(models.MonitoredUrl{Url: " https://example.com ", Method: ""})
+
+	if url.Method != "GET" {
+		t.Errorf("Expected default method GET, got %s", url.Method)
+	}
+
+	if url.Url != "https://example.com" {
+		t.Errorf("Expected trimmed url, got %q", url.Url)
+	}
+
+	if url := Normalize(models.MonitoredUrl{Method: "post"}); url.Method != "POST" {
+		t.Errorf("Expected upper-cased method POST, got %s", url.Method)
+	}
+}